@@ -0,0 +1,92 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ConcurrencyReconciler watches over the triggered BuildRuns, when a BuildRun finishes the next
+// BuildRun waiting on the queue for the same Build and Git reference is created.
+type ConcurrencyReconciler struct {
+	client.Client                 // kubernetes client
+	Scheme        *runtime.Scheme // shared scheme
+
+	limiter *concurrency.Limiter // creates BuildRuns honoring the Build concurrency policy
+}
+
+//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=create;get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;get;update
+
+// Reconcile releases the next BuildRun waiting on the queue, once the BuildRun is done. When the
+// BuildRun is deleted its labels are gone, thus all queues of the namespace are released.
+func (r *ConcurrencyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var br buildapi.BuildRun
+	if err := r.Get(ctx, req.NamespacedName, &br); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Unable to fetch BuildRun")
+			return RequeueOnError(err)
+		}
+		released, err := r.limiter.ReleaseNamespace(ctx, req.Namespace)
+		if err != nil {
+			logger.V(0).Error(err, "trying to release the queued BuildRuns of the namespace")
+			return RequeueOnError(err)
+		}
+		if len(released) > 0 {
+			logger.V(0).Info("Queued BuildRuns released after deletion", "buildruns", released)
+		}
+		return Done()
+	}
+	if !br.IsDone() && !br.IsCanceled() {
+		return Done()
+	}
+
+	labels := br.GetLabels()
+	released, err := r.limiter.Release(ctx, br.GetNamespace(), labels[filter.BuildName],
+		labels[filter.ConcurrencyGroup])
+	if err != nil {
+		logger.V(0).Error(err, "trying to release the next queued BuildRun")
+		return RequeueOnError(err)
+	}
+	if released != "" {
+		logger.V(0).Info("Queued BuildRun released", "buildrun", released)
+	}
+	return Done()
+}
+
+// SetupWithManager uses the manager to watch over the triggered BuildRuns.
+func (r *ConcurrencyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("concurrency").
+		For(&buildapi.BuildRun{}).
+		WithEventFilter(predicate.NewPredicateFuncs(filter.BuildRunConcurrencyFilterPredicate)).
+		Complete(r)
+}
+
+// NewConcurrencyReconciler instantiate the ConcurrencyReconciler.
+func NewConcurrencyReconciler(
+	ctrlClient client.Client,
+	apiReader client.Reader,
+	scheme *runtime.Scheme,
+) *ConcurrencyReconciler {
+	return &ConcurrencyReconciler{
+		Client:  ctrlClient,
+		Scheme:  scheme,
+		limiter: concurrency.NewLimiter(ctrlClient, apiReader),
+	}
+}
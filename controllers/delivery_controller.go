@@ -0,0 +1,126 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/webhook"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// DeliveryReconciler watches over the webhook delivery records, retrying the failed deliveries with
+// backoff, replaying the deliveries annotated, and purging the expired records.
+type DeliveryReconciler struct {
+	client.Client                 // kubernetes client
+	Scheme        *runtime.Scheme // shared scheme
+	Clock                         // local clock instance
+
+	limiter *concurrency.Limiter // creates BuildRuns honoring the Build concurrency policy
+}
+
+//+kubebuilder:rbac:groups=shipwright.io,resources=builds,verbs=get;list;watch
+//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=create;get;list;patch;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;delete;get;list;update;watch
+
+// requeueAt requeues the record at the next retry, or at expiration.
+func (r *DeliveryReconciler) requeueAt(record *webhook.DeliveryRecord) (ctrl.Result, error) {
+	at := record.ExpiresAt.Time
+	if record.NextAttemptAt != nil && record.NextAttemptAt.Before(&record.ExpiresAt) {
+		at = record.NextAttemptAt.Time
+	}
+	return ctrl.Result{RequeueAfter: at.Sub(r.Now()) + time.Second}, nil
+}
+
+// Reconcile attempts the delivery again when the retry is due, or when the record is annotated for
+// replay. Replays issue the BuildRuns for all Builds recorded, BuildRuns pinned to the event head
+// commit still existing are kept, as their names are derived from the event.
+func (r *DeliveryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, req.NamespacedName, &cm); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Unable to fetch delivery ConfigMap")
+		}
+		return RequeueOnError(client.IgnoreNotFound(err))
+	}
+
+	record, err := webhook.DecodeDeliveryRecord(&cm)
+	if err != nil {
+		logger.V(0).Error(err, "Skipping invalid delivery record")
+		return Done()
+	}
+	logger = logger.WithValues("provider", record.Provider, "delivery-id", record.DeliveryID)
+
+	now := r.Now()
+	if record.IsExpired(now) {
+		logger.V(0).Info("Deleting expired delivery record")
+		return RequeueOnError(client.IgnoreNotFound(r.Delete(ctx, &cm)))
+	}
+
+	_, replay := cm.GetAnnotations()[filter.WebHookReplay]
+	if !replay && !record.IsRetryDue(now) {
+		return r.requeueAt(record)
+	}
+	if replay {
+		logger.V(0).Info("Replaying webhook delivery")
+		record.BuildRuns = nil
+		annotations := cm.GetAnnotations()
+		delete(annotations, filter.WebHookReplay)
+		cm.SetAnnotations(annotations)
+	} else {
+		logger.V(0).Info("Retrying webhook delivery", "attempts", record.Attempts)
+	}
+
+	webhook.AttemptDelivery(ctx, r.Client, r.limiter, cm.GetNamespace(), record, now)
+	logger.V(0).Info("Webhook delivery attempted", "outcome", record.Outcome,
+		"buildruns", record.BuildRuns, "errors", record.Errors)
+
+	if err = webhook.EncodeDeliveryRecord(&cm, record); err != nil {
+		return RequeueOnError(err)
+	}
+	if err = r.Update(ctx, &cm); err != nil {
+		logger.V(0).Error(err, "trying to update delivery record")
+		return RequeueOnError(err)
+	}
+	return r.requeueAt(record)
+}
+
+// SetupWithManager uses the manager to watch over the webhook delivery ConfigMaps.
+func (r *DeliveryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("delivery").
+		For(&corev1.ConfigMap{}).
+		WithEventFilter(predicate.NewPredicateFuncs(filter.ConfigMapWebHookDeliveryFilterPredicate)).
+		Complete(r)
+}
+
+// NewDeliveryReconciler instantiate the DeliveryReconciler.
+func NewDeliveryReconciler(
+	ctrlClient client.Client,
+	apiReader client.Reader,
+	scheme *runtime.Scheme,
+) *DeliveryReconciler {
+	return &DeliveryReconciler{
+		Client:  ctrlClient,
+		Scheme:  scheme,
+		limiter: concurrency.NewLimiter(ctrlClient, apiReader),
+	}
+}
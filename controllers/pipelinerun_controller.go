@@ -9,14 +9,17 @@ import (
 	"fmt"
 
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
 	"github.com/shipwright-io/triggers/pkg/constants"
 	"github.com/shipwright-io/triggers/pkg/filter"
 	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/template"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -32,14 +35,18 @@ type PipelineRunReconciler struct {
 	Clock                         // local clock instance
 
 	buildInventory *inventory.Inventory // local build triggers database
+	limiter        *concurrency.Limiter // creates BuildRuns honoring the Build concurrency policy
 }
 
 //+kubebuilder:rbac:groups=shipwright.io,resources=builds,verbs=get;list;watch
-//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=create;get;list;update;watch
+//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=create;get;list;patch;update;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;get;update
 //+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;update;patch;watch
 
 // createBuildRun handles the actual BuildRun creation, uses the informed PipelineRun instance to
-// establish ownership. Only returns the created object name and error.
+// establish ownership. The Build template is rendered with the PipelineRun attributes, and the
+// concurrency policy is honored, thus the BuildRun may wait on the queue until the running ones
+// finish. Only returns the created object name and error.
 func (r *PipelineRunReconciler) createBuildRun(
 	ctx context.Context,
 	pipelineRun *tektonapi.PipelineRun,
@@ -65,7 +72,22 @@ func (r *PipelineRunReconciler) createBuildRun(
 			},
 		},
 	}
-	if err := r.Create(ctx, &br); err != nil {
+	var b buildapi.Build
+	key := types.NamespacedName{Namespace: br.GetNamespace(), Name: buildName}
+	if err := r.Get(ctx, key, &b); err != nil {
+		return "", err
+	}
+	err := template.Render(&b, &br, &template.Data{PipelineRun: &template.PipelineRun{
+		Name:        pipelineRun.GetName(),
+		Namespace:   pipelineRun.GetNamespace(),
+		Labels:      pipelineRun.GetLabels(),
+		Annotations: pipelineRun.GetAnnotations(),
+	}})
+	if err != nil {
+		return "", err
+	}
+	// PipelineRuns don't carry a Git reference, all BuildRuns of the Build share the same group
+	if _, err = r.limiter.Create(ctx, &b, &br, ""); err != nil {
 		return "", err
 	}
 	return br.GetName(), nil
@@ -81,6 +103,12 @@ func (r *PipelineRunReconciler) issueBuildRunsForPipelineRun(
 	var created []string
 	for _, buildName := range buildNames {
 		buildRunName, err := r.createBuildRun(ctx, pipelineRun, buildName)
+		if concurrency.IsQueueFull(err) {
+			// the trigger is rejected, retrying would only pile up on the queue
+			log.FromContext(ctx).V(0).Error(err, "BuildRun rejected by the concurrency queue",
+				"build-name", buildName)
+			continue
+		}
 		if err != nil {
 			return created, err
 		}
@@ -128,6 +156,18 @@ func (r *PipelineRunReconciler) Reconcile(
 		return Done()
 	}
 
+	// Builds annotated with a filter expression are only triggered when it matches the PipelineRun
+	pipelineRunVars, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pipelineRun)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	buildsToBeIssued = r.buildInventory.FilterByExpression(buildsToBeIssued, map[string]interface{}{
+		inventory.PipelineRunVariable: pipelineRunVars,
+	})
+	if len(buildsToBeIssued) == 0 {
+		return Done()
+	}
+
 	buildNames := inventory.ExtractBuildNames(buildsToBeIssued...)
 	logger.V(0).Info("Build names in the Inventory matching criteria", "build-names", buildNames)
 
@@ -206,6 +246,7 @@ func (r *PipelineRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // NewPipelineRunReconciler instantiate the PipelineRunReconciler.
 func NewPipelineRunReconciler(
 	ctrlClient client.Client,
+	apiReader client.Reader,
 	scheme *runtime.Scheme,
 	buildInventory *inventory.Inventory,
 ) *PipelineRunReconciler {
@@ -213,5 +254,6 @@ func NewPipelineRunReconciler(
 		Client:         ctrlClient,
 		Scheme:         scheme,
 		buildInventory: buildInventory,
+		limiter:        concurrency.NewLimiter(ctrlClient, apiReader),
 	}
 }
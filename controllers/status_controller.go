@@ -0,0 +1,169 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/status"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// statusSecretTokenKey key on the status Secret carrying the Git provider API token.
+const statusSecretTokenKey = "token"
+
+// StatusReconciler watches over the BuildRuns issued by the webhook, reporting their status on the
+// originating commit using the Git provider API.
+type StatusReconciler struct {
+	client.Client                 // kubernetes client
+	Scheme        *runtime.Scheme // shared scheme
+	Clock                         // local clock instance
+
+	reporters map[string]status.Reporter // status reporters indexed by provider name
+}
+
+//+kubebuilder:rbac:groups=shipwright.io,resources=builds,verbs=get;list;watch
+//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=get;list;patch;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// getStatusToken retrieves the Git provider API token from the Secret referenced by the Build
+// annotation, returns nil when the Build is not annotated.
+func (r *StatusReconciler) getStatusToken(ctx context.Context, b *buildapi.Build) ([]byte, error) {
+	secretName := b.GetAnnotations()[filter.BuildStatusSecret]
+	if secretName == "" {
+		return nil, nil
+	}
+
+	var secret corev1.Secret
+	err := r.Get(ctx, types.NamespacedName{Namespace: b.GetNamespace(), Name: secretName}, &secret)
+	if err != nil {
+		return nil, err
+	}
+	token, ok := secret.Data[statusSecretTokenKey]
+	if !ok || len(token) == 0 {
+		return nil, fmt.Errorf("secret %q does not contain the %q key", secretName,
+			statusSecretTokenKey)
+	}
+	return token, nil
+}
+
+// Reconcile reports the BuildRun status on the originating commit, when the Build references a
+// status Secret. The state reported is recorded on the BuildRun annotations, so the same state is
+// only reported once, including the pull-request note when the Build asks for it.
+func (r *StatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var br buildapi.BuildRun
+	if err := r.Get(ctx, req.NamespacedName, &br); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Unable to fetch BuildRun")
+		}
+		return RequeueOnError(client.IgnoreNotFound(err))
+	}
+
+	provider := br.GetAnnotations()[filter.WebHookProvider]
+	reporter, ok := r.reporters[provider]
+	if !ok {
+		logger.V(0).Info("Git provider does not support status reporting", "provider", provider)
+		return Done()
+	}
+	s := status.NewStatusForBuildRun(&br)
+	if s == nil {
+		logger.V(0).Info("BuildRun does not carry the repository and commit annotations")
+		return Done()
+	}
+	logger = logger.WithValues("provider", provider, "sha", s.SHA, "state", s.State)
+	if br.GetAnnotations()[filter.WebHookReportedStatus] == string(s.State) {
+		logger.V(0).Info("BuildRun status is already reported")
+		return Done()
+	}
+
+	buildName := br.GetLabels()[filter.BuildName]
+	if buildName == "" {
+		logger.V(0).Info("BuildRun does not carry the Build name label")
+		return Done()
+	}
+	var b buildapi.Build
+	err := r.Get(ctx, types.NamespacedName{Namespace: br.GetNamespace(), Name: buildName}, &b)
+	if err != nil {
+		logger.V(0).Error(err, "Retrieving the Build", "build-name", buildName)
+		return RequeueOnError(client.IgnoreNotFound(err))
+	}
+	// the repository is identified by the Build source, the repository URL annotated comes from the
+	// webhook payload, and is not trusted to locate the Git provider API receiving the token
+	if b.Spec.Source == nil || b.Spec.Source.Git == nil || b.Spec.Source.Git.URL == "" {
+		logger.V(0).Info("Build does not have a Git source", "build-name", buildName)
+		return Done()
+	}
+	s.RepoURL = b.Spec.Source.Git.URL
+
+	token, err := r.getStatusToken(ctx, &b)
+	if err != nil {
+		logger.V(0).Error(err, "Retrieving the status Secret token", "build-name", buildName)
+		return RequeueOnError(client.IgnoreNotFound(err))
+	}
+	if token == nil {
+		logger.V(0).Info("Build does not reference a status Secret", "build-name", buildName)
+		return Done()
+	}
+
+	// the pull-request note is only posted once the BuildRun is done
+	if b.GetAnnotations()[filter.BuildStatusNote] == "true" && s.State.IsFinal() {
+		s.Note = status.NewNoteForBuildRun(&br, s)
+	}
+	if err = reporter.Report(ctx, token, s); err != nil {
+		logger.V(0).Error(err, "Reporting BuildRun status on the Git provider")
+		return RequeueOnError(err)
+	}
+	logger.V(0).Info("BuildRun status reported on the Git provider")
+
+	originalBr := br.DeepCopy()
+	annotations := br.GetAnnotations()
+	annotations[filter.WebHookReportedStatus] = string(s.State)
+	br.SetAnnotations(annotations)
+	if err = r.Patch(ctx, &br, client.MergeFrom(originalBr)); err != nil {
+		logger.V(0).Error(err, "trying to patch BuildRun reported status annotation")
+		return RequeueOnError(err)
+	}
+	return Done()
+}
+
+// SetupWithManager uses the manager to watch over the BuildRuns issued by the webhook.
+func (r *StatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("status").
+		For(&buildapi.BuildRun{}).
+		WithEventFilter(predicate.NewPredicateFuncs(filter.BuildRunWebHookEventFilterPredicate)).
+		Complete(r)
+}
+
+// NewStatusReconciler instantiate the StatusReconciler with the status reporters indexed by the
+// Git provider name.
+func NewStatusReconciler(
+	ctrlClient client.Client,
+	scheme *runtime.Scheme,
+	reporters map[string]status.Reporter,
+) *StatusReconciler {
+	return &StatusReconciler{
+		Client:    ctrlClient,
+		Scheme:    scheme,
+		reporters: reporters,
+	}
+}
@@ -7,17 +7,26 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
 	"github.com/shipwright-io/triggers/controllers"
+	"github.com/shipwright-io/triggers/pkg/filter"
 	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/status"
+	"github.com/shipwright-io/triggers/pkg/webhook"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonapibeta "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -44,6 +53,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var webhookAddr string
+	var webhookProviders string
+	var webhookPendingTTL time.Duration
+	var webhookDeliveryTTL time.Duration
+	var webhookWorkers int
+	var webhookQueueSize int
+	var gitHubAPIURL string
+	var gitLabAPIURL string
 
 	flag.StringVar(
 		&metricsAddr,
@@ -57,6 +74,55 @@ func main() {
 		":8081",
 		"The address the probe endpoint binds to.",
 	)
+	flag.StringVar(
+		&webhookAddr,
+		"webhook-bind-address",
+		":8082",
+		"The address the webhook endpoint binds to.",
+	)
+	flag.StringVar(
+		&webhookProviders,
+		"webhook-providers",
+		strings.Join(webhook.ProviderNames(), ","),
+		"Comma separated list of Git providers the webhook endpoint accepts events from.",
+	)
+	flag.DurationVar(
+		&webhookPendingTTL,
+		"webhook-pending-ttl",
+		webhook.DefaultPendingTTL,
+		"Amount of time pull-request triggers held for approval are kept before expiring.",
+	)
+	flag.DurationVar(
+		&webhookDeliveryTTL,
+		"webhook-delivery-ttl",
+		webhook.DefaultDeliveryTTL,
+		"Amount of time webhook delivery records are kept, failed deliveries are retried meanwhile.",
+	)
+	flag.IntVar(
+		&webhookWorkers,
+		"webhook-workers",
+		webhook.DefaultWorkers,
+		"Amount of workers processing webhook events asynchronously, zero processes them inline.",
+	)
+	flag.IntVar(
+		&webhookQueueSize,
+		"webhook-queue-size",
+		webhook.DefaultQueueSize,
+		"Maximum amount of webhook events waiting to be processed, events are rejected when full.",
+	)
+	flag.StringVar(
+		&gitHubAPIURL,
+		"github-api-url",
+		status.DefaultGitHubAPIURL,
+		"The GitHub API base URL employed to report BuildRun statuses on commits.",
+	)
+	flag.StringVar(
+		&gitLabAPIURL,
+		"gitlab-api-url",
+		"",
+		"The GitLab API base URL employed to report BuildRun statuses on commits, "+
+			"by default the API of the instance hosting the repository.",
+	)
 	flag.BoolVar(
 		&enableLeaderElection,
 		"leader-elect",
@@ -78,6 +144,22 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "1337.triggers.shipwright.io",
+		// secrets are only read by the webhook to validate payloads, and configmaps only store the
+		// pending and debounced triggers, the concurrency queues and delivery records, thus they
+		// are retrieved directly from the API server instead of being cached by the manager
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
+			},
+		},
+		// the delivery controller only watches over the delivery records
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}: {
+					Label: labels.SelectorFromSet(labels.Set{filter.WebHookDelivery: "true"}),
+				},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -85,6 +167,7 @@ func main() {
 	}
 
 	buildInventory := inventory.NewInventory()
+	buildInventory.SetEventRecorder(mgr.GetEventRecorderFor("shipwright-triggers"))
 	inventoryReconciler := controllers.NewInventoryReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
@@ -92,6 +175,7 @@ func main() {
 	)
 	pipelineRunReconciler := controllers.NewPipelineRunReconciler(
 		mgr.GetClient(),
+		mgr.GetAPIReader(),
 		mgr.GetScheme(),
 		buildInventory,
 	)
@@ -114,6 +198,62 @@ func main() {
 		os.Exit(1)
 	}
 
+	gitHubReporter, err := status.NewGitHubReporter(gitHubAPIURL)
+	if err != nil {
+		setupLog.Error(err, "unable to configure GitHub status reporter")
+		os.Exit(1)
+	}
+	gitLabReporter, err := status.NewGitLabReporter(gitLabAPIURL)
+	if err != nil {
+		setupLog.Error(err, "unable to configure GitLab status reporter")
+		os.Exit(1)
+	}
+	statusReconciler := controllers.NewStatusReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		map[string]status.Reporter{
+			webhook.GitHubProvider: gitHubReporter,
+			webhook.GitLabProvider: gitLabReporter,
+		},
+	)
+	if err = statusReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to bootstrap controller", "controller", "Status")
+		os.Exit(1)
+	}
+
+	deliveryReconciler := controllers.NewDeliveryReconciler(mgr.GetClient(), mgr.GetAPIReader(),
+		mgr.GetScheme())
+	if err = deliveryReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to bootstrap controller", "controller", "Delivery")
+		os.Exit(1)
+	}
+
+	concurrencyReconciler := controllers.NewConcurrencyReconciler(mgr.GetClient(),
+		mgr.GetAPIReader(), mgr.GetScheme())
+	if err = concurrencyReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to bootstrap controller", "controller", "Concurrency")
+		os.Exit(1)
+	}
+
+	providers, err := webhook.NewProviders(strings.Split(webhookProviders, ","))
+	if err != nil {
+		setupLog.Error(err, "unable to configure webhook providers")
+		os.Exit(1)
+	}
+	webHook := webhook.NewWebHook(mgr.GetClient(), buildInventory, webhook.Options{
+		Addr:        webhookAddr,
+		Providers:   providers,
+		PendingTTL:  webhookPendingTTL,
+		DeliveryTTL: webhookDeliveryTTL,
+		Workers:     webhookWorkers,
+		QueueSize:   webhookQueueSize,
+		APIReader:   mgr.GetAPIReader(),
+	})
+	if err = mgr.Add(webHook); err != nil {
+		setupLog.Error(err, "unable to add webhook server to the manager")
+		os.Exit(1)
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
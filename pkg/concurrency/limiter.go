@@ -0,0 +1,315 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/store"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// QueueConfigMapName name of the ConfigMap storing the BuildRuns waiting for the running ones,
+	// one instance per namespace.
+	QueueConfigMapName = "shipwright-triggers-queue"
+	// nameSuffixLength amount of random characters appended to the BuildRuns queued.
+	nameSuffixLength = 5
+)
+
+// ErrQueueFull the maximum amount of BuildRuns waiting has been reached.
+var ErrQueueFull = errors.New("concurrency queue is full")
+
+// IsQueueFull asserts the error is caused by a full concurrency queue, the trigger is rejected
+// instead of retried.
+func IsQueueFull(err error) bool {
+	return errors.Is(err, ErrQueueFull)
+}
+
+// Limiter creates the triggered BuildRuns honoring the Build concurrency policy.
+type Limiter struct {
+	client client.Client // kubernetes client
+	reader client.Reader // uncached kubernetes reader
+}
+
+// queueKey returns the queue ConfigMap key for the Build and concurrency group.
+func queueKey(buildName, group string) string {
+	return fmt.Sprintf("%s.%s", buildName, group)
+}
+
+// running lists the BuildRuns of the Build and concurrency group still running, using the informed
+// reader.
+func running(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	buildName string,
+	group string,
+) ([]buildapi.BuildRun, error) {
+	var brs buildapi.BuildRunList
+	err := reader.List(ctx, &brs, client.InNamespace(namespace), client.MatchingLabels{
+		filter.BuildName:        buildName,
+		filter.ConcurrencyGroup: group,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var running []buildapi.BuildRun
+	for _, br := range brs.Items {
+		if !br.IsDone() && !br.IsCanceled() {
+			running = append(running, br)
+		}
+	}
+	return running, nil
+}
+
+// idle asserts no BuildRuns of the Build and concurrency group are running. The cache may lag
+// behind BuildRuns just created, thus when the cache shows no BuildRuns running it's confirmed by
+// reading directly from the API server.
+func (l *Limiter) idle(ctx context.Context, namespace, buildName, group string) (bool, error) {
+	for _, reader := range []client.Reader{l.client, l.reader} {
+		brs, err := running(ctx, reader, namespace, buildName, group)
+		if err != nil || len(brs) > 0 {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// createQueued creates the BuildRun taken from the queue, the BuildRun is named beforehand, thus
+// creating it again on conflicts is harmless.
+func (l *Limiter) createQueued(ctx context.Context, queued *buildapi.BuildRun) error {
+	next := queued.DeepCopy()
+	next.SetResourceVersion("")
+	if err := l.client.Create(ctx, next); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// exists asserts the named BuildRun already exists, BuildRuns named after the event are only
+// created once.
+func (l *Limiter) exists(ctx context.Context, br *buildapi.BuildRun) (bool, error) {
+	if br.GetName() == "" {
+		return false, nil
+	}
+	err := l.client.Get(ctx, client.ObjectKeyFromObject(br), &buildapi.BuildRun{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// cancelInProgress cancels the BuildRuns still running.
+func (l *Limiter) cancelInProgress(ctx context.Context, running []buildapi.BuildRun) error {
+	logger := log.FromContext(ctx)
+	for i := range running {
+		br := &running[i]
+		logger.V(0).Info("Cancelling in-progress BuildRun", "buildrun", br.GetName())
+
+		originalBr := br.DeepCopy()
+		br.Spec.State = buildapi.BuildRunRequestedStatePtr(buildapi.BuildRunStateCancel)
+		if err := l.client.Patch(ctx, br, client.MergeFrom(originalBr)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueStore stores the BuildRuns queued for each Build and concurrency group, empty queues are
+// purged.
+var queueStore = store.NewStore(
+	QueueConfigMapName,
+	filter.ConcurrencyQueue,
+	func(queued *[]buildapi.BuildRun) bool { return len(*queued) > 0 },
+)
+
+// updateQueue retrieves, or creates, the namespace's queue ConfigMap and applies the mutate
+// function on the BuildRuns queued for the key, the ConfigMap is only written when the function
+// returns true, even when it also returns an error. The update is retried on conflicts.
+func (l *Limiter) updateQueue(
+	ctx context.Context,
+	namespace string,
+	key string,
+	mutateFn func([]buildapi.BuildRun) ([]buildapi.BuildRun, bool, error),
+) error {
+	return queueStore.Update(ctx, l.client, namespace,
+		func(queues map[string]*[]buildapi.BuildRun) (bool, error) {
+			var queued []buildapi.BuildRun
+			if entry, ok := queues[key]; ok {
+				queued = *entry
+			}
+			queued, write, err := mutateFn(queued)
+			if len(queued) == 0 {
+				delete(queues, key)
+			} else {
+				queues[key] = &queued
+			}
+			return write, err
+		})
+}
+
+// enqueue creates the BuildRun when nothing is running nor waiting, otherwise the BuildRun is
+// queued. When nothing is running but BuildRuns are waiting, the running BuildRun has been deleted
+// or its completion has not been observed yet, thus the BuildRun waiting first is released before
+// queueing. BuildRuns queued are named beforehand, so they are created only once. Returns false
+// when the BuildRun is queued.
+func (l *Limiter) enqueue(ctx context.Context, br *buildapi.BuildRun, maxQueue int) (bool, error) {
+	if br.GetName() == "" {
+		br.SetName(fmt.Sprintf("%s%s", br.GetGenerateName(), utilrand.String(nameSuffixLength)))
+		br.SetGenerateName("")
+	}
+
+	logger := log.FromContext(ctx)
+	labels := br.GetLabels()
+	buildName, group := labels[filter.BuildName], labels[filter.ConcurrencyGroup]
+	created := false
+	err := l.updateQueue(ctx, br.GetNamespace(), queueKey(buildName, group),
+		func(queued []buildapi.BuildRun) ([]buildapi.BuildRun, bool, error) {
+			for _, q := range queued {
+				if q.GetName() == br.GetName() {
+					return queued, false, nil
+				}
+			}
+			idle, err := l.idle(ctx, br.GetNamespace(), buildName, group)
+			if err != nil {
+				return queued, false, err
+			}
+			if idle && len(queued) == 0 {
+				created = true
+				return queued, false, l.client.Create(ctx, br)
+			}
+			released := false
+			if idle {
+				logger.V(0).Info("No BuildRuns running, releasing the next queued BuildRun",
+					"buildrun", queued[0].GetName())
+				if err = l.createQueued(ctx, &queued[0]); err != nil {
+					return queued, false, err
+				}
+				queued, released = queued[1:], true
+			}
+			if len(queued) >= maxQueue {
+				// the BuildRun released is taken out of the queue regardless
+				return queued, released, fmt.Errorf("%w: %d BuildRuns waiting", ErrQueueFull,
+					len(queued))
+			}
+			return append(queued, *br), true, nil
+		})
+	return created, err
+}
+
+// Create creates the BuildRun triggered for the Build and Git reference, honoring the Build
+// concurrency policy. The BuildRun is labeled with the Build name and the concurrency group. Named
+// BuildRuns already existing are left untouched. Returns false when the BuildRun is queued instead
+// of created.
+func (l *Limiter) Create(
+	ctx context.Context,
+	b *buildapi.Build,
+	br *buildapi.BuildRun,
+	ref string,
+) (bool, error) {
+	labels := br.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[filter.BuildName] = b.GetName()
+	labels[filter.ConcurrencyGroup] = Group(ref)
+	br.SetLabels(labels)
+
+	settings, err := ParseSettings(b.GetAnnotations())
+	if err != nil {
+		log.FromContext(ctx).V(0).Error(err, "Invalid Build concurrency annotations, allowing",
+			"build-name", b.GetName())
+		settings = &Settings{Policy: PolicyAllow}
+	}
+	if settings.Policy == PolicyAllow {
+		return true, l.client.Create(ctx, br)
+	}
+
+	if exists, err := l.exists(ctx, br); err != nil || exists {
+		return true, err
+	}
+	if settings.Policy == PolicyQueue {
+		return l.enqueue(ctx, br, settings.MaxQueue)
+	}
+
+	inProgress, err := running(ctx, l.client, br.GetNamespace(), b.GetName(),
+		labels[filter.ConcurrencyGroup])
+	if err != nil {
+		return false, err
+	}
+	if err = l.cancelInProgress(ctx, inProgress); err != nil {
+		return false, err
+	}
+	return true, l.client.Create(ctx, br)
+}
+
+// Release creates the next BuildRun waiting for the Build and concurrency group, when nothing is
+// running anymore. Returns the BuildRun name created, empty when none.
+func (l *Limiter) Release(
+	ctx context.Context,
+	namespace string,
+	buildName string,
+	group string,
+) (string, error) {
+	var released string
+	err := l.updateQueue(ctx, namespace, queueKey(buildName, group),
+		func(queued []buildapi.BuildRun) ([]buildapi.BuildRun, bool, error) {
+			if len(queued) == 0 {
+				return queued, false, nil
+			}
+			idle, err := l.idle(ctx, namespace, buildName, group)
+			if err != nil || !idle {
+				return queued, false, err
+			}
+			if err = l.createQueued(ctx, &queued[0]); err != nil {
+				return queued, false, err
+			}
+			released = queued[0].GetName()
+			return queued[1:], true, nil
+		})
+	return released, err
+}
+
+// ReleaseNamespace releases the next BuildRun waiting for each Build and concurrency group queued
+// on the namespace, when nothing is running for them anymore. Employed when a BuildRun is deleted,
+// since the deleted BuildRun labels are no longer available. Returns the BuildRun names created.
+func (l *Limiter) ReleaseNamespace(ctx context.Context, namespace string) ([]string, error) {
+	queues, err := queueStore.Get(ctx, l.client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var released []string
+	for key := range queues {
+		// the Build name may carry dots, the concurrency group is a hash
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			continue
+		}
+		name, err := l.Release(ctx, namespace, key[:i], key[i+1:])
+		if err != nil {
+			return released, err
+		}
+		if name != "" {
+			released = append(released, name)
+		}
+	}
+	return released, nil
+}
+
+// NewLimiter instantiate the Limiter, the reader is employed to confirm no BuildRuns are running
+// bypassing the cache, usually the manager's API reader.
+func NewLimiter(c client.Client, reader client.Reader) *Limiter {
+	return &Limiter{client: c, reader: reader}
+}
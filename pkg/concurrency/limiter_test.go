@@ -0,0 +1,243 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	namespace = "default"
+	ref       = "refs/heads/main"
+)
+
+// newFakeClient instantiate a fake client with the Kubernetes and Shipwright schemes.
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register Kubernetes scheme: %v", err)
+	}
+	if err := buildapi.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register Shipwright scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+// newBuild instantiate a Build with the informed concurrency annotations.
+func newBuild(annotations map[string]string) *buildapi.Build {
+	return &buildapi.Build{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        "build",
+		Annotations: annotations,
+	}}
+}
+
+// newBuildRun instantiate a BuildRun for the Build, named after the informed name.
+func newBuildRun(name string) *buildapi.BuildRun {
+	buildName := "build"
+	return &buildapi.BuildRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: buildapi.BuildRunSpec{
+			Build: buildapi.ReferencedBuild{Name: &buildName},
+		},
+	}
+}
+
+// listBuildRuns lists the BuildRuns in the test namespace, by name.
+func listBuildRuns(t *testing.T, c client.Client) map[string]*buildapi.BuildRun {
+	var brs buildapi.BuildRunList
+	if err := c.List(context.TODO(), &brs, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("failed to list BuildRuns: %v", err)
+	}
+	byName := map[string]*buildapi.BuildRun{}
+	for i := range brs.Items {
+		byName[brs.Items[i].GetName()] = &brs.Items[i]
+	}
+	return byName
+}
+
+// finishBuildRun marks the named BuildRun as succeeded.
+func finishBuildRun(t *testing.T, c client.Client, name string) {
+	var br buildapi.BuildRun
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if err := c.Get(context.TODO(), key, &br); err != nil {
+		t.Fatalf("failed to get BuildRun: %v", err)
+	}
+	br.Status.Conditions = buildapi.Conditions{{
+		Type:   buildapi.Succeeded,
+		Status: corev1.ConditionTrue,
+	}}
+	if err := c.Update(context.TODO(), &br); err != nil {
+		t.Fatalf("failed to update BuildRun: %v", err)
+	}
+}
+
+func TestLimiter_CreateAllow(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := newFakeClient(t)
+	l := NewLimiter(c, c)
+
+	for _, name := range []string{"br-1", "br-2"} {
+		created, err := l.Create(context.TODO(), newBuild(nil), newBuildRun(name), ref)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(created).To(gomega.BeTrue())
+	}
+
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(2))
+	g.Expect(brs["br-1"].GetLabels()).To(gomega.HaveKeyWithValue(filter.BuildName, "build"))
+	g.Expect(brs["br-1"].GetLabels()).To(gomega.HaveKeyWithValue(filter.ConcurrencyGroup, Group(ref)))
+	g.Expect(brs["br-1"].IsCanceled()).To(gomega.BeFalse())
+}
+
+func TestLimiter_CreateCancelInProgress(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := newFakeClient(t)
+	l := NewLimiter(c, c)
+	b := newBuild(map[string]string{filter.BuildConcurrency: string(PolicyCancelInProgress)})
+
+	_, err := l.Create(context.TODO(), b, newBuildRun("br-1"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	// BuildRuns of other Git references are not affected
+	_, err = l.Create(context.TODO(), b, newBuildRun("br-other"), "refs/heads/other")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	// creating the same BuildRun again is a replay, the existing BuildRun is kept running
+	created, err := l.Create(context.TODO(), b, newBuildRun("br-1"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeTrue())
+	g.Expect(listBuildRuns(t, c)["br-1"].IsCanceled()).To(gomega.BeFalse())
+
+	created, err = l.Create(context.TODO(), b, newBuildRun("br-2"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeTrue())
+
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(3))
+	g.Expect(brs["br-1"].IsCanceled()).To(gomega.BeTrue())
+	g.Expect(brs["br-2"].IsCanceled()).To(gomega.BeFalse())
+	g.Expect(brs["br-other"].IsCanceled()).To(gomega.BeFalse())
+}
+
+func TestLimiter_CreateQueue(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := newFakeClient(t)
+	l := NewLimiter(c, c)
+	b := newBuild(map[string]string{
+		filter.BuildConcurrency:         string(PolicyQueue),
+		filter.BuildConcurrencyMaxQueue: "2",
+	})
+
+	created, err := l.Create(context.TODO(), b, newBuildRun("br-1"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeTrue())
+
+	// BuildRuns without name are named before waiting on the queue
+	generated := newBuildRun("")
+	generated.SetGenerateName("build-")
+	created, err = l.Create(context.TODO(), b, generated, ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeFalse())
+	g.Expect(generated.GetName()).To(gomega.HavePrefix("build-"))
+
+	// queueing the same BuildRun again is a no-op
+	for i := 0; i < 2; i++ {
+		created, err = l.Create(context.TODO(), b, newBuildRun("br-3"), ref)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(created).To(gomega.BeFalse())
+	}
+
+	_, err = l.Create(context.TODO(), b, newBuildRun("br-4"), ref)
+	g.Expect(errors.Is(err, ErrQueueFull)).To(gomega.BeTrue())
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+
+	var cm corev1.ConfigMap
+	key := types.NamespacedName{Namespace: namespace, Name: QueueConfigMapName}
+	g.Expect(c.Get(context.TODO(), key, &cm)).To(gomega.Succeed())
+	g.Expect(cm.GetLabels()).To(gomega.HaveKeyWithValue(filter.ConcurrencyQueue, "true"))
+	g.Expect(cm.Data).To(gomega.HaveKey(queueKey("build", Group(ref))))
+
+	// nothing is released while the BuildRun is running
+	released, err := l.Release(context.TODO(), namespace, "build", Group(ref))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(released).To(gomega.BeEmpty())
+
+	finishBuildRun(t, c, "br-1")
+	released, err = l.Release(context.TODO(), namespace, "build", Group(ref))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(released).To(gomega.Equal(generated.GetName()))
+
+	finishBuildRun(t, c, generated.GetName())
+	released, err = l.Release(context.TODO(), namespace, "build", Group(ref))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(released).To(gomega.Equal("br-3"))
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(3))
+
+	// the queue is empty, the key is removed
+	g.Expect(c.Get(context.TODO(), key, &cm)).To(gomega.Succeed())
+	g.Expect(cm.Data).ToNot(gomega.HaveKey(queueKey("build", Group(ref))))
+}
+
+func TestLimiter_CreateQueueReleasesWhenIdle(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := newFakeClient(t)
+	l := NewLimiter(c, c)
+	b := newBuild(map[string]string{filter.BuildConcurrency: string(PolicyQueue)})
+
+	for _, name := range []string{"br-1", "br-2"} {
+		_, err := l.Create(context.TODO(), b, newBuildRun(name), ref)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	}
+	// the running BuildRun is deleted, thus its completion never releases the queue
+	g.Expect(c.Delete(context.TODO(), listBuildRuns(t, c)["br-1"])).To(gomega.Succeed())
+
+	// the BuildRun waiting is released before the new one is queued
+	created, err := l.Create(context.TODO(), b, newBuildRun("br-3"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeFalse())
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	g.Expect(brs).To(gomega.HaveKey("br-2"))
+
+	// deleting the BuildRun released, the namespace queues are released
+	g.Expect(c.Delete(context.TODO(), brs["br-2"])).To(gomega.Succeed())
+	released, err := l.ReleaseNamespace(context.TODO(), namespace)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(released).To(gomega.Equal([]string{"br-3"}))
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveKey("br-3"))
+}
+
+func TestLimiter_CreateQueueConfirmsIdle(t *testing.T) {
+	g := gomega.NewWithT(t)
+	b := newBuild(map[string]string{filter.BuildConcurrency: string(PolicyQueue)})
+
+	// the cache does not show the BuildRun just created, the API server does
+	running := newBuildRun("br-1")
+	running.SetLabels(map[string]string{
+		filter.BuildName:        "build",
+		filter.ConcurrencyGroup: Group(ref),
+	})
+	cached := newFakeClient(t)
+	l := NewLimiter(cached, newFakeClient(t, running))
+
+	created, err := l.Create(context.TODO(), b, newBuildRun("br-2"), ref)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(created).To(gomega.BeFalse())
+	g.Expect(listBuildRuns(t, cached)).To(gomega.BeEmpty())
+}
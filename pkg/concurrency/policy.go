@@ -0,0 +1,72 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package concurrency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/shipwright-io/triggers/pkg/filter"
+)
+
+// Policy concurrency policy for the BuildRuns triggered for the same Build and Git reference.
+type Policy string
+
+const (
+	// PolicyAllow BuildRuns run in parallel.
+	PolicyAllow Policy = "allow"
+	// PolicyCancelInProgress BuildRuns still running are cancelled before the new one is created.
+	PolicyCancelInProgress Policy = "cancel-in-progress"
+	// PolicyQueue new BuildRuns wait until the running one finishes.
+	PolicyQueue Policy = "queue"
+)
+
+const (
+	// DefaultMaxQueue default maximum amount of BuildRuns waiting, per Build and Git reference.
+	DefaultMaxQueue = 5
+	// groupLength amount of hex characters from the Git reference hash used as label value.
+	groupLength = 16
+)
+
+// ErrInvalidPolicy the concurrency annotations are invalid.
+var ErrInvalidPolicy = errors.New("invalid concurrency policy")
+
+// Settings concurrency settings of a Build.
+type Settings struct {
+	Policy   Policy // concurrency policy
+	MaxQueue int    // maximum amount of BuildRuns waiting, only for the queue policy
+}
+
+// ParseSettings parses the Build concurrency annotations, the policy defaults to "allow".
+func ParseSettings(annotations map[string]string) (*Settings, error) {
+	settings := &Settings{Policy: PolicyAllow, MaxQueue: DefaultMaxQueue}
+	switch policy := Policy(annotations[filter.BuildConcurrency]); policy {
+	case "":
+	case PolicyAllow, PolicyCancelInProgress, PolicyQueue:
+		settings.Policy = policy
+	default:
+		return nil, fmt.Errorf("%w: unknown policy %q", ErrInvalidPolicy, policy)
+	}
+
+	if value, ok := annotations[filter.BuildConcurrencyMaxQueue]; ok {
+		maxQueue, err := strconv.Atoi(value)
+		if err != nil || maxQueue < 1 {
+			return nil, fmt.Errorf("%w: maximum queue length must be a positive integer, got %q",
+				ErrInvalidPolicy, value)
+		}
+		settings.MaxQueue = maxQueue
+	}
+	return settings, nil
+}
+
+// Group returns the concurrency group label value for the Git reference, BuildRuns triggered
+// without a Git reference (i.e. by PipelineRuns) share the same group.
+func Group(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return hex.EncodeToString(sum[:])[:groupLength]
+}
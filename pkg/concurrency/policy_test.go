@@ -0,0 +1,75 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package concurrency
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/shipwright-io/triggers/pkg/filter"
+)
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *Settings
+		wantErr     bool
+	}{{
+		name:        "no annotations, allow",
+		annotations: nil,
+		want:        &Settings{Policy: PolicyAllow, MaxQueue: DefaultMaxQueue},
+	}, {
+		name:        "cancel-in-progress",
+		annotations: map[string]string{filter.BuildConcurrency: "cancel-in-progress"},
+		want:        &Settings{Policy: PolicyCancelInProgress, MaxQueue: DefaultMaxQueue},
+	}, {
+		name: "queue with maximum length",
+		annotations: map[string]string{
+			filter.BuildConcurrency:         "queue",
+			filter.BuildConcurrencyMaxQueue: "2",
+		},
+		want: &Settings{Policy: PolicyQueue, MaxQueue: 2},
+	}, {
+		name:        "unknown policy",
+		annotations: map[string]string{filter.BuildConcurrency: "serial"},
+		wantErr:     true,
+	}, {
+		name: "invalid maximum length",
+		annotations: map[string]string{
+			filter.BuildConcurrency:         "queue",
+			filter.BuildConcurrencyMaxQueue: "0",
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSettings(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("ParseSettings() error = %v, want ErrInvalidPolicy", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSettings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	main := Group("refs/heads/main")
+	if len(main) != groupLength {
+		t.Errorf("Group() length = %d, want %d", len(main), groupLength)
+	}
+	if main != Group("refs/heads/main") {
+		t.Error("Group() is not deterministic")
+	}
+	if main == Group("refs/heads/feature") {
+		t.Error("Group() is the same for different references")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"fmt"
+)
+
+var (
+	// BuildTags annotates the Build with the Git tag expression to trigger on tag pushes, either a
+	// semantic version range or a comma separated list of glob patterns.
+	BuildTags = fmt.Sprintf("%s/tags", Prefix)
+	// BuildPaths annotates the Build with a comma separated list of glob patterns, the Build is only
+	// triggered when a changed file matches, replacing the default context directory criteria.
+	BuildPaths = fmt.Sprintf("%s/paths", Prefix)
+	// BuildPathsIgnore annotates the Build with a comma separated list of glob patterns, changed files
+	// matching are not taken into account to trigger the Build.
+	BuildPathsIgnore = fmt.Sprintf("%s/paths-ignore", Prefix)
+	// BuildPullRequestRevision annotates the Build with the revision pull-request BuildRuns should
+	// build, either the pull-request head commit ("head", default) or the merge reference ("merge").
+	BuildPullRequestRevision = fmt.Sprintf("%s/pull-request-revision", Prefix)
+	// BuildStatusSecret annotates the Build with the name of the Secret carrying the Git provider
+	// API token, employed to report the BuildRun status on the commit.
+	BuildStatusSecret = fmt.Sprintf("%s/status-secret", Prefix)
+	// BuildStatusNote annotates the Build to post a note summarizing the BuildRun result on the
+	// pull-request, when set to "true".
+	BuildStatusNote = fmt.Sprintf("%s/status-note", Prefix)
+	// BuildConcurrency annotates the Build with the concurrency policy for the BuildRuns triggered,
+	// either "allow" (default), "cancel-in-progress" or "queue".
+	BuildConcurrency = fmt.Sprintf("%s/concurrency", Prefix)
+	// BuildConcurrencyMaxQueue annotates the Build with the maximum amount of BuildRuns waiting
+	// when the concurrency policy is "queue".
+	BuildConcurrencyMaxQueue = fmt.Sprintf("%s/concurrency-max-queue", Prefix)
+	// BuildDebounce annotates the Build with the debounce window (i.e. "30s"), events for the same
+	// Git reference arriving within the window are coalesced into a single BuildRun.
+	BuildDebounce = fmt.Sprintf("%s/debounce", Prefix)
+	// BuildTemplate annotates the Build with the template rendering the trigger data into the
+	// BuildRun parameter values, environment variables, labels and output image.
+	BuildTemplate = fmt.Sprintf("%s/template", Prefix)
+	// BuildFilter annotates the Build with the CEL expression evaluated against the event, or the
+	// PipelineRun, after the trigger rules matched. The Build is triggered when it's true.
+	BuildFilter = fmt.Sprintf("%s/filter", Prefix)
+	// BuildObjectRefSelector annotates the Build with a label selector (i.e. "env in (staging,prod),
+	// !skip-builds") combined with the ObjectRef selector labels when matching PipelineRuns.
+	BuildObjectRefSelector = fmt.Sprintf("%s/objectref-selector", Prefix)
+)
+
+const (
+	// PullRequestRevisionHead builds the pull-request head commit.
+	PullRequestRevisionHead = "head"
+	// PullRequestRevisionMerge builds the pull-request merge reference, when the provider has one.
+	PullRequestRevisionMerge = "merge"
+)
@@ -5,10 +5,21 @@
 package filter
 
 import (
+	"fmt"
+
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
 	"github.com/shipwright-io/triggers/pkg/constants"
 
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// ConcurrencyGroup labels the triggered BuildRuns with the hash of the Git reference which
+	// triggered them, BuildRuns of the same Build and group are subject to the concurrency policy.
+	ConcurrencyGroup = fmt.Sprintf("%s/concurrency-group", Prefix)
+	// ConcurrencyQueue labels the ConfigMaps storing the BuildRuns waiting for the running ones.
+	ConcurrencyQueue = fmt.Sprintf("%s/concurrency-queue", Prefix)
 )
 
 // ExtractBuildRunCustomRunOwner inspect the object owners for Tekton CustomRun and returns it,
@@ -21,3 +32,18 @@ func ExtractBuildRunCustomRunOwner(br *buildapi.BuildRun) *types.NamespacedName
 	}
 	return nil
 }
+
+// BuildRunWebHookEventFilterPredicate predicate filter for BuildRuns issued by the webhook, only the
+// ones carrying the provider and commit SHA annotations go through reconciliation.
+func BuildRunWebHookEventFilterPredicate(obj client.Object) bool {
+	annotations := obj.GetAnnotations()
+	return annotations[WebHookProvider] != "" && annotations[WebHookCommitSHA] != ""
+}
+
+// BuildRunConcurrencyFilterPredicate predicate filter for the triggered BuildRuns subject to the
+// concurrency policy, only the ones carrying the Build name and concurrency group labels go through
+// reconciliation.
+func BuildRunConcurrencyFilterPredicate(obj client.Object) bool {
+	labels := obj.GetLabels()
+	return labels[BuildName] != "" && labels[ConcurrencyGroup] != ""
+}
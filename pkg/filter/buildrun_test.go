@@ -51,3 +51,35 @@ func TestExtractBuildRunCustomRunOwner(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildRunWebHookEventFilterPredicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{{
+		name:        "buildrun without annotations",
+		annotations: nil,
+		want:        false,
+	}, {
+		name:        "buildrun issued by the webhook without commit SHA",
+		annotations: map[string]string{WebHookProvider: "github"},
+		want:        false,
+	}, {
+		name: "buildrun issued by the webhook",
+		annotations: map[string]string{
+			WebHookProvider:  "github",
+			WebHookCommitSHA: "commit-sha",
+		},
+		want: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := &buildapi.BuildRun{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := BuildRunWebHookEventFilterPredicate(br); got != tt.want {
+				t.Errorf("BuildRunWebHookEventFilterPredicate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
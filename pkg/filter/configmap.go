@@ -0,0 +1,15 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMapWebHookDeliveryFilterPredicate predicate filter for the ConfigMaps recording webhook
+// deliveries, only the ones carrying the delivery label go through reconciliation.
+func ConfigMapWebHookDeliveryFilterPredicate(obj client.Object) bool {
+	return obj.GetLabels()[WebHookDelivery] == "true"
+}
@@ -0,0 +1,51 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"fmt"
+)
+
+var (
+	// WebHookProvider annotates the BuildRun with the name of the Git provider which sent the event.
+	WebHookProvider = fmt.Sprintf("%s/webhook-provider", Prefix)
+	// WebHookEventName annotates the BuildRun with the webhook event name, i.e. Push.
+	WebHookEventName = fmt.Sprintf("%s/webhook-event", Prefix)
+	// WebHookRepoURL annotates the BuildRun with the repository URL informed on the event.
+	WebHookRepoURL = fmt.Sprintf("%s/webhook-repo-url", Prefix)
+	// WebHookGitRef annotates the BuildRun with the Git reference informed on the event.
+	WebHookGitRef = fmt.Sprintf("%s/webhook-git-ref", Prefix)
+	// WebHookBranch annotates the BuildRun with the branch informed on the event, the base branch
+	// for pull-requests.
+	WebHookBranch = fmt.Sprintf("%s/webhook-branch", Prefix)
+	// WebHookGitTag annotates the BuildRun with the Git tag name, when the event refers to a tag.
+	WebHookGitTag = fmt.Sprintf("%s/webhook-git-tag", Prefix)
+	// WebHookCommitSHA annotates the BuildRun with the head commit SHA informed on the event.
+	WebHookCommitSHA = fmt.Sprintf("%s/webhook-commit-sha", Prefix)
+	// BuildName labels the BuildRun with the originating Build name, BuildRuns pinned to a commit
+	// carry a copy of the Build spec instead of referring to it by name.
+	BuildName = fmt.Sprintf("%s/build-name", Prefix)
+	// WebHookRepo labels the BuildRun with the hash of the event repository URL, repository URLs
+	// are not valid label values.
+	WebHookRepo = fmt.Sprintf("%s/webhook-repo", Prefix)
+	// WebHookPullRequest labels the BuildRun with the pull-request number which triggered it.
+	WebHookPullRequest = fmt.Sprintf("%s/webhook-pull-request", Prefix)
+	// WebHookEventHash labels the BuildRun with the hash identifying the event and Build which
+	// issued it, replays of the same event resolve to the same BuildRun.
+	WebHookEventHash = fmt.Sprintf("%s/webhook-event-hash", Prefix)
+	// WebHookReportedStatus annotates the BuildRun with the last commit status state reported on
+	// the Git provider.
+	WebHookReportedStatus = fmt.Sprintf("%s/webhook-reported-status", Prefix)
+	// PendingTriggers labels the ConfigMaps storing the pull-request triggers held for approval.
+	PendingTriggers = fmt.Sprintf("%s/pending-triggers", Prefix)
+	// DebouncedTriggers labels the ConfigMaps storing the triggers waiting for the debounce window.
+	DebouncedTriggers = fmt.Sprintf("%s/debounced-triggers", Prefix)
+	// PullRequestHeads labels the ConfigMaps storing the pull-request head commits.
+	PullRequestHeads = fmt.Sprintf("%s/pull-request-heads", Prefix)
+	// WebHookDelivery labels the ConfigMaps recording the webhook deliveries.
+	WebHookDelivery = fmt.Sprintf("%s/webhook-delivery", Prefix)
+	// WebHookReplay annotates the webhook delivery ConfigMap to replay the delivery.
+	WebHookReplay = fmt.Sprintf("%s/webhook-replay", Prefix)
+)
@@ -0,0 +1,215 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// negatePrefix prefix to negate the branch pattern, i.e. "!main".
+	negatePrefix = "!"
+	// regexPrefix prefix to employ a regular expression as branch pattern, i.e. "regex:^v\d+$".
+	regexPrefix = "regex:"
+)
+
+// ErrInvalidBranchPattern unable to parse the branch pattern.
+var ErrInvalidBranchPattern = errors.New("invalid branch pattern")
+
+// BranchMatcher compiled representation of a single branch pattern. Patterns are either a glob,
+// where "*" matches any character except "/", "**" matches any character including "/", "?" matches
+// a single character, "[...]" a character class and "{a,b}" alternatives, or a regular expression
+// when prefixed with "regex:". Both forms must match the whole branch name, and may be negated using
+// the "!" prefix.
+type BranchMatcher struct {
+	pattern string         // original pattern
+	negate  bool           // negated pattern
+	re      *regexp.Regexp // compiled expression
+}
+
+// Match asserts the informed branch matches the pattern, negation is not taken into account.
+func (b *BranchMatcher) Match(branch string) bool {
+	return b.re.MatchString(branch)
+}
+
+// IsNegated asserts the pattern is negated.
+func (b *BranchMatcher) IsNegated() bool {
+	return b.negate
+}
+
+// String returns the original pattern.
+func (b *BranchMatcher) String() string {
+	return b.pattern
+}
+
+// splitPatterns splits the expression on the separator characters outside brace alternatives,
+// character classes and escapes, so patterns like "v{1,2}.*" are kept whole.
+func splitPatterns(expr, separators string) []string {
+	patterns := []string{}
+	braces := 0
+	inClass := false
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '{':
+			braces++
+		case c == '}' && braces > 0:
+			braces--
+		case braces == 0 && strings.IndexByte(separators, c) >= 0:
+			patterns = append(patterns, strings.TrimSpace(expr[start:i]))
+			start = i + 1
+		}
+	}
+	return append(patterns, strings.TrimSpace(expr[start:]))
+}
+
+// globToRegexp translates the doublestar-style glob pattern into a regular expression.
+func globToRegexp(glob string) (string, error) {
+	var sb strings.Builder
+	inClass := false
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		if inClass {
+			switch c {
+			case ']':
+				inClass = false
+				sb.WriteByte(c)
+			case '\\':
+				sb.WriteString(`\\`)
+			default:
+				sb.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories, i.e. "feature/**/fix" matches "feature/fix"
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString(`(?:.*/)?`)
+				} else {
+					sb.WriteString(`.*`)
+				}
+			} else {
+				sb.WriteString(`[^/]*`)
+			}
+		case '?':
+			sb.WriteString(`[^/]`)
+		case '[':
+			inClass = true
+			sb.WriteByte(c)
+			if i+1 < len(glob) && glob[i+1] == '!' {
+				i++
+				sb.WriteByte('^')
+			}
+		case '{':
+			braces++
+			sb.WriteString(`(?:`)
+		case '}':
+			if braces == 0 {
+				return "", fmt.Errorf("unbalanced braces")
+			}
+			braces--
+			sb.WriteByte(')')
+		case ',':
+			if braces > 0 {
+				sb.WriteByte('|')
+			} else {
+				sb.WriteByte(c)
+			}
+		case '\\':
+			if i+1 >= len(glob) {
+				return "", fmt.Errorf("trailing escape character")
+			}
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inClass {
+		return "", fmt.Errorf("unterminated character class")
+	}
+	if braces > 0 {
+		return "", fmt.Errorf("unbalanced braces")
+	}
+	return sb.String(), nil
+}
+
+// NewBranchMatcher compiles the informed branch pattern.
+func NewBranchMatcher(pattern string) (*BranchMatcher, error) {
+	expr := strings.TrimPrefix(pattern, negatePrefix)
+	negate := expr != pattern
+
+	if strings.HasPrefix(expr, regexPrefix) {
+		expr = strings.TrimPrefix(expr, regexPrefix)
+	} else {
+		var err error
+		if expr, err = globToRegexp(expr); err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidBranchPattern, pattern, err)
+		}
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("%w %q: empty expression", ErrInvalidBranchPattern, pattern)
+	}
+
+	re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", expr))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidBranchPattern, pattern, err)
+	}
+	return &BranchMatcher{pattern: pattern, negate: negate, re: re}, nil
+}
+
+// BranchPatterns ordered list of branch patterns.
+type BranchPatterns []*BranchMatcher
+
+// Matches evaluates the patterns in order, a branch matching a regular pattern is included, and
+// a later negated pattern matching the branch excludes it again. When all patterns are negated,
+// every branch not matching them is included.
+func (p BranchPatterns) Matches(branch string) bool {
+	if len(p) == 0 || branch == "" {
+		return false
+	}
+
+	matched := true
+	for _, m := range p {
+		if !m.IsNegated() {
+			matched = false
+			break
+		}
+	}
+	for _, m := range p {
+		if m.Match(branch) {
+			matched = !m.IsNegated()
+		}
+	}
+	return matched
+}
+
+// NewBranchPatterns compiles all informed patterns, returns error on the first invalid pattern.
+func NewBranchPatterns(patterns []string) (BranchPatterns, error) {
+	branchPatterns := BranchPatterns{}
+	for _, pattern := range patterns {
+		m, err := NewBranchMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		branchPatterns = append(branchPatterns, m)
+	}
+	return branchPatterns, nil
+}
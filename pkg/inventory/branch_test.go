@@ -0,0 +1,182 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"testing"
+)
+
+func TestNewBranchMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		branch  string
+		want    bool
+		negated bool
+		wantErr bool
+	}{{
+		name:    "plain branch name",
+		pattern: "main",
+		branch:  "main",
+		want:    true,
+	}, {
+		name:    "plain branch name does not match prefix",
+		pattern: "main",
+		branch:  "main-backup",
+		want:    false,
+	}, {
+		name:    "single star matches one path segment",
+		pattern: "release/*",
+		branch:  "release/v1.0",
+		want:    true,
+	}, {
+		name:    "single star does not match nested segments",
+		pattern: "release/*",
+		branch:  "release/v1/hotfix",
+		want:    false,
+	}, {
+		name:    "double star matches nested segments",
+		pattern: "feature/**",
+		branch:  "feature/team/awesome",
+		want:    true,
+	}, {
+		name:    "double star followed by slash matches zero segments",
+		pattern: "feature/**/fix",
+		branch:  "feature/fix",
+		want:    true,
+	}, {
+		name:    "question mark matches a single character",
+		pattern: "v?",
+		branch:  "v1",
+		want:    true,
+	}, {
+		name:    "character class",
+		pattern: "v[0-9]",
+		branch:  "v7",
+		want:    true,
+	}, {
+		name:    "negated character class",
+		pattern: "v[!0-9]",
+		branch:  "v7",
+		want:    false,
+	}, {
+		name:    "alternatives",
+		pattern: "{main,develop}",
+		branch:  "develop",
+		want:    true,
+	}, {
+		name:    "dots are taken literally",
+		pattern: "v1.0",
+		branch:  "v1x0",
+		want:    false,
+	}, {
+		name:    "regular expression",
+		pattern: `regex:release-\d+`,
+		branch:  "release-10",
+		want:    true,
+	}, {
+		name:    "regular expression must match the whole branch name",
+		pattern: `regex:release-\d+`,
+		branch:  "release-10-rc",
+		want:    false,
+	}, {
+		name:    "negated pattern",
+		pattern: "!main",
+		branch:  "main",
+		want:    true,
+		negated: true,
+	}, {
+		name:    "invalid regular expression",
+		pattern: "regex:(",
+		wantErr: true,
+	}, {
+		name:    "unterminated character class",
+		pattern: "v[0-9",
+		wantErr: true,
+	}, {
+		name:    "unbalanced braces",
+		pattern: "{main,develop",
+		wantErr: true,
+	}, {
+		name:    "empty pattern",
+		pattern: "",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewBranchMatcher(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewBranchMatcher() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := m.Match(tt.branch); got != tt.want {
+				t.Errorf("BranchMatcher.Match() = %v, want %v", got, tt.want)
+			}
+			if got := m.IsNegated(); got != tt.negated {
+				t.Errorf("BranchMatcher.IsNegated() = %v, want %v", got, tt.negated)
+			}
+		})
+	}
+}
+
+func TestBranchPatterns_Matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		branch   string
+		want     bool
+	}{{
+		name:     "empty patterns",
+		patterns: []string{},
+		branch:   "main",
+		want:     false,
+	}, {
+		name:     "empty branch",
+		patterns: []string{"!main"},
+		branch:   "",
+		want:     false,
+	}, {
+		name:     "matches any of the patterns",
+		patterns: []string{"main", "release/*"},
+		branch:   "release/v1",
+		want:     true,
+	}, {
+		name:     "only negated patterns include every other branch",
+		patterns: []string{"!main"},
+		branch:   "develop",
+		want:     true,
+	}, {
+		name:     "only negated patterns exclude the informed branch",
+		patterns: []string{"!main"},
+		branch:   "main",
+		want:     false,
+	}, {
+		name:     "negated pattern excludes a previously included branch",
+		patterns: []string{"release/**", "!release/**-rc"},
+		branch:   "release/v1-rc",
+		want:     false,
+	}, {
+		name:     "pattern includes a previously excluded branch",
+		patterns: []string{"release/**", "!release/**-rc", "release/v2-rc"},
+		branch:   "release/v2-rc",
+		want:     true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewBranchPatterns(tt.patterns)
+			if err != nil {
+				t.Fatalf("NewBranchPatterns() error = %v", err)
+			}
+			if got := p.Matches(tt.branch); got != tt.want {
+				t.Errorf("BranchPatterns.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	// EventVariable CEL variable carrying the normalized webhook event.
+	EventVariable = "event"
+	// PipelineRunVariable CEL variable carrying the PipelineRun object.
+	PipelineRunVariable = "pipelineRun"
+	// expressionCostLimit maximum evaluation cost, so expressions can't hog the controller.
+	expressionCostLimit = 1000000
+)
+
+// expressionEnv shared CEL environment, declares the event and PipelineRun variables.
+var expressionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable(EventVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(PipelineRunVariable, cel.MapType(cel.StringType, cel.DynType)),
+	)
+})
+
+// ExpressionFilter compiled CEL expression filtering the events which trigger the Build.
+type ExpressionFilter struct {
+	expr    string      // original expression
+	program cel.Program // compiled program
+}
+
+// Matches evaluates the expression with the informed variables, variables not informed are empty.
+// Returns an error when the evaluation fails, i.e. referring to missing attributes.
+func (f *ExpressionFilter) Matches(vars map[string]interface{}) (bool, error) {
+	activation := map[string]interface{}{
+		EventVariable:       map[string]interface{}{},
+		PipelineRunVariable: map[string]interface{}{},
+	}
+	for k, v := range vars {
+		activation[k] = v
+	}
+	out, _, err := f.program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	matches, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluates to %s, not bool", f.expr, out.Type())
+	}
+	return matches, nil
+}
+
+// NewExpressionFilter compiles the CEL expression, which must evaluate to bool.
+func NewExpressionFilter(expr string) (*ExpressionFilter, error) {
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %w", expr, issues.Err())
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("filter expression %q evaluates to %s, not bool", expr, t)
+	}
+	program, err := env.Program(ast, cel.CostLimit(expressionCostLimit))
+	if err != nil {
+		return nil, err
+	}
+	return &ExpressionFilter{expr: expr, program: program}, nil
+}
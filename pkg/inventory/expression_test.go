@@ -0,0 +1,117 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"testing"
+)
+
+func TestNewExpressionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{{
+		name: "event expression",
+		expr: `event.author != "dependabot[bot]"`,
+	}, {
+		name: "pipelineRun expression",
+		expr: `pipelineRun.metadata.labels["env"] == "prod"`,
+	}, {
+		name:    "syntax error",
+		expr:    `event.author ==`,
+		wantErr: true,
+	}, {
+		name:    "undeclared variable",
+		expr:    `build.name == "build"`,
+		wantErr: true,
+	}, {
+		name:    "not a boolean expression",
+		expr:    `"dependabot[bot]"`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExpressionFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewExpressionFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpressionFilter_Matches(t *testing.T) {
+	pipelineRun := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"env": "prod"},
+		},
+		"status": map[string]interface{}{
+			"results": []interface{}{
+				map[string]interface{}{"name": "approved", "value": "true"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		vars    map[string]interface{}
+		want    bool
+		wantErr bool
+	}{{
+		name: "event matches",
+		expr: `event.author != "dependabot[bot]"`,
+		vars: map[string]interface{}{EventVariable: map[string]interface{}{"author": "user"}},
+		want: true,
+	}, {
+		name: "event does not match",
+		expr: `event.author != "dependabot[bot]"`,
+		vars: map[string]interface{}{
+			EventVariable: map[string]interface{}{"author": "dependabot[bot]"},
+		},
+		want: false,
+	}, {
+		name: "optional event attribute",
+		expr: `!has(event.labels) || !("skip-ci" in event.labels)`,
+		vars: map[string]interface{}{EventVariable: map[string]interface{}{"author": "user"}},
+		want: true,
+	}, {
+		name: "pipelineRun matches",
+		expr: `pipelineRun.metadata.labels["env"] == "prod" && ` +
+			`pipelineRun.status.results.exists(r, r.name == "approved")`,
+		vars: map[string]interface{}{PipelineRunVariable: pipelineRun},
+		want: true,
+	}, {
+		name: "pipelineRun does not match",
+		expr: `pipelineRun.status.results.exists(r, r.name == "rejected")`,
+		vars: map[string]interface{}{PipelineRunVariable: pipelineRun},
+		want: false,
+	}, {
+		name:    "missing attribute",
+		expr:    `event.author == "user"`,
+		vars:    map[string]interface{}{PipelineRunVariable: pipelineRun},
+		wantErr: true,
+	}, {
+		name:    "dynamic expression not evaluating to bool",
+		expr:    `event.author`,
+		vars:    map[string]interface{}{EventVariable: map[string]interface{}{"author": "user"}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewExpressionFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("NewExpressionFilter() error = %v", err)
+			}
+			got, err := f.Matches(tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Matches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
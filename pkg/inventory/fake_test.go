@@ -85,13 +85,36 @@ func (i *FakeInventory) SearchForObjectRef(
 }
 
 // SearchForGit returns all Builds in cache.
-func (i *FakeInventory) SearchForGit(buildapi.TriggerType, string, string) []SearchResult {
+func (i *FakeInventory) SearchForGit(
+	buildapi.TriggerType,
+	buildapi.GitHubEventName,
+	string,
+	string,
+) []SearchResult {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	return i.search()
+}
+
+// SearchForGitTag returns all Builds in cache.
+func (i *FakeInventory) SearchForGitTag(buildapi.TriggerType, string, string) []SearchResult {
 	i.m.Lock()
 	defer i.m.Unlock()
 
 	return i.search()
 }
 
+// FilterByChangedFiles returns all informed results.
+func (i *FakeInventory) FilterByChangedFiles(results []SearchResult, _ []string) []SearchResult {
+	return results
+}
+
+// FilterByExpression returns all informed results.
+func (i *FakeInventory) FilterByExpression(results []SearchResult, _ map[string]interface{}) []SearchResult {
+	return results
+}
+
 // NewFakeInventory instante a fake inventory for testing.
 func NewFakeInventory() *FakeInventory {
 	return &FakeInventory{
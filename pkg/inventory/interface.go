@@ -13,5 +13,8 @@ type Interface interface {
 	Add(*buildapi.Build)
 	Remove(types.NamespacedName)
 	SearchForObjectRef(buildapi.TriggerType, *buildapi.WhenObjectRef) []SearchResult
-	SearchForGit(buildapi.TriggerType, string, string) []SearchResult
+	SearchForGit(buildapi.TriggerType, buildapi.GitHubEventName, string, string) []SearchResult
+	SearchForGitTag(buildapi.TriggerType, string, string) []SearchResult
+	FilterByChangedFiles([]SearchResult, []string) []SearchResult
+	FilterByExpression([]SearchResult, map[string]interface{}) []SearchResult
 }
@@ -8,12 +8,16 @@ import (
 	"sync"
 
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/template"
 	"github.com/shipwright-io/triggers/pkg/util"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -22,16 +26,55 @@ import (
 type Inventory struct {
 	m sync.Mutex
 
-	logger logr.Logger                           // component logger
-	cache  map[types.NamespacedName]TriggerRules // cache storage
+	logger   logr.Logger                           // component logger
+	recorder record.EventRecorder                  // records events on the Builds, optional
+	cache    map[types.NamespacedName]TriggerRules // cache storage
 }
 
+// InvalidObjectRefSelectorReason event reason recorded on Builds annotated with an invalid ObjectRef
+// label selector.
+const InvalidObjectRefSelectorReason = "InvalidObjectRefSelector"
+
 var _ Interface = &Inventory{}
 
 // TriggerRules keeps the source and webhook trigger information for each Build instance.
 type TriggerRules struct {
 	source  *buildapi.Source
 	trigger buildapi.Trigger
+
+	// branches compiled GitHub branch patterns, indexed by the trigger "when" position, invalid
+	// patterns are not stored and thus the respective rule does not match
+	branches map[int]BranchPatterns
+	// tags compiled tag expression from the Build annotation, nil when not informed or invalid
+	tags Matcher
+	// paths compiled changed files filter, nil when the path annotations are invalid
+	paths *PathFilter
+	// invalidTemplate the BuildRun template annotated is invalid, thus the Build is not triggered
+	invalidTemplate bool
+	// expression compiled CEL filter expression, nil when not informed
+	expression *ExpressionFilter
+	// invalidExpression the filter expression annotated is invalid, thus the Build is not triggered
+	invalidExpression bool
+	// objectRefSelector label selector annotated, combined with the ObjectRef selector labels, nil
+	// when not informed
+	objectRefSelector labels.Selector
+	// invalidObjectRefSelector the ObjectRef selector annotated is invalid, thus ObjectRef triggers
+	// don't match
+	invalidObjectRefSelector bool
+}
+
+// matchesRepoURL asserts the Build's Git source URL matches the informed repository URL.
+func (tr *TriggerRules) matchesRepoURL(repoURL string) bool {
+	if tr.source == nil {
+		return false
+	}
+	if tr.source.Type != buildapi.GitType {
+		return false
+	}
+	if tr.source.Git == nil {
+		return false
+	}
+	return CompareURLs(repoURL, tr.source.Git.URL)
 }
 
 // SearchFn search function signature.
@@ -54,10 +97,143 @@ func (i *Inventory) Add(b *buildapi.Build) {
 		"build-name", b.GetName(),
 		"generation", b.GetGeneration(),
 	)
+	expression, err := i.compileExpressionFilter(buildName, b.GetAnnotations())
+	objectRefSelector, selectorErr := i.parseObjectRefSelector(b)
 	i.cache[buildName] = TriggerRules{
-		source:  b.Spec.Source,
-		trigger: *trigger,
+		source:   b.Spec.Source,
+		trigger:  *trigger,
+		branches: i.compileBranchPatterns(buildName, trigger),
+		tags:     i.compileTagMatcher(buildName, b.GetAnnotations()),
+		paths:    i.compilePathFilter(buildName, b.Spec.Source, b.GetAnnotations()),
+
+		invalidTemplate:   !i.validateTemplate(buildName, b),
+		expression:        expression,
+		invalidExpression: err != nil,
+
+		objectRefSelector:        objectRefSelector,
+		invalidObjectRefSelector: selectorErr != nil,
+	}
+}
+
+// parseObjectRefSelector parses the ObjectRef label selector annotated on the Build, invalid
+// selectors are logged and recorded as an event on the Build, ObjectRef triggers won't match.
+func (i *Inventory) parseObjectRefSelector(b *buildapi.Build) (labels.Selector, error) {
+	value, ok := b.GetAnnotations()[filter.BuildObjectRefSelector]
+	if !ok {
+		return nil, nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		i.logger.V(0).Error(err, "Invalid ObjectRef selector, ObjectRef triggers won't match",
+			"build-namespace", b.GetNamespace(), "build-name", b.GetName(),
+			"annotation", filter.BuildObjectRefSelector)
+		if i.recorder != nil {
+			i.recorder.Eventf(b, corev1.EventTypeWarning, InvalidObjectRefSelectorReason,
+				"Invalid %s annotation, ObjectRef triggers won't match: %v",
+				filter.BuildObjectRefSelector, err)
+		}
+		return nil, err
+	}
+	return selector, nil
+}
+
+// compileExpressionFilter compiles the CEL filter expression annotated on the Build, the compiled
+// expression is reused while the Build annotation is unchanged, i.e. on new Build generations.
+// Invalid expressions are logged and the Build won't be triggered.
+func (i *Inventory) compileExpressionFilter(
+	buildName types.NamespacedName,
+	annotations map[string]string,
+) (*ExpressionFilter, error) {
+	expr, ok := annotations[filter.BuildFilter]
+	if !ok {
+		return nil, nil
+	}
+	if tr, ok := i.cache[buildName]; ok && tr.expression != nil && tr.expression.expr == expr {
+		return tr.expression, nil
+	}
+	expression, err := NewExpressionFilter(expr)
+	if err != nil {
+		i.logger.V(0).Error(err, "Invalid filter expression, the Build won't be triggered",
+			"build-name", buildName, "annotation", filter.BuildFilter)
+		return nil, err
+	}
+	return expression, nil
+}
+
+// validateTemplate asserts the BuildRun template annotated on the Build compiles, invalid templates
+// are logged and the Build won't be triggered.
+func (i *Inventory) validateTemplate(buildName types.NamespacedName, b *buildapi.Build) bool {
+	if _, err := template.ForBuild(b); err != nil {
+		i.logger.V(0).Error(err, "Invalid BuildRun template, the Build won't be triggered",
+			"build-name", buildName, "annotation", filter.BuildTemplate)
+		return false
+	}
+	return true
+}
+
+// compilePathFilter compiles the changed files filter based on the Build's context directory and
+// path annotations, invalid patterns are logged and the Build won't match events informing files.
+func (i *Inventory) compilePathFilter(
+	buildName types.NamespacedName,
+	source *buildapi.Source,
+	annotations map[string]string,
+) *PathFilter {
+	var contextDir *string
+	if source != nil {
+		contextDir = source.ContextDir
+	}
+	paths, err := NewPathFilter(
+		contextDir,
+		annotations[filter.BuildPaths],
+		annotations[filter.BuildPathsIgnore],
+	)
+	if err != nil {
+		i.logger.V(0).Error(err, "Invalid path patterns, events informing changed files won't "+
+			"trigger the Build", "build-name", buildName)
+		return nil
+	}
+	return paths
+}
+
+// compileTagMatcher compiles the tag expression annotated on the Build, invalid expressions are
+// logged and ignored.
+func (i *Inventory) compileTagMatcher(
+	buildName types.NamespacedName,
+	annotations map[string]string,
+) Matcher {
+	expr, ok := annotations[filter.BuildTags]
+	if !ok || expr == "" {
+		return nil
+	}
+	tags, err := NewTagMatcher(expr)
+	if err != nil {
+		i.logger.V(0).Error(err, "Invalid tags expression, tag pushes won't trigger the Build",
+			"build-name", buildName, "annotation", filter.BuildTags, "tags", expr)
+		return nil
 	}
+	return tags
+}
+
+// compileBranchPatterns compiles the GitHub branch patterns for each trigger rule, invalid patterns
+// are logged and the respective rule is left out.
+func (i *Inventory) compileBranchPatterns(
+	buildName types.NamespacedName,
+	trigger *buildapi.Trigger,
+) map[int]BranchPatterns {
+	branches := map[int]BranchPatterns{}
+	for idx, w := range trigger.When {
+		if w.GitHub == nil {
+			continue
+		}
+		patterns, err := NewBranchPatterns(w.GitHub.Branches)
+		if err != nil {
+			i.logger.V(0).Error(err, "Invalid branch pattern, trigger rule is disabled",
+				"build-name", buildName, "branches", w.GitHub.Branches)
+			continue
+		}
+		branches[idx] = patterns
+	}
+	return branches
 }
 
 // Remove the informed entry from the cache.
@@ -78,6 +254,9 @@ func (i *Inventory) Remove(buildName types.NamespacedName) {
 func (i *Inventory) loopByWhenType(triggerType buildapi.TriggerType, fn SearchFn) []SearchResult {
 	found := []SearchResult{}
 	for k, v := range i.cache {
+		if v.invalidTemplate || v.invalidExpression {
+			continue
+		}
 		for _, when := range v.trigger.When {
 			if triggerType != when.Type {
 				continue
@@ -92,6 +271,9 @@ func (i *Inventory) loopByWhenType(triggerType buildapi.TriggerType, fn SearchFn
 					BuildName:  k,
 					SecretName: secretName,
 				})
+				// the search function inspects all trigger rules at once, thus the Build must be
+				// listed only once
+				break
 			}
 		}
 	}
@@ -109,6 +291,9 @@ func (i *Inventory) SearchForObjectRef(
 	defer i.m.Unlock()
 
 	return i.loopByWhenType(triggerType, func(tr TriggerRules) bool {
+		if tr.invalidObjectRefSelector {
+			return false
+		}
 		for _, w := range tr.trigger.When {
 			if w.ObjectRef == nil {
 				continue
@@ -129,9 +314,6 @@ func (i *Inventory) SearchForObjectRef(
 					continue
 				}
 			} else {
-				if len(w.ObjectRef.Selector) == 0 || len(objectRef.Selector) == 0 {
-					continue
-				}
 				// transforming the matching labels passed to this method as a regular label selector
 				// instance, which is employed to match against the Build trigger definition
 				selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
@@ -142,6 +324,15 @@ func (i *Inventory) SearchForObjectRef(
 						"ref-selector", w.ObjectRef.Selector)
 					continue
 				}
+				// combining the set-based requirements annotated on the Build
+				if tr.objectRefSelector != nil {
+					requirements, _ := tr.objectRefSelector.Requirements()
+					selector = selector.Add(requirements...)
+				}
+				// an empty selector would match every object
+				if selector.Empty() {
+					continue
+				}
 				if !selector.Matches(labels.Set(objectRef.Selector)) {
 					continue
 				}
@@ -152,10 +343,21 @@ func (i *Inventory) SearchForObjectRef(
 	})
 }
 
-// SearchForGit search for builds using the Git repository details, like the URL, branch name and
-// such type of information.
+// gitHubEventsContains asserts if the informed event name is part of the slice.
+func gitHubEventsContains(events []buildapi.GitHubEventName, eventName buildapi.GitHubEventName) bool {
+	for _, e := range events {
+		if e == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchForGit search for builds using the Git repository details, like the URL, event name, branch
+// name and such type of information.
 func (i *Inventory) SearchForGit(
 	triggerType buildapi.TriggerType,
+	eventName buildapi.GitHubEventName,
 	repoURL string,
 	branch string,
 ) []SearchResult {
@@ -165,31 +367,29 @@ func (i *Inventory) SearchForGit(
 	return i.loopByWhenType(triggerType, func(tr TriggerRules) bool {
 		// first thing to compare, is the repository URL, it must match in order to define the actual
 		// builds that are representing the repository
-		if tr.source == nil {
-			return false
-		}
-		if tr.source.Type != buildapi.GitType {
-			return false
-		}
-		if tr.source.Git == nil {
-			return false
-		}
-		if !CompareURLs(repoURL, tr.source.Git.URL) {
+		if !tr.matchesRepoURL(repoURL) {
 			return false
 		}
 
 		// second part is to search for event-type and compare the informed branch, with the allowed
 		// branches, configured for that build
-		for _, w := range tr.trigger.When {
+		for idx, w := range tr.trigger.When {
 			if w.GitHub == nil {
 				continue
 			}
-			for _, b := range w.GitHub.Branches {
-				if branch == b {
-					i.logger.V(0).Info("GitHub repository URL matches criteria",
-						"repo-url", repoURL, "branch", branch)
-					return true
-				}
+			// the event must be listed on the trigger rule, otherwise a Build configured for push
+			// events would be triggered by pull-requests as well
+			if !gitHubEventsContains(w.GitHub.Events, eventName) {
+				continue
+			}
+			patterns, ok := tr.branches[idx]
+			if !ok {
+				continue
+			}
+			if patterns.Matches(branch) {
+				i.logger.V(0).Info("GitHub repository URL matches criteria",
+					"repo-url", repoURL, "event", eventName, "branch", branch)
+				return true
 			}
 		}
 
@@ -197,6 +397,101 @@ func (i *Inventory) SearchForGit(
 	})
 }
 
+// SearchForGitTag search for builds using the Git repository URL and the tag pushed, the Build must
+// have a trigger rule for push events, and the tags expression annotated must match the tag.
+func (i *Inventory) SearchForGitTag(
+	triggerType buildapi.TriggerType,
+	repoURL string,
+	tag string,
+) []SearchResult {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	return i.loopByWhenType(triggerType, func(tr TriggerRules) bool {
+		if tr.tags == nil || !tr.matchesRepoURL(repoURL) {
+			return false
+		}
+		for _, w := range tr.trigger.When {
+			if w.GitHub == nil {
+				continue
+			}
+			if !gitHubEventsContains(w.GitHub.Events, buildapi.GitHubPushEvent) {
+				continue
+			}
+			if tr.tags.Matches(tag) {
+				i.logger.V(0).Info("Git repository URL and tag matches criteria",
+					"repo-url", repoURL, "tag", tag)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterByChangedFiles filters the search results, keeping only the Builds where at least one of
+// the changed files is relevant, either located under the context directory or matching the path
+// annotations.
+func (i *Inventory) FilterByChangedFiles(results []SearchResult, changedFiles []string) []SearchResult {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	filtered := []SearchResult{}
+	for _, result := range results {
+		tr, ok := i.cache[result.BuildName]
+		if !ok || tr.paths == nil {
+			continue
+		}
+		if !tr.paths.Matches(changedFiles) {
+			i.logger.V(0).Info("Changed files are not relevant for the Build",
+				"build-name", result.BuildName)
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// FilterByExpression filters the search results, keeping only the Builds without filter
+// expression or whose expression evaluates to true with the informed variables. Expressions
+// failing to evaluate are logged, and the Build is left out.
+func (i *Inventory) FilterByExpression(
+	results []SearchResult,
+	vars map[string]interface{},
+) []SearchResult {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	filtered := []SearchResult{}
+	for _, result := range results {
+		tr, ok := i.cache[result.BuildName]
+		if !ok {
+			continue
+		}
+		if tr.expression != nil {
+			matches, err := tr.expression.Matches(vars)
+			if err != nil {
+				i.logger.V(0).Error(err, "Unable to evaluate filter expression",
+					"build-name", result.BuildName)
+				continue
+			}
+			if !matches {
+				i.logger.V(0).Info("Filter expression does not match", "build-name", result.BuildName)
+				continue
+			}
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// SetEventRecorder sets the recorder employed to surface invalid Build annotations as events.
+func (i *Inventory) SetEventRecorder(recorder record.EventRecorder) {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	i.recorder = recorder
+}
+
 // NewInventory instantiate the inventory.
 func NewInventory() *Inventory {
 	logger := logr.New(log.Log.GetSink())
@@ -10,9 +10,11 @@ import (
 
 	"github.com/onsi/gomega"
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
 	"github.com/shipwright-io/triggers/test/stubs"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 )
 
 var buildWithTrigger = stubs.ShipwrightBuildWithTriggers(
@@ -47,19 +49,243 @@ func TestInventorySearchForgit(t *testing.T) {
 	i.Add(buildWithTrigger)
 
 	t.Run("should not find any results", func(_ *testing.T) {
-		found := i.SearchForGit(buildapi.GitHubWebHookTrigger, "", "")
+		found := i.SearchForGit(buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, "", "")
 		g.Expect(len(found)).To(gomega.Equal(0))
 
-		found = i.SearchForGit(buildapi.GitHubWebHookTrigger, stubs.RepoURL, "")
+		found = i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, "")
+		g.Expect(len(found)).To(gomega.Equal(0))
+	})
+
+	t.Run("should not find the build object for pull-request events", func(_ *testing.T) {
+		found := i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPullRequestEvent, stubs.RepoURL, stubs.Branch)
 		g.Expect(len(found)).To(gomega.Equal(0))
 	})
 
 	t.Run("should find the build object", func(_ *testing.T) {
-		found := i.SearchForGit(buildapi.GitHubWebHookTrigger, stubs.RepoURL, stubs.Branch)
+		found := i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, stubs.Branch)
+		g.Expect(len(found)).To(gomega.Equal(1))
+	})
+
+	t.Run("should find the build object only once with multiple trigger rules", func(_ *testing.T) {
+		i := NewInventory()
+		i.Add(stubs.ShipwrightBuildWithTriggers(
+			"ghcr.io/shipwright-io",
+			"name",
+			stubs.TriggerWhenPushToMain,
+			stubs.TriggerWhenPullRequestToMain,
+		))
+
+		found := i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, stubs.Branch)
+		g.Expect(len(found)).To(gomega.Equal(1))
+
+		found = i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPullRequestEvent, stubs.RepoURL, stubs.Branch)
 		g.Expect(len(found)).To(gomega.Equal(1))
 	})
 }
 
+func TestInventorySearchForGitBranchPatterns(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	whenPushToReleases := buildapi.TriggerWhen{
+		Type: buildapi.GitHubWebHookTrigger,
+		GitHub: &buildapi.WhenGitHub{
+			Events:   []buildapi.GitHubEventName{buildapi.GitHubPushEvent},
+			Branches: []string{"release/**", "!release/**-rc"},
+		},
+	}
+	whenPushInvalidPattern := buildapi.TriggerWhen{
+		Type: buildapi.GitHubWebHookTrigger,
+		GitHub: &buildapi.WhenGitHub{
+			Events:   []buildapi.GitHubEventName{buildapi.GitHubPushEvent},
+			Branches: []string{"regex:(", "!main"},
+		},
+	}
+
+	i := NewInventory()
+	i.Add(stubs.ShipwrightBuildWithTriggers("ghcr.io/shipwright-io", "releases", whenPushToReleases))
+	i.Add(stubs.ShipwrightBuildWithTriggers("ghcr.io/shipwright-io", "invalid", whenPushInvalidPattern))
+
+	t.Run("invalid patterns are not stored", func(_ *testing.T) {
+		tr := i.cache[types.NamespacedName{Namespace: stubs.Namespace, Name: "invalid"}]
+		g.Expect(tr.branches).To(gomega.BeEmpty())
+	})
+
+	t.Run("should find the build matching the glob", func(_ *testing.T) {
+		found := i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, "release/v1/x")
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"releases"}))
+	})
+
+	t.Run("should not find the build for the negated glob", func(_ *testing.T) {
+		found := i.SearchForGit(
+			buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, "release/v1-rc")
+		g.Expect(len(found)).To(gomega.Equal(0))
+	})
+}
+
+func TestInventorySearchForGitTag(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithTags := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "tags", stubs.TriggerWhenPushToMain)
+	buildWithTags.SetAnnotations(map[string]string{filter.BuildTags: ">=1.2.0 <2.0.0"})
+
+	buildWithInvalidTags := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "invalid", stubs.TriggerWhenPushToMain)
+	buildWithInvalidTags.SetAnnotations(map[string]string{filter.BuildTags: ">=bogus"})
+
+	i := NewInventory()
+	i.Add(buildWithTrigger)
+	i.Add(buildWithTags)
+	i.Add(buildWithInvalidTags)
+
+	t.Run("should find the build with tags in range", func(_ *testing.T) {
+		found := i.SearchForGitTag(buildapi.GitHubWebHookTrigger, stubs.RepoURL, "v1.2.3")
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"tags"}))
+	})
+
+	t.Run("should not find builds for tags out of range", func(_ *testing.T) {
+		found := i.SearchForGitTag(buildapi.GitHubWebHookTrigger, stubs.RepoURL, "v2.0.0")
+		g.Expect(len(found)).To(gomega.Equal(0))
+	})
+
+	t.Run("should not find builds for another repository", func(_ *testing.T) {
+		found := i.SearchForGitTag(
+			buildapi.GitHubWebHookTrigger, "https://github.com/org/repo", "v1.2.3")
+		g.Expect(len(found)).To(gomega.Equal(0))
+	})
+}
+
+func TestInventoryFilterByChangedFiles(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	// stub builds use "source-build" as context directory
+	buildOnContextDir := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "context-dir", stubs.TriggerWhenPushToMain)
+
+	buildWithPaths := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "paths", stubs.TriggerWhenPushToMain)
+	buildWithPaths.SetAnnotations(map[string]string{
+		filter.BuildPaths:       "libs/**, services/api/**",
+		filter.BuildPathsIgnore: "**/*.md",
+	})
+
+	buildWithInvalidPaths := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "invalid", stubs.TriggerWhenPushToMain)
+	buildWithInvalidPaths.SetAnnotations(map[string]string{filter.BuildPaths: "libs/[a"})
+
+	i := NewInventory()
+	i.Add(buildOnContextDir)
+	i.Add(buildWithPaths)
+	i.Add(buildWithInvalidPaths)
+
+	results := i.SearchForGit(
+		buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, stubs.Branch)
+	g.Expect(results).To(gomega.HaveLen(3))
+
+	t.Run("should find the build for files in the context directory", func(_ *testing.T) {
+		found := i.FilterByChangedFiles(results, []string{"source-build/index.js"})
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"context-dir"}))
+	})
+
+	t.Run("should find the build for files matching path patterns", func(_ *testing.T) {
+		found := i.FilterByChangedFiles(results, []string{"libs/common/util.go"})
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"paths"}))
+	})
+
+	t.Run("should not find builds for ignored files", func(_ *testing.T) {
+		found := i.FilterByChangedFiles(results, []string{"services/api/README.md", "docs/index.md"})
+		g.Expect(found).To(gomega.BeEmpty())
+	})
+}
+
+func TestInventoryInvalidTemplate(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithTemplate := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "template", stubs.TriggerWhenPushToMain)
+	buildWithTemplate.SetAnnotations(map[string]string{
+		filter.BuildTemplate: `outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA }}"`,
+	})
+
+	buildWithInvalidTemplate := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "invalid", stubs.TriggerWhenPushToMain)
+	buildWithInvalidTemplate.SetAnnotations(map[string]string{
+		filter.BuildTemplate: `outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA"`,
+	})
+
+	i := NewInventory()
+	i.Add(buildWithTemplate)
+	i.Add(buildWithInvalidTemplate)
+
+	found := i.SearchForGit(
+		buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, stubs.Branch)
+	g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"template"}))
+}
+
+func TestInventoryFilterByExpression(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithoutExpression := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "no-expression", stubs.TriggerWhenPushToMain)
+
+	buildWithExpression := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "expression", stubs.TriggerWhenPushToMain)
+	buildWithExpression.SetAnnotations(map[string]string{
+		filter.BuildFilter: `event.author != "dependabot[bot]"`,
+	})
+
+	buildWithInvalidExpression := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "invalid", stubs.TriggerWhenPushToMain)
+	buildWithInvalidExpression.SetAnnotations(map[string]string{filter.BuildFilter: `event.author ==`})
+
+	i := NewInventory()
+	i.Add(buildWithoutExpression)
+	i.Add(buildWithExpression)
+	i.Add(buildWithInvalidExpression)
+
+	results := i.SearchForGit(
+		buildapi.GitHubWebHookTrigger, buildapi.GitHubPushEvent, stubs.RepoURL, stubs.Branch)
+
+	t.Run("should not find the build with invalid expression", func(_ *testing.T) {
+		g.Expect(results).To(gomega.HaveLen(2))
+	})
+
+	t.Run("should find the builds matching the expression", func(_ *testing.T) {
+		found := i.FilterByExpression(results, map[string]interface{}{
+			EventVariable: map[string]interface{}{"author": "user"},
+		})
+		g.Expect(found).To(gomega.HaveLen(2))
+	})
+
+	t.Run("should not find the build not matching the expression", func(_ *testing.T) {
+		found := i.FilterByExpression(results, map[string]interface{}{
+			EventVariable: map[string]interface{}{"author": "dependabot[bot]"},
+		})
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"no-expression"}))
+	})
+
+	t.Run("should not find the build failing to evaluate the expression", func(_ *testing.T) {
+		found := i.FilterByExpression(results, nil)
+		g.Expect(ExtractBuildNames(found...)).To(gomega.Equal([]string{"no-expression"}))
+	})
+
+	t.Run("should reuse the compiled expression on new generations", func(_ *testing.T) {
+		buildName := types.NamespacedName{Namespace: stubs.Namespace, Name: "expression"}
+		compiled := i.cache[buildName].expression
+
+		nextGeneration := buildWithExpression.DeepCopy()
+		nextGeneration.SetGeneration(buildWithExpression.GetGeneration() + 1)
+		i.Add(nextGeneration)
+		g.Expect(i.cache[buildName].expression).To(gomega.BeIdenticalTo(compiled))
+	})
+}
+
 func TestInventory_SearchForObjectRef(t *testing.T) {
 	buildWithObjectRefName := buildapi.Build{
 		ObjectMeta: metav1.ObjectMeta{
@@ -96,6 +322,16 @@ func TestInventory_SearchForObjectRef(t *testing.T) {
 			},
 		},
 	}
+	buildWithObjectRefSetSelector := *buildWithObjectRefSelector.DeepCopy()
+	buildWithObjectRefSetSelector.SetAnnotations(map[string]string{
+		filter.BuildObjectRefSelector: "env in (staging,prod),!skip-builds",
+	})
+	buildWithOnlySetSelector := *buildWithObjectRefSetSelector.DeepCopy()
+	buildWithOnlySetSelector.Spec.Trigger.When[0].ObjectRef.Selector = nil
+	buildWithInvalidSetSelector := *buildWithObjectRefSelector.DeepCopy()
+	buildWithInvalidSetSelector.SetAnnotations(map[string]string{
+		filter.BuildObjectRefSelector: "env in (staging",
+	})
 
 	tests := []struct {
 		name      string
@@ -143,6 +379,55 @@ func TestInventory_SearchForObjectRef(t *testing.T) {
 			Status: []string{"Successful"},
 		},
 		want: []SearchResult{},
+	}, {
+		name:     "find build by label selector and annotated selector",
+		builds:   []buildapi.Build{buildWithObjectRefSetSelector},
+		whenType: buildapi.PipelineTrigger,
+		objectRef: buildapi.WhenObjectRef{
+			Status:   []string{"Successful"},
+			Selector: map[string]string{"k": "v", "env": "prod"},
+		},
+		want: []SearchResult{{
+			BuildName: types.NamespacedName{Namespace: stubs.Namespace, Name: "buildname"},
+		}},
+	}, {
+		name:     "does not find builds, due to annotated selector set requirement",
+		builds:   []buildapi.Build{buildWithObjectRefSetSelector},
+		whenType: buildapi.PipelineTrigger,
+		objectRef: buildapi.WhenObjectRef{
+			Status:   []string{"Successful"},
+			Selector: map[string]string{"k": "v", "env": "dev"},
+		},
+		want: []SearchResult{},
+	}, {
+		name:     "does not find builds, due to annotated selector exclusion",
+		builds:   []buildapi.Build{buildWithObjectRefSetSelector},
+		whenType: buildapi.PipelineTrigger,
+		objectRef: buildapi.WhenObjectRef{
+			Status:   []string{"Successful"},
+			Selector: map[string]string{"k": "v", "env": "prod", "skip-builds": "true"},
+		},
+		want: []SearchResult{},
+	}, {
+		name:     "find build by annotated selector only",
+		builds:   []buildapi.Build{buildWithOnlySetSelector},
+		whenType: buildapi.PipelineTrigger,
+		objectRef: buildapi.WhenObjectRef{
+			Status:   []string{"Successful"},
+			Selector: map[string]string{"env": "staging"},
+		},
+		want: []SearchResult{{
+			BuildName: types.NamespacedName{Namespace: stubs.Namespace, Name: "buildname"},
+		}},
+	}, {
+		name:     "does not find builds, due to invalid annotated selector",
+		builds:   []buildapi.Build{buildWithInvalidSetSelector},
+		whenType: buildapi.PipelineTrigger,
+		objectRef: buildapi.WhenObjectRef{
+			Status:   []string{"Successful"},
+			Selector: map[string]string{"k": "v"},
+		},
+		want: []SearchResult{},
 	}}
 
 	for _, tt := range tests {
@@ -160,3 +445,21 @@ func TestInventory_SearchForObjectRef(t *testing.T) {
 		})
 	}
 }
+
+func TestInventoryInvalidObjectRefSelector(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	b := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io", "invalid", stubs.TriggerWhenPipelineSucceeded)
+	b.SetAnnotations(map[string]string{filter.BuildObjectRefSelector: "env in (staging"})
+
+	recorder := record.NewFakeRecorder(1)
+	i := NewInventory()
+	i.SetEventRecorder(recorder)
+	i.Add(b)
+
+	g.Expect(recorder.Events).To(gomega.Receive(gomega.And(
+		gomega.HavePrefix("Warning %s", InvalidObjectRefSelectorReason),
+		gomega.ContainSubstring(filter.BuildObjectRefSelector),
+	)))
+}
@@ -0,0 +1,106 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPathPattern unable to parse the path pattern.
+var ErrInvalidPathPattern = errors.New("invalid path pattern")
+
+// PathPatterns list of compiled path glob patterns, using the same syntax as branch patterns.
+type PathPatterns []*regexp.Regexp
+
+// Matches asserts the informed path matches at least one pattern.
+func (p PathPatterns) Matches(filePath string) bool {
+	for _, re := range p {
+		if re.MatchString(filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPathPatterns compiles the comma (or new-line) separated list of path glob patterns, commas
+// within brace alternatives don't separate patterns.
+func NewPathPatterns(expr string) (PathPatterns, error) {
+	patterns := PathPatterns{}
+	for _, pattern := range splitPatterns(expr, ",\n") {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		glob, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidPathPattern, pattern, err)
+		}
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", glob))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidPathPattern, pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// PathFilter decides whether the files changed by a Git event are relevant for a Build. By default
+// files must be located under the Build's context directory, explicit include patterns replace the
+// context directory criteria, and ignore patterns exclude files regardless.
+type PathFilter struct {
+	contextDir string       // build source context directory, empty means the repository root
+	include    PathPatterns // explicit include patterns, replaces the context directory criteria
+	ignore     PathPatterns // exclude patterns
+}
+
+// includes asserts the informed file is relevant for the Build.
+func (f *PathFilter) includes(filePath string) bool {
+	filePath = strings.TrimPrefix(filePath, "/")
+	if f.ignore.Matches(filePath) {
+		return false
+	}
+	if len(f.include) > 0 {
+		return f.include.Matches(filePath)
+	}
+	if f.contextDir == "" {
+		return true
+	}
+	return filePath == f.contextDir || strings.HasPrefix(filePath, f.contextDir+"/")
+}
+
+// Matches asserts at least one of the changed files is relevant for the Build.
+func (f *PathFilter) Matches(changedFiles []string) bool {
+	for _, filePath := range changedFiles {
+		if f.includes(filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPathFilter instantiates a PathFilter using the informed context directory and the include
+// and ignore comma separated glob patterns, both optional.
+func NewPathFilter(contextDir *string, include, ignore string) (*PathFilter, error) {
+	f := &PathFilter{}
+	if contextDir != nil {
+		dir := strings.Trim(path.Clean(*contextDir), "/")
+		if dir != "." {
+			f.contextDir = dir
+		}
+	}
+
+	var err error
+	if f.include, err = NewPathPatterns(include); err != nil {
+		return nil, err
+	}
+	if f.ignore, err = NewPathPatterns(ignore); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,148 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewPathPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    int
+		wantErr bool
+	}{{
+		name: "empty expression",
+		expr: "",
+		want: 0,
+	}, {
+		name: "comma separated patterns",
+		expr: "services/api/**, libs/*.go",
+		want: 2,
+	}, {
+		name: "new-line separated patterns with blank entries",
+		expr: "services/api/**\n\nlibs/*.go\n",
+		want: 2,
+	}, {
+		name: "brace alternatives",
+		expr: "src/**/*.{go,mod}, docs/**",
+		want: 2,
+	}, {
+		name: "character class with comma",
+		expr: "libs/[a,b]*.go",
+		want: 1,
+	}, {
+		name:    "unterminated character class",
+		expr:    "libs/[a",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPathPatterns(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPathPatterns() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPathPattern) {
+				t.Errorf("NewPathPatterns() error = %v, want %v", err, ErrInvalidPathPattern)
+				return
+			}
+			if len(got) != tt.want {
+				t.Errorf("NewPathPatterns() = %d patterns, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestPathFilter_Matches(t *testing.T) {
+	contextDir := "./services/api/"
+	rootDir := "."
+
+	tests := []struct {
+		name         string
+		contextDir   *string
+		include      string
+		ignore       string
+		changedFiles []string
+		want         bool
+	}{{
+		name:         "without context directory all files are relevant",
+		changedFiles: []string{"README.md"},
+		want:         true,
+	}, {
+		name:         "context directory on the repository root",
+		contextDir:   &rootDir,
+		changedFiles: []string{"README.md"},
+		want:         true,
+	}, {
+		name:         "file under the context directory",
+		contextDir:   &contextDir,
+		changedFiles: []string{"docs/index.md", "services/api/main.go"},
+		want:         true,
+	}, {
+		name:         "file on a directory sharing the context directory prefix",
+		contextDir:   &contextDir,
+		changedFiles: []string{"services/api-gateway/main.go"},
+		want:         false,
+	}, {
+		name:         "include patterns replace the context directory",
+		contextDir:   &contextDir,
+		include:      "libs/**",
+		changedFiles: []string{"services/api/main.go"},
+		want:         false,
+	}, {
+		name:         "file matching the include patterns",
+		contextDir:   &contextDir,
+		include:      "libs/**",
+		changedFiles: []string{"libs/common/util.go"},
+		want:         true,
+	}, {
+		name:         "ignored file under the context directory",
+		contextDir:   &contextDir,
+		ignore:       "**/*.md",
+		changedFiles: []string{"services/api/README.md"},
+		want:         false,
+	}, {
+		name:         "ignored and relevant files",
+		contextDir:   &contextDir,
+		ignore:       "**/*.md",
+		changedFiles: []string{"services/api/README.md", "services/api/main.go"},
+		want:         true,
+	}, {
+		name:         "include pattern with brace alternatives",
+		include:      "src/**/*.{go,mod}",
+		changedFiles: []string{"README.md", "src/pkg/go.mod"},
+		want:         true,
+	}, {
+		name:         "include pattern with brace alternatives does not match",
+		include:      "src/**/*.{go,mod}",
+		changedFiles: []string{"src/pkg/README.md"},
+		want:         false,
+	}, {
+		name:         "ignore pattern with brace alternatives",
+		ignore:       "**/*.{md,txt}",
+		changedFiles: []string{"README.md", "docs/notes.txt"},
+		want:         false,
+	}, {
+		name:         "no changed files",
+		changedFiles: []string{},
+		want:         false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewPathFilter(tt.contextDir, tt.include, tt.ignore)
+			if err != nil {
+				t.Fatalf("NewPathFilter() error = %v", err)
+			}
+			if got := f.Matches(tt.changedFiles); got != tt.want {
+				t.Errorf("PathFilter.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -30,9 +30,35 @@ func parseGitSchemeURL(rawURL string) (string, error) {
 	if len(urlParts) != 2 {
 		return "", fmt.Errorf("%w: %q", ErrInvalidGItURL, rawURL)
 	}
-	suffix := strings.TrimSuffix(urlParts[1], ".git")
+	// the remaining path may have multiple segments, like GitLab subgroups "group/subgroup/project"
+	suffix := strings.TrimSuffix(strings.TrimSuffix(urlParts[1], "/"), ".git")
 
-	return fmt.Sprintf("%s/%s/%s", gitURLParts[0], urlParts[0], suffix), nil
+	return fmt.Sprintf("%s/%s/%s", strings.ToLower(gitURLParts[0]), urlParts[0], suffix), nil
+}
+
+// bitbucketServerSSHPort default Bitbucket Server SSH port, SSH clone URLs employing it are
+// identified as Bitbucket Server repositories.
+const bitbucketServerSSHPort = "7999"
+
+// normalizeBitbucketServerPath rewrites Bitbucket Server clone ("/scm/PROJ/repo"), browse
+// ("/projects/PROJ/repos/repo/browse") and SSH clone ("/PROJ/repo", on port 7999) paths into
+// "/scm/proj/repo". Only paths matching these shapes from the root are rewritten, the "scm" prefix
+// is kept so the result does not collide with other repositories on the same host. Bitbucket
+// Server project keys are case insensitive, thus the path is lowercased.
+func normalizeBitbucketServerPath(u *url.URL, urlPath string) string {
+	segments := strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+	switch {
+	case u.Scheme == "ssh" && u.Port() == bitbucketServerSSHPort && len(segments) == 2:
+		return strings.ToLower(fmt.Sprintf("/scm/%s/%s", segments[0], segments[1]))
+	case u.Scheme == "ssh":
+		return urlPath
+	case len(segments) == 3 && segments[0] == "scm":
+		return strings.ToLower(fmt.Sprintf("/scm/%s/%s", segments[1], segments[2]))
+	case len(segments) == 5 && segments[0] == "projects" && segments[2] == "repos" &&
+		segments[4] == "browse":
+		return strings.ToLower(fmt.Sprintf("/scm/%s/%s", segments[1], segments[3]))
+	}
+	return urlPath
 }
 
 // SanitizeURL takes a raw repository URL and returns only the hostname and path, removing possible
@@ -47,8 +73,9 @@ func SanitizeURL(rawURL string) (string, error) {
 		return "", err
 	}
 
-	urlPath := strings.TrimSuffix(u.EscapedPath(), ".git")
-	return fmt.Sprintf("%s%s", u.Hostname(), urlPath), nil
+	urlPath := strings.TrimSuffix(strings.TrimSuffix(u.EscapedPath(), "/"), ".git")
+	urlPath = normalizeBitbucketServerPath(u, urlPath)
+	return fmt.Sprintf("%s%s", strings.ToLower(u.Hostname()), urlPath), nil
 }
 
 // CompareURLs compare the informed URLs.
@@ -24,6 +24,61 @@ func TestSanitizeURL(t *testing.T) {
 		rawURL:  "git@github.com:username/repository.git",
 		want:    "github.com/username/repository",
 		wantErr: false,
+	}, {
+		name:    "http scheme URL with subgroups",
+		rawURL:  "https://gitlab.com/group/subgroup/project.git",
+		want:    "gitlab.com/group/subgroup/project",
+		wantErr: false,
+	}, {
+		name:    "git scheme URL with subgroups",
+		rawURL:  "git@gitlab.com:group/subgroup/project.git",
+		want:    "gitlab.com/group/subgroup/project",
+		wantErr: false,
+	}, {
+		name:    "ssh scheme URL with port and subgroups",
+		rawURL:  "ssh://git@gitlab.example.com:2222/group/subgroup/project.git",
+		want:    "gitlab.example.com/group/subgroup/project",
+		wantErr: false,
+	}, {
+		name:    "http scheme URL with trailing slash and uppercase hostname",
+		rawURL:  "https://GitLab.com/group/project/",
+		want:    "gitlab.com/group/project",
+		wantErr: false,
+	}, {
+		name:    "bitbucket server http clone URL",
+		rawURL:  "https://bitbucket.example.com/scm/PROJ/repo.git",
+		want:    "bitbucket.example.com/scm/proj/repo",
+		wantErr: false,
+	}, {
+		name:    "bitbucket server ssh clone URL",
+		rawURL:  "ssh://git@bitbucket.example.com:7999/proj/repo.git",
+		want:    "bitbucket.example.com/scm/proj/repo",
+		wantErr: false,
+	}, {
+		name:    "bitbucket server browse URL",
+		rawURL:  "https://bitbucket.example.com/projects/PROJ/repos/repo/browse",
+		want:    "bitbucket.example.com/scm/proj/repo",
+		wantErr: false,
+	}, {
+		name:    "bitbucket server ssh clone URL with uppercase project key",
+		rawURL:  "ssh://git@bitbucket.example.com:7999/PROJ/repo.git",
+		want:    "bitbucket.example.com/scm/proj/repo",
+		wantErr: false,
+	}, {
+		name:    "ssh scheme URL on another port is not lowercased",
+		rawURL:  "ssh://git@gitlab.example.com:2222/Group/Project.git",
+		want:    "gitlab.example.com/Group/Project",
+		wantErr: false,
+	}, {
+		name:    "http scheme URL with scm subgroup",
+		rawURL:  "https://gitlab.example.com/group/scm/team/app.git",
+		want:    "gitlab.example.com/group/scm/team/app",
+		wantErr: false,
+	}, {
+		name:    "http scheme URL with projects group",
+		rawURL:  "https://gitlab.example.com/projects/team/repos/app.git",
+		want:    "gitlab.example.com/projects/team/repos/app",
+		wantErr: false,
 	}}
 
 	for _, tt := range tests {
@@ -66,6 +121,46 @@ func TestCompareURLs(t *testing.T) {
 		a:    "https://github.com/username/repository.git",
 		b:    "git@github.com:username/another-repository.git",
 		want: false,
+	}, {
+		name: "git and http URLs with subgroups",
+		a:    "https://gitlab.com/group/subgroup/project",
+		b:    "git@gitlab.com:group/subgroup/project.git",
+		want: true,
+	}, {
+		name: "http URLs with different subgroups",
+		a:    "https://gitlab.com/group/subgroup/project",
+		b:    "https://gitlab.com/group/another-subgroup/project",
+		want: false,
+	}, {
+		name: "bitbucket server browse and http clone URLs",
+		a:    "https://bitbucket.example.com/projects/PROJ/repos/repo/browse",
+		b:    "https://bitbucket.example.com/scm/PROJ/repo.git",
+		want: true,
+	}, {
+		name: "bitbucket server browse and ssh clone URLs",
+		a:    "https://bitbucket.example.com/projects/PROJ/repos/repo/browse",
+		b:    "ssh://git@bitbucket.example.com:7999/proj/repo.git",
+		want: true,
+	}, {
+		name: "bitbucket server different repositories",
+		a:    "https://bitbucket.example.com/projects/PROJ/repos/repo/browse",
+		b:    "https://bitbucket.example.com/scm/PROJ/another-repo.git",
+		want: false,
+	}, {
+		name: "bitbucket server http and uppercase ssh clone URLs",
+		a:    "https://bitbucket.example.com/scm/PROJ/repo.git",
+		b:    "ssh://git@bitbucket.example.com:7999/PROJ/repo.git",
+		want: true,
+	}, {
+		name: "scm group does not collide with the project without it",
+		a:    "https://gitlab.example.com/scm/team/app.git",
+		b:    "https://gitlab.example.com/team/app.git",
+		want: false,
+	}, {
+		name: "scm subgroup does not collide with the project without it",
+		a:    "https://gitlab.example.com/group/scm/team/app.git",
+		b:    "https://gitlab.example.com/team/app.git",
+		want: false,
 	}}
 
 	for _, tt := range tests {
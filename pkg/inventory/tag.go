@@ -0,0 +1,66 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// semverOperatorPrefixes prefixes identifying a tag expression as a semantic version range.
+var semverOperatorPrefixes = []string{"<", ">", "=", "~", "^", "!="}
+
+// Matcher asserts the informed Git reference name matches the rules.
+type Matcher interface {
+	Matches(string) bool
+}
+
+// SemverMatcher matches Git tags carrying a semantic version, with optional "v" prefix, against a
+// version range, i.e. ">=1.2.0 <2.0.0".
+type SemverMatcher struct {
+	constraints *semver.Constraints
+}
+
+var (
+	_ Matcher = &SemverMatcher{}
+	_ Matcher = BranchPatterns{}
+)
+
+// Matches asserts the tag is a semantic version within the range.
+func (s *SemverMatcher) Matches(tag string) bool {
+	v, err := semver.NewVersion(tag)
+	if err != nil {
+		return false
+	}
+	return s.constraints.Check(v)
+}
+
+// isSemverExpression asserts the expression starts with a version range operator.
+func isSemverExpression(expr string) bool {
+	for _, prefix := range semverOperatorPrefixes {
+		if strings.HasPrefix(expr, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTagMatcher parses the informed tag expression, when it starts with a version operator it's
+// handled as a semantic version range, otherwise as comma separated glob patterns, following the
+// same rules than branch patterns.
+func NewTagMatcher(expr string) (Matcher, error) {
+	expr = strings.TrimSpace(expr)
+	if isSemverExpression(expr) {
+		constraints, err := semver.NewConstraint(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version range %q: %w", expr, err)
+		}
+		return &SemverMatcher{constraints: constraints}, nil
+	}
+
+	return NewBranchPatterns(splitPatterns(expr, ","))
+}
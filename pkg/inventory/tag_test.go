@@ -0,0 +1,88 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"testing"
+)
+
+func TestNewTagMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		tag     string
+		want    bool
+		wantErr bool
+	}{{
+		name: "semver range matches",
+		expr: ">=1.2.0 <2.0.0",
+		tag:  "1.4.0",
+		want: true,
+	}, {
+		name: "semver range matches tag with v prefix",
+		expr: ">=1.2.0 <2.0.0",
+		tag:  "v1.2.0",
+		want: true,
+	}, {
+		name: "semver range does not match",
+		expr: ">=1.2.0 <2.0.0",
+		tag:  "v2.0.0",
+		want: false,
+	}, {
+		name: "semver range alternatives",
+		expr: "^1.0.0 || ^3.0.0",
+		tag:  "v3.1.0",
+		want: true,
+	}, {
+		name: "semver range does not match non-semver tags",
+		expr: ">=1.0.0",
+		tag:  "latest",
+		want: false,
+	}, {
+		name: "glob pattern",
+		expr: "v*",
+		tag:  "v1.0.0",
+		want: true,
+	}, {
+		name: "comma separated glob patterns",
+		expr: "release-*, v*, !v*-rc*",
+		tag:  "v1.0.0-rc1",
+		want: false,
+	}, {
+		name: "glob pattern with brace alternatives",
+		expr: "v{1,2}.*",
+		tag:  "v2.3",
+		want: true,
+	}, {
+		name: "comma separated glob patterns with brace alternatives",
+		expr: "release-*, v{1,2}.*",
+		tag:  "v3.0",
+		want: false,
+	}, {
+		name:    "invalid semver range",
+		expr:    ">=bogus",
+		wantErr: true,
+	}, {
+		name:    "invalid glob pattern",
+		expr:    "v[0-9",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewTagMatcher(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTagMatcher() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := m.Matches(tt.tag); got != tt.want {
+				t.Errorf("Matcher.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v53/github"
+)
+
+// DefaultGitHubAPIURL GitHub API base URL.
+const DefaultGitHubAPIURL = "https://api.github.com/"
+
+// gitHubStates GitHub commit status states, GitHub does not have running nor cancelled states.
+var gitHubStates = map[State]string{
+	StatePending:   "pending",
+	StateRunning:   "pending",
+	StateSuccess:   "success",
+	StateFailure:   "failure",
+	StateCancelled: "error",
+}
+
+// tokenTransport adds the bearer token on every request.
+type tokenTransport struct {
+	token []byte
+}
+
+// RoundTrip sets the authorization header on a copy of the request.
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+string(t.token))
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// GitHubReporter reports commit statuses using the GitHub API.
+type GitHubReporter struct {
+	baseURL *url.URL // github api base url
+}
+
+var _ Reporter = &GitHubReporter{}
+
+// Report creates the commit status for the commit SHA, pull-request notes are not supported.
+func (g *GitHubReporter) Report(ctx context.Context, token []byte, s *Status) error {
+	owner, repo, err := repoOwnerAndName(s.RepoURL)
+	if err != nil {
+		return err
+	}
+
+	client := github.NewClient(&http.Client{Transport: &tokenTransport{token: token}})
+	client.BaseURL = g.baseURL
+	_, _, err = client.Repositories.CreateStatus(ctx, owner, repo, s.SHA, &github.RepoStatus{
+		State:       github.String(gitHubStates[s.State]),
+		Context:     github.String(s.Context),
+		Description: github.String(s.Description),
+	})
+	return err
+}
+
+// NewGitHubReporter instantiate the GitHubReporter using the informed API base URL, GitHub
+// Enterprise API is located at "https://hostname/api/v3/".
+func NewGitHubReporter(baseURL string) (*GitHubReporter, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubReporter{baseURL: u}, nil
+}
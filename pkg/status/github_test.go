@@ -0,0 +1,73 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestGitHubReporter_Report(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var gotPath, gotAuthorization string
+	var gotBody map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+		_, _ = rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	reporter, err := NewGitHubReporter(server.URL + "/api/v3")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = reporter.Report(context.TODO(), []byte("token"), &Status{
+		RepoURL:     repoURL,
+		SHA:         sha,
+		State:       StateFailure,
+		Context:     "shipwright/build",
+		Description: "BuildahFailed: unable to build the image",
+	})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	g.Expect(gotPath).To(gomega.Equal("/api/v3/repos/shipwright-io/sample-nodejs/statuses/commit-sha"))
+	g.Expect(gotAuthorization).To(gomega.Equal("Bearer token"))
+	g.Expect(gotBody).To(gomega.Equal(map[string]string{
+		"state":       "failure",
+		"context":     "shipwright/build",
+		"description": "BuildahFailed: unable to build the image",
+	}))
+}
+
+func TestGitHubReporter_ReportError(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		_, _ = rw.Write([]byte(`{"message":"Bad credentials"}`))
+	}))
+	defer server.Close()
+
+	reporter, err := NewGitHubReporter(server.URL)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = reporter.Report(context.TODO(), []byte("token"), &Status{
+		RepoURL: repoURL,
+		SHA:     sha,
+		State:   StatePending,
+	})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,149 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitLabAPIPath path of the GitLab API on the GitLab instance.
+const gitLabAPIPath = "/api/v4/"
+
+// gitLabStates GitLab commit status states.
+var gitLabStates = map[State]string{
+	StatePending:   "pending",
+	StateRunning:   "running",
+	StateSuccess:   "success",
+	StateFailure:   "failed",
+	StateCancelled: "canceled",
+}
+
+// GitLabReporter reports commit statuses, and merge-request notes, using the GitLab API.
+type GitLabReporter struct {
+	baseURL *url.URL     // gitlab api base url, derived from the repository url when nil
+	client  *http.Client // http client employed on the api requests
+}
+
+var _ Reporter = &GitLabReporter{}
+
+// repoHost extracts the host of the instance serving the repository, either from HTTP or SSH clone
+// URLs. The SSH port is not related to the instance API, thus it's left out.
+func repoHost(repoURL string) (string, error) {
+	if strings.HasPrefix(repoURL, "git@") {
+		host, _, ok := strings.Cut(strings.TrimPrefix(repoURL, "git@"), ":")
+		if !ok || host == "" {
+			return "", fmt.Errorf("%w: %q", ErrInvalidRepoURL, repoURL)
+		}
+		return strings.ToLower(host), nil
+	}
+	u, err := url.ParseRequestURI(repoURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRepoURL, repoURL)
+	}
+	if u.Scheme == "ssh" || u.Scheme == "git" {
+		return strings.ToLower(u.Hostname()), nil
+	}
+	return strings.ToLower(u.Host), nil
+}
+
+// apiURL returns the GitLab API base URL for the repository, either the configured one or the
+// API of the instance hosting the repository. The repository URL must come from the Build, and the
+// instance API is always reached using HTTPS, so the token is never sent in cleartext.
+func (g *GitLabReporter) apiURL(repoURL string) (*url.URL, error) {
+	if g.baseURL != nil {
+		return g.baseURL, nil
+	}
+	host, err := repoHost(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: "https", Host: host, Path: gitLabAPIPath}, nil
+}
+
+// post sends the payload as JSON to the GitLab API endpoint, relative to the project.
+func (g *GitLabReporter) post(
+	ctx context.Context,
+	token []byte,
+	repoURL string,
+	endpoint string,
+	payload interface{},
+) error {
+	owner, repo, err := repoOwnerAndName(repoURL)
+	if err != nil {
+		return err
+	}
+	apiURL, err := g.apiURL(repoURL)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// the project is identified by its url-encoded path, "group%2Fproject"
+	projectURL := fmt.Sprintf("%sprojects/%s/%s",
+		apiURL.String(), url.PathEscape(fmt.Sprintf("%s/%s", owner, repo)), endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, projectURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", string(token))
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("gitlab api %q returned status %d: %s", endpoint, res.StatusCode,
+			strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Report creates the commit status for the commit SHA, and when the status carries a note for a
+// merge-request, posts the note on the merge-request as well.
+func (g *GitLabReporter) Report(ctx context.Context, token []byte, s *Status) error {
+	err := g.post(ctx, token, s.RepoURL, fmt.Sprintf("statuses/%s", s.SHA), map[string]string{
+		"state":       gitLabStates[s.State],
+		"name":        s.Context,
+		"description": s.Description,
+	})
+	if err != nil {
+		return err
+	}
+	if s.Note == "" || s.PullRequest <= 0 {
+		return nil
+	}
+	return g.post(ctx, token, s.RepoURL, fmt.Sprintf("merge_requests/%d/notes", s.PullRequest),
+		map[string]string{"body": s.Note})
+}
+
+// NewGitLabReporter instantiate the GitLabReporter using the informed API base URL, i.e.
+// "https://gitlab.com/api/v4/". When empty, the API of the instance hosting the repository is
+// employed.
+func NewGitLabReporter(baseURL string) (*GitLabReporter, error) {
+	if baseURL == "" {
+		return &GitLabReporter{client: http.DefaultClient}, nil
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &GitLabReporter{baseURL: u, client: http.DefaultClient}, nil
+}
@@ -0,0 +1,167 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+// gitLabRequest request received by the GitLab API stub.
+type gitLabRequest struct {
+	path  string
+	token string
+	body  map[string]string
+}
+
+// newGitLabServer starts a GitLab API stub recording the requests received.
+func newGitLabServer(t *testing.T, requests *[]gitLabRequest) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req := gitLabRequest{path: r.URL.EscapedPath(), token: r.Header.Get("PRIVATE-TOKEN")}
+		if err := json.NewDecoder(r.Body).Decode(&req.body); err != nil {
+			t.Errorf("unable to decode request body: %v", err)
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*requests = append(*requests, req)
+		rw.WriteHeader(http.StatusCreated)
+		_, _ = rw.Write([]byte("{}"))
+	}))
+}
+
+func TestGitLabReporter_Report(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *Status
+		want   []gitLabRequest
+	}{{
+		name: "running commit status",
+		status: &Status{
+			State:       StateRunning,
+			Context:     "shipwright/build",
+			Description: "BuildRun is running",
+			PullRequest: 1,
+		},
+		want: []gitLabRequest{{
+			path:  "/api/v4/projects/group%2Fsubgroup%2Fproject/statuses/commit-sha",
+			token: "token",
+			body: map[string]string{
+				"state":       "running",
+				"name":        "shipwright/build",
+				"description": "BuildRun is running",
+			},
+		}},
+	}, {
+		name: "cancelled commit status with merge-request note",
+		status: &Status{
+			State:       StateCancelled,
+			Context:     "shipwright/build",
+			Description: "BuildRun cancelled",
+			PullRequest: 1,
+			Note:        "note",
+		},
+		want: []gitLabRequest{{
+			path:  "/api/v4/projects/group%2Fsubgroup%2Fproject/statuses/commit-sha",
+			token: "token",
+			body: map[string]string{
+				"state":       "canceled",
+				"name":        "shipwright/build",
+				"description": "BuildRun cancelled",
+			},
+		}, {
+			path:  "/api/v4/projects/group%2Fsubgroup%2Fproject/merge_requests/1/notes",
+			token: "token",
+			body:  map[string]string{"body": "note"},
+		}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			var requests []gitLabRequest
+			server := newGitLabServer(t, &requests)
+			defer server.Close()
+
+			// the API base URL is derived from the repository URL, always using HTTPS
+			reporter, err := NewGitLabReporter("")
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			reporter.client = server.Client()
+
+			tt.status.RepoURL = strings.Replace(server.URL, "https://", "http://", 1) +
+				"/group/subgroup/project"
+			tt.status.SHA = sha
+			g.Expect(reporter.Report(context.TODO(), []byte("token"), tt.status)).To(gomega.Succeed())
+			g.Expect(requests).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+func TestGitLabReporter_apiURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoURL string
+		want    string
+		wantErr bool
+	}{{
+		name:    "https repository URL",
+		repoURL: "https://gitlab.com/group/project.git",
+		want:    "https://gitlab.com/api/v4/",
+	}, {
+		name:    "http repository URL is upgraded to https",
+		repoURL: "http://gitlab.example.com:8443/group/project",
+		want:    "https://gitlab.example.com:8443/api/v4/",
+	}, {
+		name:    "git scheme repository URL",
+		repoURL: "git@gitlab.com:group/project.git",
+		want:    "https://gitlab.com/api/v4/",
+	}, {
+		name:    "ssh repository URL does not keep the ssh port",
+		repoURL: "ssh://git@gitlab.example.com:2222/group/project.git",
+		want:    "https://gitlab.example.com/api/v4/",
+	}, {
+		name:    "invalid repository URL",
+		repoURL: "gitlab.com/group/project",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &GitLabReporter{}
+			got, err := reporter.apiURL(tt.repoURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apiURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("apiURL() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabReporter_ReportError(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		_, _ = rw.Write([]byte(`{"message":"401 Unauthorized"}`))
+	}))
+	defer server.Close()
+
+	reporter, err := NewGitLabReporter(server.URL + "/api/v4")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = reporter.Report(context.TODO(), []byte("token"), &Status{
+		RepoURL: "https://gitlab.com/group/project",
+		SHA:     sha,
+		State:   StatePending,
+	})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("401 Unauthorized")))
+}
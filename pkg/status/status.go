@@ -0,0 +1,161 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+)
+
+// maxDescriptionLength maximum commit status description length accepted by GitHub.
+const maxDescriptionLength = 140
+
+// ErrInvalidRepoURL the repository URL does not inform the repository owner and name.
+var ErrInvalidRepoURL = errors.New("invalid repository URL")
+
+// State provider-neutral commit status state.
+type State string
+
+const (
+	// StatePending the BuildRun has not started yet.
+	StatePending State = "pending"
+	// StateRunning the BuildRun is running.
+	StateRunning State = "running"
+	// StateSuccess the BuildRun succeeded.
+	StateSuccess State = "success"
+	// StateFailure the BuildRun failed.
+	StateFailure State = "failure"
+	// StateCancelled the BuildRun has been cancelled.
+	StateCancelled State = "cancelled"
+)
+
+// IsFinal asserts the state won't change anymore.
+func (s State) IsFinal() bool {
+	return s == StateSuccess || s == StateFailure || s == StateCancelled
+}
+
+// Status commit status describing the BuildRun issued for the commit.
+type Status struct {
+	RepoURL     string // repository URL
+	SHA         string // commit SHA
+	State       State  // commit status state
+	Context     string // status context, identifies the Build
+	Description string // short description, carries the failure details
+	PullRequest int    // pull-request number, zero when not issued for a pull-request
+	Note        string // pull-request note (comment) body, posted when informed
+}
+
+// Reporter reports the commit statuses on the Git provider, using the informed API token.
+type Reporter interface {
+	Report(ctx context.Context, token []byte, status *Status) error
+}
+
+// NewStatusForBuildRun describes the commit status for the BuildRun issued by the webhook, returns
+// nil when the BuildRun does not carry the repository URL and commit SHA annotations.
+func NewStatusForBuildRun(br *buildapi.BuildRun) *Status {
+	annotations := br.GetAnnotations()
+	repoURL := annotations[filter.WebHookRepoURL]
+	sha := annotations[filter.WebHookCommitSHA]
+	if repoURL == "" || sha == "" {
+		return nil
+	}
+
+	s := &Status{
+		RepoURL: repoURL,
+		SHA:     sha,
+		Context: fmt.Sprintf("shipwright/%s", br.GetLabels()[filter.BuildName]),
+	}
+	if pr, err := strconv.Atoi(br.GetLabels()[filter.WebHookPullRequest]); err == nil {
+		s.PullRequest = pr
+	}
+	switch {
+	case br.IsCanceled():
+		s.State = StateCancelled
+		s.Description = "BuildRun cancelled"
+	case !br.IsDone() && !br.HasStarted():
+		s.State = StatePending
+		s.Description = "BuildRun is pending"
+	case !br.IsDone():
+		s.State = StateRunning
+		s.Description = "BuildRun is running"
+	case br.IsSuccessful():
+		s.State = StateSuccess
+		s.Description = "BuildRun succeeded"
+	default:
+		s.State = StateFailure
+		s.Description = failureDescription(br)
+	}
+	return s
+}
+
+// failureDescription describes the BuildRun failure using the failure details, falling back to the
+// succeeded condition message, truncated to the maximum description length.
+func failureDescription(br *buildapi.BuildRun) string {
+	description := "BuildRun failed"
+	if details := br.Status.FailureDetails; details != nil && details.Message != "" {
+		description = details.Message
+		if details.Reason != "" {
+			description = fmt.Sprintf("%s: %s", details.Reason, details.Message)
+		}
+	} else if c := br.Status.GetCondition(buildapi.Succeeded); c != nil && c.GetMessage() != "" {
+		description = c.GetMessage()
+	}
+	if len(description) > maxDescriptionLength {
+		description = fmt.Sprintf("%s...", description[:maxDescriptionLength-3])
+	}
+	return description
+}
+
+// NewNoteForBuildRun summarizes the BuildRun result as a pull-request note (markdown), informing the
+// image digest and the vulnerabilities found.
+func NewNoteForBuildRun(br *buildapi.BuildRun, s *Status) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**: BuildRun `%s` %s\n", s.Context, br.GetName(), s.State)
+	if s.State == StateFailure {
+		fmt.Fprintf(&b, "\n%s\n", s.Description)
+	}
+
+	output := br.Status.Output
+	if output == nil {
+		return b.String()
+	}
+	if output.Digest != "" {
+		fmt.Fprintf(&b, "\nImage digest: `%s`\n", output.Digest)
+	}
+	if len(output.Vulnerabilities) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\nVulnerabilities found: %d\n\n", len(output.Vulnerabilities))
+	b.WriteString("| ID | Severity |\n| --- | --- |\n")
+	for _, v := range output.Vulnerabilities {
+		fmt.Fprintf(&b, "| %s | %s |\n", v.ID, v.Severity)
+	}
+	return b.String()
+}
+
+// repoOwnerAndName extracts the repository owner (namespace) and name from the repository URL.
+func repoOwnerAndName(repoURL string) (string, string, error) {
+	sanitized, err := inventory.SanitizeURL(repoURL)
+	if err != nil {
+		return "", "", err
+	}
+	// the sanitized URL is composed by the hostname followed by the repository path
+	parts := strings.SplitN(sanitized, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidRepoURL, repoURL)
+	}
+	idx := strings.LastIndex(parts[1], "/")
+	if idx <= 0 || idx == len(parts[1])-1 {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidRepoURL, repoURL)
+	}
+	return parts[1][:idx], parts[1][idx+1:], nil
+}
@@ -0,0 +1,252 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package status
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	repoURL = "https://github.com/shipwright-io/sample-nodejs"
+	sha     = "commit-sha"
+)
+
+// webhookBuildRun returns a BuildRun issued by the webhook with the informed succeeded condition
+// status, when informed.
+func webhookBuildRun(status corev1.ConditionStatus) *buildapi.BuildRun {
+	br := &buildapi.BuildRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{filter.BuildName: "build"},
+			Annotations: map[string]string{
+				filter.WebHookRepoURL:   repoURL,
+				filter.WebHookCommitSHA: sha,
+			},
+		},
+	}
+	if status != "" {
+		br.Status.Conditions = buildapi.Conditions{{
+			Type:    buildapi.Succeeded,
+			Status:  status,
+			Message: "condition message",
+		}}
+	}
+	return br
+}
+
+func TestNewStatusForBuildRun(t *testing.T) {
+	failedWithDetails := webhookBuildRun(corev1.ConditionFalse)
+	failedWithDetails.Status.FailureDetails = &buildapi.FailureDetails{
+		Reason:  "BuildahFailed",
+		Message: "unable to build the image",
+	}
+
+	failedWithLongMessage := webhookBuildRun(corev1.ConditionFalse)
+	failedWithLongMessage.Status.FailureDetails = &buildapi.FailureDetails{
+		Message: strings.Repeat("a", 200),
+	}
+
+	cancelled := webhookBuildRun(corev1.ConditionFalse)
+	cancelled.Spec.State = buildapi.BuildRunRequestedStatePtr(buildapi.BuildRunStateCancel)
+
+	running := webhookBuildRun(corev1.ConditionUnknown)
+	running.Status.StartTime = &metav1.Time{Time: time.Now()}
+
+	pullRequest := webhookBuildRun(corev1.ConditionTrue)
+	pullRequest.Labels[filter.WebHookPullRequest] = "1"
+
+	tests := []struct {
+		name string
+		br   *buildapi.BuildRun
+		want *Status
+	}{{
+		name: "buildrun without webhook annotations",
+		br:   &buildapi.BuildRun{},
+		want: nil,
+	}, {
+		name: "pending buildrun",
+		br:   webhookBuildRun(corev1.ConditionUnknown),
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StatePending,
+			Context:     "shipwright/build",
+			Description: "BuildRun is pending",
+		},
+	}, {
+		name: "running buildrun",
+		br:   running,
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateRunning,
+			Context:     "shipwright/build",
+			Description: "BuildRun is running",
+		},
+	}, {
+		name: "succeeded pull-request buildrun",
+		br:   pullRequest,
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateSuccess,
+			Context:     "shipwright/build",
+			Description: "BuildRun succeeded",
+			PullRequest: 1,
+		},
+	}, {
+		name: "succeeded buildrun",
+		br:   webhookBuildRun(corev1.ConditionTrue),
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateSuccess,
+			Context:     "shipwright/build",
+			Description: "BuildRun succeeded",
+		},
+	}, {
+		name: "failed buildrun with failure details",
+		br:   failedWithDetails,
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateFailure,
+			Context:     "shipwright/build",
+			Description: "BuildahFailed: unable to build the image",
+		},
+	}, {
+		name: "failed buildrun without failure details",
+		br:   webhookBuildRun(corev1.ConditionFalse),
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateFailure,
+			Context:     "shipwright/build",
+			Description: "condition message",
+		},
+	}, {
+		name: "failed buildrun with long failure message",
+		br:   failedWithLongMessage,
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateFailure,
+			Context:     "shipwright/build",
+			Description: strings.Repeat("a", maxDescriptionLength-3) + "...",
+		},
+	}, {
+		name: "cancelled buildrun",
+		br:   cancelled,
+		want: &Status{
+			RepoURL:     repoURL,
+			SHA:         sha,
+			State:       StateCancelled,
+			Context:     "shipwright/build",
+			Description: "BuildRun cancelled",
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewStatusForBuildRun(tt.br); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewStatusForBuildRun() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewNoteForBuildRun(t *testing.T) {
+	withOutput := webhookBuildRun(corev1.ConditionTrue)
+	withOutput.SetName("build-xyz")
+	withOutput.Status.Output = &buildapi.Output{
+		Digest: "sha256:digest",
+		Vulnerabilities: []buildapi.Vulnerability{
+			{ID: "CVE-2023-0001", Severity: buildapi.Critical},
+			{ID: "CVE-2023-0002", Severity: buildapi.Low},
+		},
+	}
+
+	withoutOutput := webhookBuildRun(corev1.ConditionFalse)
+	withoutOutput.SetName("build-xyz")
+
+	tests := []struct {
+		name string
+		br   *buildapi.BuildRun
+		want string
+	}{{
+		name: "buildrun with image digest and vulnerabilities",
+		br:   withOutput,
+		want: "**shipwright/build**: BuildRun `build-xyz` success\n" +
+			"\nImage digest: `sha256:digest`\n" +
+			"\nVulnerabilities found: 2\n\n" +
+			"| ID | Severity |\n| --- | --- |\n" +
+			"| CVE-2023-0001 | critical |\n" +
+			"| CVE-2023-0002 | low |\n",
+	}, {
+		name: "failed buildrun without output",
+		br:   withoutOutput,
+		want: "**shipwright/build**: BuildRun `build-xyz` failure\n\ncondition message\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStatusForBuildRun(tt.br)
+			if got := NewNoteForBuildRun(tt.br, s); got != tt.want {
+				t.Errorf("NewNoteForBuildRun() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoOwnerAndName(t *testing.T) {
+	tests := []struct {
+		name      string
+		repoURL   string
+		wantOwner string
+		wantName  string
+		wantErr   bool
+	}{{
+		name:      "http scheme URL",
+		repoURL:   "https://github.com/shipwright-io/sample-nodejs.git",
+		wantOwner: "shipwright-io",
+		wantName:  "sample-nodejs",
+	}, {
+		name:      "git scheme URL",
+		repoURL:   "git@github.com:shipwright-io/sample-nodejs.git",
+		wantOwner: "shipwright-io",
+		wantName:  "sample-nodejs",
+	}, {
+		name:      "URL with subgroups",
+		repoURL:   "https://gitlab.com/group/subgroup/project",
+		wantOwner: "group/subgroup",
+		wantName:  "project",
+	}, {
+		name:    "URL without owner",
+		repoURL: "https://github.com/sample-nodejs",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, name, err := repoOwnerAndName(tt.repoURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("repoOwnerAndName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if owner != tt.wantOwner || name != tt.wantName {
+				t.Errorf("repoOwnerAndName() = %q, %q, want %q, %q", owner, name,
+					tt.wantOwner, tt.wantName)
+			}
+		})
+	}
+}
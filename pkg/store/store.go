@@ -0,0 +1,131 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Store keeps entries as JSON values on a ConfigMap, one instance per namespace, all instances
+// share the same name and are labeled so they can be searched across namespaces.
+type Store[T any] struct {
+	name  string        // ConfigMap name
+	label string        // label identifying the ConfigMaps, set to "true"
+	valid func(*T) bool // asserts the entry decoded is kept, invalid entries are purged
+}
+
+// Decode decodes the ConfigMap entries, skipping the ones failing to decode or not valid.
+func (s *Store[T]) Decode(cm *corev1.ConfigMap) map[string]*T {
+	entries := map[string]*T{}
+	for key, value := range cm.Data {
+		var entry T
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+		if s.valid != nil && !s.valid(&entry) {
+			continue
+		}
+		entries[key] = &entry
+	}
+	return entries
+}
+
+// Get retrieves the entries stored on the namespace, empty when the ConfigMap does not exist.
+func (s *Store[T]) Get(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+) (map[string]*T, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: s.name}, cm); err != nil {
+		return map[string]*T{}, client.IgnoreNotFound(err)
+	}
+	return s.Decode(cm), nil
+}
+
+// List retrieves the entries stored on all namespaces, indexed by namespace.
+func (s *Store[T]) List(ctx context.Context, c client.Reader) (map[string]map[string]*T, error) {
+	var cms corev1.ConfigMapList
+	if err := c.List(ctx, &cms, client.MatchingLabels{s.label: "true"}); err != nil {
+		return nil, err
+	}
+	namespaces := map[string]map[string]*T{}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if cm.GetName() != s.name {
+			continue
+		}
+		namespaces[cm.GetNamespace()] = s.Decode(cm)
+	}
+	return namespaces, nil
+}
+
+// Update retrieves, or creates, the namespace's ConfigMap and applies the mutate function on the
+// entries stored. The ConfigMap is only written when the function returns true, even when it also
+// returns an error, which is returned after writing. Invalid entries are purged on every write, and
+// the update is retried on conflicts.
+func (s *Store[T]) Update(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	mutateFn func(map[string]*T) (bool, error),
+) error {
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm := &corev1.ConfigMap{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: s.name}, cm)
+		notFound := apierrors.IsNotFound(err)
+		if err != nil && !notFound {
+			return err
+		}
+
+		entries := s.Decode(cm)
+		write, mutateErr := mutateFn(entries)
+		if !write || (notFound && len(entries) == 0) {
+			return mutateErr
+		}
+
+		data := make(map[string]string, len(entries))
+		for key, entry := range entries {
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			data[key] = string(value)
+		}
+		cm.Data = data
+
+		if notFound {
+			cm.ObjectMeta = metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      s.name,
+				Labels:    map[string]string{s.label: "true"},
+			}
+			err = c.Create(ctx, cm)
+		} else {
+			err = c.Update(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+		return mutateErr
+	})
+}
+
+// NewStore instantiate the Store for the ConfigMaps with the informed name and label, entries are
+// kept when the valid function is nil or asserts them.
+func NewStore[T any](name, label string, valid func(*T) bool) *Store[T] {
+	return &Store[T]{name: name, label: label, valid: valid}
+}
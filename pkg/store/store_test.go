@@ -0,0 +1,158 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	name  = "entries"
+	label = "entries-label"
+)
+
+// entry stored on the test ConfigMaps.
+type entry struct {
+	Value string `json:"value"`
+}
+
+// newStore instantiate the Store for the test entries, entries without value are invalid.
+func newStore() *Store[entry] {
+	return NewStore(name, label, func(e *entry) bool { return e.Value != "" })
+}
+
+// newConfigMap instantiate the store ConfigMap on the namespace with the informed data.
+func newConfigMap(namespace string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{label: "true"},
+		},
+		Data: data,
+	}
+}
+
+func TestStore_Decode(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	entries := newStore().Decode(newConfigMap("default", map[string]string{
+		"valid":   `{"value":"a"}`,
+		"invalid": `{"value":""}`,
+		"garbage": `not-json`,
+	}))
+	g.Expect(entries).To(gomega.HaveLen(1))
+	g.Expect(entries).To(gomega.HaveKeyWithValue("valid", &entry{Value: "a"}))
+}
+
+func TestStore_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		objs     []client.Object
+		write    bool
+		mutate   map[string]string
+		mutErr   error
+		wantErr  bool
+		wantData map[string]string // nil when the ConfigMap is not expected
+	}{{
+		name:     "ConfigMap is created on the first entry",
+		write:    true,
+		mutate:   map[string]string{"a": "a"},
+		wantData: map[string]string{"a": `{"value":"a"}`},
+	}, {
+		name:   "ConfigMap is not created without entries",
+		write:  true,
+		mutate: map[string]string{},
+	}, {
+		name:     "entries are added and invalid ones purged",
+		objs:     []client.Object{newConfigMap("default", map[string]string{"x": `{"value":""}`})},
+		write:    true,
+		mutate:   map[string]string{"b": "b"},
+		wantData: map[string]string{"b": `{"value":"b"}`},
+	}, {
+		name:     "ConfigMap is not written when the function returns false",
+		objs:     []client.Object{newConfigMap("default", map[string]string{"a": `{"value":"a"}`})},
+		write:    false,
+		mutate:   map[string]string{"b": "b"},
+		wantData: map[string]string{"a": `{"value":"a"}`},
+	}, {
+		name:     "ConfigMap is written before returning the function error",
+		objs:     []client.Object{newConfigMap("default", map[string]string{"a": `{"value":"a"}`})},
+		write:    true,
+		mutate:   map[string]string{"b": "b"},
+		mutErr:   errors.New("mutate failed"),
+		wantErr:  true,
+		wantData: map[string]string{"a": `{"value":"a"}`, "b": `{"value":"b"}`},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			c := fake.NewClientBuilder().WithObjects(tt.objs...).Build()
+			s := newStore()
+
+			err := s.Update(context.TODO(), c, "default", func(entries map[string]*entry) (bool, error) {
+				for key, value := range tt.mutate {
+					entries[key] = &entry{Value: value}
+				}
+				return tt.write, tt.mutErr
+			})
+			if tt.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+			}
+
+			var cm corev1.ConfigMap
+			err = c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, &cm)
+			if tt.wantData == nil {
+				g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+				return
+			}
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(cm.GetLabels()).To(gomega.HaveKeyWithValue(label, "true"))
+			g.Expect(cm.Data).To(gomega.Equal(tt.wantData))
+		})
+	}
+}
+
+func TestStore_GetAndList(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	other := newConfigMap("other", map[string]string{"b": `{"value":"b"}`})
+	unrelated := newConfigMap("other", map[string]string{"c": `{"value":"c"}`})
+	unrelated.SetName("unrelated")
+	c := fake.NewClientBuilder().WithObjects(
+		newConfigMap("default", map[string]string{"a": `{"value":"a"}`}),
+		other,
+		unrelated,
+	).Build()
+	s := newStore()
+
+	entries, err := s.Get(context.TODO(), c, "default")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(entries).To(gomega.HaveKeyWithValue("a", &entry{Value: "a"}))
+
+	entries, err = s.Get(context.TODO(), c, "missing")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(entries).To(gomega.BeEmpty())
+
+	namespaces, err := s.List(context.TODO(), c)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(namespaces).To(gomega.Equal(map[string]map[string]*entry{
+		"default": {"a": &entry{Value: "a"}},
+		"other":   {"b": &entry{Value: "b"}},
+	}))
+}
@@ -0,0 +1,248 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	gotemplate "text/template"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// shortSHALength amount of characters of the abbreviated commit SHA.
+const shortSHALength = 7
+
+// Spec BuildRun template annotated on the Build, as YAML or JSON. Each value is a Go template
+// rendered with the trigger Data.
+type Spec struct {
+	// ParamValues parameter values informed on the BuildRun, by parameter name.
+	ParamValues map[string]string `json:"paramValues,omitempty"`
+	// Env environment variables informed on the BuildRun, by variable name.
+	Env map[string]string `json:"env,omitempty"`
+	// Labels labels informed on the BuildRun.
+	Labels map[string]string `json:"labels,omitempty"`
+	// OutputImage output image informed on the BuildRun, i.e.
+	// "ghcr.io/org/app:{{ .Event.ShortSHA }}".
+	OutputImage string `json:"outputImage,omitempty"`
+}
+
+// Event webhook event attributes available to the templates.
+type Event struct {
+	Provider string // git provider name
+	Name     string // event kind, i.e. "Push" or "PullRequest"
+	RepoURL  string // repository URL
+	Ref      string // full git reference, i.e. "refs/heads/main"
+	Branch   string // branch name, empty for tags
+	Tag      string // tag name, empty for branches
+	HeadSHA  string // head commit SHA
+	ShortSHA string // abbreviated head commit SHA
+	Author   string // user who originated the event
+}
+
+// PullRequest pull-request attributes available to the templates.
+type PullRequest struct {
+	Number int // pull-request number
+}
+
+// PipelineRun Tekton PipelineRun attributes available to the templates.
+type PipelineRun struct {
+	Name        string            // PipelineRun name
+	Namespace   string            // PipelineRun namespace
+	Labels      map[string]string // PipelineRun labels
+	Annotations map[string]string // PipelineRun annotations
+}
+
+// Data trigger data the templates are rendered with, attributes not related to the trigger are nil.
+type Data struct {
+	Event       *Event       // webhook event, nil when triggered by a PipelineRun
+	PR          *PullRequest // pull-request, nil when not triggered by a pull-request event
+	PipelineRun *PipelineRun // PipelineRun, nil when triggered by a webhook event
+}
+
+// ShortSHA abbreviates the commit SHA.
+func ShortSHA(sha string) string {
+	if len(sha) > shortSHALength {
+		return sha[:shortSHALength]
+	}
+	return sha
+}
+
+// Template compiled BuildRun template.
+type Template struct {
+	paramValues map[string]*gotemplate.Template // parameter values, by name
+	env         map[string]*gotemplate.Template // environment variables, by name
+	labels      map[string]*gotemplate.Template // label values, by key
+	outputImage *gotemplate.Template            // output image, nil when not informed
+}
+
+// compile compiles the named template, referring to missing map keys is an error.
+func compile(name, text string) (*gotemplate.Template, error) {
+	tmpl, err := gotemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// compileAll compiles the templates, named after the section and key.
+func compileAll(section string, texts map[string]string) (map[string]*gotemplate.Template, error) {
+	templates := make(map[string]*gotemplate.Template, len(texts))
+	for key, text := range texts {
+		tmpl, err := compile(fmt.Sprintf("%s.%s", section, key), text)
+		if err != nil {
+			return nil, err
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// render renders the template with the informed data.
+func render(tmpl *gotemplate.Template, data *Data) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// sortedKeys returns the map keys in order, so the BuildRun is rendered deterministically.
+func sortedKeys(m map[string]*gotemplate.Template) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parse parses and compiles the BuildRun template. Label keys must be valid, and can't be part of
+// the Shipwright Triggers domain.
+func Parse(value string) (*Template, error) {
+	var spec Spec
+	if err := yaml.UnmarshalStrict([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("invalid template spec: %w", err)
+	}
+	for key := range spec.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if strings.HasPrefix(key, fmt.Sprintf("%s/", filter.Prefix)) {
+			return nil, fmt.Errorf("label key %q is reserved", key)
+		}
+	}
+
+	var err error
+	t := &Template{}
+	if t.paramValues, err = compileAll("paramValues", spec.ParamValues); err != nil {
+		return nil, err
+	}
+	if t.env, err = compileAll("env", spec.Env); err != nil {
+		return nil, err
+	}
+	if t.labels, err = compileAll("labels", spec.Labels); err != nil {
+		return nil, err
+	}
+	if spec.OutputImage != "" {
+		if t.outputImage, err = compile("outputImage", spec.OutputImage); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ForBuild parses the template annotated on the Build, returns nil when the Build is not annotated.
+func ForBuild(b *buildapi.Build) (*Template, error) {
+	value, ok := b.GetAnnotations()[filter.BuildTemplate]
+	if !ok {
+		return nil, nil
+	}
+	return Parse(value)
+}
+
+// Apply renders the templates with the informed data on the BuildRun, parameter values and
+// environment variables with the same name are replaced. The output image is based on the Build
+// output, when the BuildRun does not inform one.
+func (t *Template) Apply(b *buildapi.Build, br *buildapi.BuildRun, data *Data) error {
+	for _, name := range sortedKeys(t.paramValues) {
+		value, err := render(t.paramValues[name], data)
+		if err != nil {
+			return err
+		}
+		paramValue := buildapi.ParamValue{
+			Name:        name,
+			SingleValue: &buildapi.SingleValue{Value: &value},
+		}
+		replaced := false
+		for i := range br.Spec.ParamValues {
+			if br.Spec.ParamValues[i].Name == name {
+				br.Spec.ParamValues[i], replaced = paramValue, true
+			}
+		}
+		if !replaced {
+			br.Spec.ParamValues = append(br.Spec.ParamValues, paramValue)
+		}
+	}
+
+	for _, name := range sortedKeys(t.env) {
+		value, err := render(t.env[name], data)
+		if err != nil {
+			return err
+		}
+		env := corev1.EnvVar{Name: name, Value: value}
+		replaced := false
+		for i := range br.Spec.Env {
+			if br.Spec.Env[i].Name == name {
+				br.Spec.Env[i], replaced = env, true
+			}
+		}
+		if !replaced {
+			br.Spec.Env = append(br.Spec.Env, env)
+		}
+	}
+
+	if len(t.labels) > 0 && br.Labels == nil {
+		br.Labels = map[string]string{}
+	}
+	for _, key := range sortedKeys(t.labels) {
+		value, err := render(t.labels[key], data)
+		if err != nil {
+			return err
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid label %q value %q: %s", key, value, strings.Join(errs, ", "))
+		}
+		br.Labels[key] = value
+	}
+
+	if t.outputImage != nil {
+		image, err := render(t.outputImage, data)
+		if err != nil {
+			return err
+		}
+		if br.Spec.Output == nil {
+			br.Spec.Output = b.Spec.Output.DeepCopy()
+		}
+		br.Spec.Output.Image = image
+	}
+	return nil
+}
+
+// Render renders the template annotated on the Build with the informed data on the BuildRun, Builds
+// without template are left untouched.
+func Render(b *buildapi.Build, br *buildapi.BuildRun, data *Data) error {
+	tmpl, err := ForBuild(b)
+	if err != nil || tmpl == nil {
+		return err
+	}
+	return tmpl.Apply(b, br, data)
+}
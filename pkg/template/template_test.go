@@ -0,0 +1,183 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{{
+		name: "YAML template",
+		value: `
+paramValues:
+  commit: "{{ .Event.HeadSHA }}"
+env:
+  GIT_REF: "{{ .Event.Ref }}"
+labels:
+  app.kubernetes.io/version: "{{ .Event.ShortSHA }}"
+outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA }}"
+`,
+	}, {
+		name:  "JSON template",
+		value: `{"paramValues": {"pr": "{{ .PR.Number }}"}}`,
+	}, {
+		name:    "unknown field",
+		value:   `image: "ghcr.io/shipwright-io/app"`,
+		wantErr: true,
+	}, {
+		name:    "invalid Go template",
+		value:   `outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA"`,
+		wantErr: true,
+	}, {
+		name:    "invalid label key",
+		value:   `labels: {"invalid key": "value"}`,
+		wantErr: true,
+	}, {
+		name:    "reserved label key",
+		value:   `labels: {"triggers.shipwright.io/build-name": "value"}`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShortSHA(t *testing.T) {
+	if got := ShortSHA("0123456789abcdef"); got != "0123456" {
+		t.Errorf("ShortSHA() = %q, want %q", got, "0123456")
+	}
+	if got := ShortSHA("0123"); got != "0123" {
+		t.Errorf("ShortSHA() = %q, want %q", got, "0123")
+	}
+}
+
+func TestRender(t *testing.T) {
+	pushSecret := "registry-credentials"
+	newBuild := func(template string) *buildapi.Build {
+		b := &buildapi.Build{
+			ObjectMeta: metav1.ObjectMeta{Name: "build"},
+			Spec: buildapi.BuildSpec{Output: buildapi.Image{
+				Image:      "ghcr.io/shipwright-io/app:latest",
+				PushSecret: &pushSecret,
+			}},
+		}
+		if template != "" {
+			b.SetAnnotations(map[string]string{filter.BuildTemplate: template})
+		}
+		return b
+	}
+
+	eventData := &Data{
+		Event: &Event{
+			Ref:      "refs/heads/main",
+			HeadSHA:  "0123456789abcdef",
+			ShortSHA: "0123456",
+		},
+		PR: &PullRequest{Number: 42},
+	}
+
+	t.Run("Build without template", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		br := &buildapi.BuildRun{}
+		g.Expect(Render(newBuild(""), br, eventData)).To(gomega.Succeed())
+		g.Expect(br).To(gomega.Equal(&buildapi.BuildRun{}))
+	})
+
+	t.Run("event data", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := newBuild(`
+paramValues:
+  commit: "{{ .Event.HeadSHA }}"
+  pr: "{{ .PR.Number }}"
+env:
+  GIT_REF: "{{ .Event.Ref }}"
+labels:
+  app.kubernetes.io/version: "{{ .Event.ShortSHA }}"
+outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA }}"
+`)
+		previous := "previous"
+		br := &buildapi.BuildRun{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{filter.BuildName: "build"}},
+			Spec: buildapi.BuildRunSpec{
+				ParamValues: []buildapi.ParamValue{{
+					Name:        "commit",
+					SingleValue: &buildapi.SingleValue{Value: &previous},
+				}},
+				Env: []corev1.EnvVar{{Name: "DEBUG", Value: "true"}},
+			},
+		}
+		g.Expect(Render(b, br, eventData)).To(gomega.Succeed())
+
+		commit, pr := "0123456789abcdef", "42"
+		g.Expect(br.Spec.ParamValues).To(gomega.Equal([]buildapi.ParamValue{{
+			Name:        "commit",
+			SingleValue: &buildapi.SingleValue{Value: &commit},
+		}, {
+			Name:        "pr",
+			SingleValue: &buildapi.SingleValue{Value: &pr},
+		}}))
+		g.Expect(br.Spec.Env).To(gomega.Equal([]corev1.EnvVar{
+			{Name: "DEBUG", Value: "true"},
+			{Name: "GIT_REF", Value: "refs/heads/main"},
+		}))
+		g.Expect(br.GetLabels()).To(gomega.Equal(map[string]string{
+			filter.BuildName:            "build",
+			"app.kubernetes.io/version": "0123456",
+		}))
+		g.Expect(br.Spec.Output).To(gomega.Equal(&buildapi.Image{
+			Image:      "ghcr.io/shipwright-io/app:0123456",
+			PushSecret: &pushSecret,
+		}))
+	})
+
+	t.Run("PipelineRun data", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := newBuild(`{"env": {"PIPELINE": "{{ .PipelineRun.Name }}-{{ index .PipelineRun.Labels \"app\" }}"}}`)
+		br := &buildapi.BuildRun{}
+		err := Render(b, br, &Data{PipelineRun: &PipelineRun{
+			Name:   "pipelinerun",
+			Labels: map[string]string{"app": "frontend"},
+		}})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(br.Spec.Env).To(gomega.Equal([]corev1.EnvVar{
+			{Name: "PIPELINE", Value: "pipelinerun-frontend"},
+		}))
+	})
+
+	t.Run("event data is not available for PipelineRuns", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := newBuild(`outputImage: "ghcr.io/shipwright-io/app:{{ .Event.ShortSHA }}"`)
+		err := Render(b, &buildapi.BuildRun{}, &Data{PipelineRun: &PipelineRun{Name: "pipelinerun"}})
+		g.Expect(err).To(gomega.HaveOccurred())
+	})
+
+	t.Run("invalid label value", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := newBuild(`labels: {"ref": "{{ .Event.Ref }}"}`)
+		g.Expect(Render(b, &buildapi.BuildRun{}, eventData)).ToNot(gomega.Succeed())
+	})
+}
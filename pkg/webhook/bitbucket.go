@@ -0,0 +1,338 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+
+	"github.com/google/go-github/v53/github"
+)
+
+const (
+	// BitbucketProvider Bitbucket provider name, Cloud and Server share the same webhook headers.
+	BitbucketProvider = "bitbucket"
+	// BitbucketCloudProvider Bitbucket Cloud provider name, informed on the Event.
+	BitbucketCloudProvider = "bitbucket-cloud"
+	// BitbucketServerProvider Bitbucket Server (and Data Center) provider name, informed on the Event.
+	BitbucketServerProvider = "bitbucket-server"
+
+	// bitbucketEventHeader header carrying the Bitbucket event key.
+	bitbucketEventHeader = "X-Event-Key"
+	// bitbucketCloudDeliveryHeader header carrying the Bitbucket Cloud delivery identifier.
+	bitbucketCloudDeliveryHeader = "X-Request-UUID"
+	// bitbucketServerDeliveryHeader header carrying the Bitbucket Server delivery identifier.
+	bitbucketServerDeliveryHeader = "X-Request-Id"
+	// bitbucketSignatureHeader header carrying the payload HMAC signature.
+	bitbucketSignatureHeader = "X-Hub-Signature"
+
+	// bitbucketCloudPush Bitbucket Cloud push event key.
+	bitbucketCloudPush = "repo:push"
+	// bitbucketCloudPullRequestPrefix Bitbucket Cloud pull-request event keys prefix.
+	bitbucketCloudPullRequestPrefix = "pullrequest:"
+	// bitbucketServerRefsChanged Bitbucket Server push event key.
+	bitbucketServerRefsChanged = "repo:refs_changed"
+	// bitbucketServerPullRequestPrefix Bitbucket Server pull-request event keys prefix.
+	bitbucketServerPullRequestPrefix = "pr:"
+)
+
+// bitbucketCloudPullRequestEvents Bitbucket Cloud pull-request event keys handled, and the
+// respective neutral action.
+var bitbucketCloudPullRequestEvents = map[string]PullRequestAction{
+	"pullrequest:created":   PullRequestOpened,
+	"pullrequest:updated":   PullRequestSynchronized,
+	"pullrequest:fulfilled": PullRequestClosed,
+	"pullrequest:rejected":  PullRequestClosed,
+}
+
+// bitbucketServerPullRequestEvents Bitbucket Server pull-request event keys handled, and the
+// respective neutral action, "pr:from_ref_updated" is sent when new commits are pushed to the
+// source branch.
+var bitbucketServerPullRequestEvents = map[string]PullRequestAction{
+	"pr:opened":           PullRequestOpened,
+	"pr:from_ref_updated": PullRequestSynchronized,
+	"pr:merged":           PullRequestClosed,
+	"pr:declined":         PullRequestClosed,
+	"pr:deleted":          PullRequestClosed,
+}
+
+// BitbucketCloudLinks Bitbucket Cloud repository links.
+type BitbucketCloudLinks struct {
+	HTML struct {
+		Href string `json:"href"`
+	} `json:"html"`
+}
+
+// BitbucketCloudRepository Bitbucket Cloud repository attributes informed on events.
+type BitbucketCloudRepository struct {
+	FullName string              `json:"full_name"`
+	Links    BitbucketCloudLinks `json:"links"`
+}
+
+// BitbucketCloudActor Bitbucket Cloud user who originated the event.
+type BitbucketCloudActor struct {
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+// login returns the actor nickname, falling back to the display name.
+func (a *BitbucketCloudActor) login() string {
+	if a.Nickname != "" {
+		return a.Nickname
+	}
+	return a.DisplayName
+}
+
+// BitbucketServerActor Bitbucket Server user who originated the event.
+type BitbucketServerActor struct {
+	Name string `json:"name"`
+}
+
+// BitbucketCloudRef Bitbucket Cloud branch or tag state, informed on push changes.
+type BitbucketCloudRef struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Target struct {
+		Hash    string `json:"hash"`
+		Message string `json:"message"`
+	} `json:"target"`
+}
+
+// BitbucketCloudPushEvent Bitbucket Cloud "repo:push" payload.
+type BitbucketCloudPushEvent struct {
+	Push struct {
+		Changes []struct {
+			New *BitbucketCloudRef `json:"new"`
+			Old *BitbucketCloudRef `json:"old"`
+		} `json:"changes"`
+	} `json:"push"`
+	Actor      BitbucketCloudActor      `json:"actor"`
+	Repository BitbucketCloudRepository `json:"repository"`
+}
+
+// BitbucketCloudPullRequestEndpoint Bitbucket Cloud pull-request source or destination.
+type BitbucketCloudPullRequestEndpoint struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Commit struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+	Repository BitbucketCloudRepository `json:"repository"`
+}
+
+// BitbucketCloudPullRequestEvent Bitbucket Cloud "pullrequest:*" payload.
+type BitbucketCloudPullRequestEvent struct {
+	PullRequest struct {
+		ID          int                               `json:"id"`
+		Source      BitbucketCloudPullRequestEndpoint `json:"source"`
+		Destination BitbucketCloudPullRequestEndpoint `json:"destination"`
+	} `json:"pullrequest"`
+	Actor      BitbucketCloudActor      `json:"actor"`
+	Repository BitbucketCloudRepository `json:"repository"`
+}
+
+// BitbucketServerRepository Bitbucket Server repository attributes informed on events.
+type BitbucketServerRepository struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// browseURL returns the repository browse URL, the first "self" link.
+func (r *BitbucketServerRepository) browseURL() string {
+	if len(r.Links.Self) == 0 {
+		return ""
+	}
+	return r.Links.Self[0].Href
+}
+
+// BitbucketServerRefsChangedEvent Bitbucket Server "repo:refs_changed" payload.
+type BitbucketServerRefsChangedEvent struct {
+	Actor      BitbucketServerActor      `json:"actor"`
+	Repository BitbucketServerRepository `json:"repository"`
+	Changes    []struct {
+		RefID  string `json:"refId"`
+		ToHash string `json:"toHash"`
+		Type   string `json:"type"`
+	} `json:"changes"`
+}
+
+// BitbucketServerPullRequestRef Bitbucket Server pull-request "from" or "to" reference.
+type BitbucketServerPullRequestRef struct {
+	ID           string                    `json:"id"`
+	DisplayID    string                    `json:"displayId"`
+	LatestCommit string                    `json:"latestCommit"`
+	Repository   BitbucketServerRepository `json:"repository"`
+}
+
+// BitbucketServerPullRequestEvent Bitbucket Server "pr:*" payload.
+type BitbucketServerPullRequestEvent struct {
+	Actor       BitbucketServerActor `json:"actor"`
+	PullRequest struct {
+		ID      int                           `json:"id"`
+		FromRef BitbucketServerPullRequestRef `json:"fromRef"`
+		ToRef   BitbucketServerPullRequestRef `json:"toRef"`
+	} `json:"pullRequest"`
+}
+
+// bitbucketCloudPushEventToEvent transforms the push event using the first change which does not
+// delete a branch or tag, Bitbucket may group several reference changes on a single delivery.
+func bitbucketCloudPushEventToEvent(push *BitbucketCloudPushEvent) (*Event, error) {
+	for _, change := range push.Push.Changes {
+		if change.New == nil {
+			continue
+		}
+		ref := fmt.Sprintf("%s%s", branchRefPrefix, change.New.Name)
+		if change.New.Type == "tag" {
+			ref = fmt.Sprintf("%s%s", tagRefPrefix, change.New.Name)
+		}
+		return &Event{
+			Provider:    BitbucketCloudProvider,
+			Name:        buildapi.GitHubPushEvent,
+			RepoURL:     push.Repository.Links.HTML.Href,
+			Ref:         ref,
+			Branch:      BranchFromRef(ref),
+			Tag:         TagFromRef(ref),
+			HeadSHA:     change.New.Target.Hash,
+			HeadMessage: change.New.Target.Message,
+			Author:      push.Actor.login(),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: push only deletes references", ErrEventIgnored)
+}
+
+// bitbucketCloudPullRequestEventToEvent transforms the pull-request event, Builds are matched
+// against the destination branch.
+func bitbucketCloudPullRequestEventToEvent(
+	pr *BitbucketCloudPullRequestEvent,
+	action PullRequestAction,
+) *Event {
+	repoURL := pr.PullRequest.Destination.Repository.Links.HTML.Href
+	if repoURL == "" {
+		repoURL = pr.Repository.Links.HTML.Href
+	}
+	return &Event{
+		Provider:    BitbucketCloudProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     repoURL,
+		Ref:         fmt.Sprintf("%s%s", branchRefPrefix, pr.PullRequest.Source.Branch.Name),
+		Branch:      pr.PullRequest.Destination.Branch.Name,
+		HeadSHA:     pr.PullRequest.Source.Commit.Hash,
+		Author:      pr.Actor.login(),
+		PullRequest: pr.PullRequest.ID,
+		Action:      action,
+		Fork: isFork(
+			pr.PullRequest.Source.Repository.Links.HTML.Href,
+			pr.PullRequest.Destination.Repository.Links.HTML.Href,
+		),
+	}
+}
+
+// bitbucketServerRefsChangedEventToEvent transforms the push event using the first change which
+// does not delete a branch or tag.
+func bitbucketServerRefsChangedEventToEvent(push *BitbucketServerRefsChangedEvent) (*Event, error) {
+	for _, change := range push.Changes {
+		if change.Type == "DELETE" {
+			continue
+		}
+		return &Event{
+			Provider: BitbucketServerProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  push.Repository.browseURL(),
+			Ref:      change.RefID,
+			Branch:   BranchFromRef(change.RefID),
+			Tag:      TagFromRef(change.RefID),
+			HeadSHA:  change.ToHash,
+			Author:   push.Actor.Name,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: push only deletes references", ErrEventIgnored)
+}
+
+// bitbucketServerPullRequestEventToEvent transforms the pull-request event, Builds are matched
+// against the target branch.
+func bitbucketServerPullRequestEventToEvent(
+	pr *BitbucketServerPullRequestEvent,
+	action PullRequestAction,
+) *Event {
+	return &Event{
+		Provider:    BitbucketServerProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     pr.PullRequest.ToRef.Repository.browseURL(),
+		Ref:         fmt.Sprintf("refs/pull-requests/%d/from", pr.PullRequest.ID),
+		Branch:      pr.PullRequest.ToRef.DisplayID,
+		HeadSHA:     pr.PullRequest.FromRef.LatestCommit,
+		Author:      pr.Actor.Name,
+		PullRequest: pr.PullRequest.ID,
+		Action:      action,
+		MergeRef:    fmt.Sprintf("refs/pull-requests/%d/merge", pr.PullRequest.ID),
+		Fork: isFork(
+			pr.PullRequest.FromRef.Repository.browseURL(),
+			pr.PullRequest.ToRef.Repository.browseURL(),
+		),
+	}
+}
+
+// ParseBitbucketEvent parses the informed payload based on the Bitbucket event key (header), both
+// Bitbucket Cloud and Server event keys are supported, only push and pull-request events which may
+// carry new commits are transformed, others are ignored.
+func ParseBitbucketEvent(eventKey string, payload []byte) (*Event, error) {
+	switch {
+	case eventKey == bitbucketCloudPush:
+		var push BitbucketCloudPushEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return nil, err
+		}
+		return bitbucketCloudPushEventToEvent(&push)
+	case strings.HasPrefix(eventKey, bitbucketCloudPullRequestPrefix):
+		action, ok := bitbucketCloudPullRequestEvents[eventKey]
+		if !ok {
+			break
+		}
+		var pr BitbucketCloudPullRequestEvent
+		if err := json.Unmarshal(payload, &pr); err != nil {
+			return nil, err
+		}
+		return bitbucketCloudPullRequestEventToEvent(&pr, action), nil
+	case eventKey == bitbucketServerRefsChanged:
+		var push BitbucketServerRefsChangedEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return nil, err
+		}
+		return bitbucketServerRefsChangedEventToEvent(&push)
+	case strings.HasPrefix(eventKey, bitbucketServerPullRequestPrefix):
+		action, ok := bitbucketServerPullRequestEvents[eventKey]
+		if !ok {
+			break
+		}
+		var pr BitbucketServerPullRequestEvent
+		if err := json.Unmarshal(payload, &pr); err != nil {
+			return nil, err
+		}
+		return bitbucketServerPullRequestEventToEvent(&pr, action), nil
+	}
+	return nil, fmt.Errorf("%w: event key %q", ErrEventIgnored, eventKey)
+}
+
+// validateBitbucketSignature validates the request payload signature against the token, Bitbucket
+// Cloud and Server sign the payload the same way GitHub does, using a different header.
+func validateBitbucketSignature(r *http.Request, payload, token []byte) error {
+	signature := r.Header.Get(bitbucketSignatureHeader)
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+	return github.ValidateSignature(signature, payload, token)
+}
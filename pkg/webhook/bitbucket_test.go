@@ -0,0 +1,164 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+func TestParseBitbucketEvent(t *testing.T) {
+	cloudPushDeleted := stubs.BitbucketCloudPushEvent("branch", stubs.Branch)
+	cloudPushDeleted["push"].(map[string]interface{})["changes"].([]map[string]interface{})[0]["new"] = nil
+
+	tests := []struct {
+		name        string
+		eventKey    string
+		payload     interface{}
+		want        *Event
+		wantErr     bool
+		wantIgnored bool
+	}{{
+		name:     "cloud push event",
+		eventKey: "repo:push",
+		payload:  stubs.BitbucketCloudPushEvent("branch", stubs.Branch),
+		want: &Event{
+			Provider: BitbucketCloudProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.BitbucketCloudRepoURL,
+			Ref:      stubs.GitRef,
+			Branch:   stubs.Branch,
+			HeadSHA:  stubs.HeadCommitID,
+		},
+	}, {
+		name:     "cloud push event on a tag",
+		eventKey: "repo:push",
+		payload:  stubs.BitbucketCloudPushEvent("tag", "v1.2.3"),
+		want: &Event{
+			Provider: BitbucketCloudProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.BitbucketCloudRepoURL,
+			Ref:      "refs/tags/v1.2.3",
+			Tag:      "v1.2.3",
+			HeadSHA:  stubs.HeadCommitID,
+		},
+	}, {
+		name:        "cloud push event deleting a branch is ignored",
+		eventKey:    "repo:push",
+		payload:     cloudPushDeleted,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:     "cloud pull-request created event",
+		eventKey: "pullrequest:created",
+		payload:  stubs.BitbucketCloudPullRequestEvent(),
+		want: &Event{
+			Provider:    BitbucketCloudProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.BitbucketCloudRepoURL,
+			Ref:         "refs/heads/feature",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestOpened,
+		},
+	}, {
+		name:        "cloud pull-request approved event is ignored",
+		eventKey:    "pullrequest:approved",
+		payload:     stubs.BitbucketCloudPullRequestEvent(),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:     "server refs changed event",
+		eventKey: "repo:refs_changed",
+		payload:  stubs.BitbucketServerRefsChangedEvent(stubs.GitRef, "UPDATE"),
+		want: &Event{
+			Provider: BitbucketServerProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.BitbucketServerRepoURL,
+			Ref:      stubs.GitRef,
+			Branch:   stubs.Branch,
+			HeadSHA:  stubs.HeadCommitID,
+			Author:   stubs.PusherLogin,
+		},
+	}, {
+		name:        "server refs changed event deleting a branch is ignored",
+		eventKey:    "repo:refs_changed",
+		payload:     stubs.BitbucketServerRefsChangedEvent(stubs.GitRef, "DELETE"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:     "server pull-request source updated event",
+		eventKey: "pr:from_ref_updated",
+		payload:  stubs.BitbucketServerPullRequestEvent(),
+		want: &Event{
+			Provider:    BitbucketServerProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.BitbucketServerRepoURL,
+			Ref:         "refs/pull-requests/1/from",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestSynchronized,
+			MergeRef:    "refs/pull-requests/1/merge",
+		},
+	}, {
+		name:     "server pull-request merged event",
+		eventKey: "pr:merged",
+		payload:  stubs.BitbucketServerPullRequestEvent(),
+		want: &Event{
+			Provider:    BitbucketServerProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.BitbucketServerRepoURL,
+			Ref:         "refs/pull-requests/1/from",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestClosed,
+			MergeRef:    "refs/pull-requests/1/merge",
+		},
+	}, {
+		name:        "server pull-request comment event is ignored",
+		eventKey:    "pr:comment:added",
+		payload:     stubs.BitbucketServerPullRequestEvent(),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "server ping event is ignored",
+		eventKey:    "diagnostics:ping",
+		payload:     map[string]interface{}{},
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:     "bogus payload",
+		eventKey: "repo:push",
+		payload:  "bogus",
+		wantErr:  true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBitbucketEvent(tt.eventKey, marshalOrFail(t, tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseBitbucketEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantIgnored != errors.Is(err, ErrEventIgnored) {
+				t.Errorf("ParseBitbucketEvent() error = %v, wantIgnored %v", err, tt.wantIgnored)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBitbucketEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
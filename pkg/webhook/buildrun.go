@@ -0,0 +1,167 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// repoLabelValueLength amount of hex characters from the repository URL hash used as label
+	// value.
+	repoLabelValueLength = 16
+	// eventHashLength amount of hex characters from the event hash used as BuildRun name suffix.
+	eventHashLength = 12
+	// maxBuildRunNameLength maximum BuildRun name length, the name is employed as label value on
+	// the objects created for the BuildRun.
+	maxBuildRunNameLength = 63
+)
+
+// pinBuildSpecToRevision returns a copy of the Build spec with the Git revision set to the informed
+// commit SHA or reference, returns nil when the Build does not have a Git source.
+func pinBuildSpecToRevision(b *buildapi.Build, revision string) *buildapi.BuildSpec {
+	if b.Spec.Source == nil || b.Spec.Source.Git == nil {
+		return nil
+	}
+	spec := b.Spec.DeepCopy()
+	spec.Source.Git.Revision = &revision
+	return spec
+}
+
+// repoLabelValue hashes the sanitized repository URL, so different URL forms of the same repository
+// result in the same label value.
+func repoLabelValue(repoURL string) string {
+	if sanitized, err := inventory.SanitizeURL(repoURL); err == nil {
+		repoURL = sanitized
+	}
+	sum := sha256.Sum256([]byte(repoURL))
+	return hex.EncodeToString(sum[:])[:repoLabelValueLength]
+}
+
+// pullRequestLabels returns the labels identifying the BuildRuns issued for the event pull-request.
+func pullRequestLabels(event *Event) map[string]string {
+	return map[string]string{
+		filter.WebHookRepo:        repoLabelValue(event.RepoURL),
+		filter.WebHookPullRequest: strconv.Itoa(event.PullRequest),
+	}
+}
+
+// eventHash hashes the Build namespace and name, the event kind, Git reference and head commit SHA,
+// identifying the BuildRun issued for the event.
+func eventHash(event *Event, b *buildapi.Build) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		b.GetNamespace(),
+		b.GetName(),
+		string(event.Name),
+		event.Ref,
+		event.HeadSHA,
+	}, "\n")))
+	return hex.EncodeToString(sum[:])[:eventHashLength]
+}
+
+// buildRunName returns the BuildRun name for the Build and event hash, the Build name is truncated
+// to respect the maximum name length.
+func buildRunName(buildName, hash string) string {
+	if maxPrefix := maxBuildRunNameLength - len(hash) - 1; len(buildName) > maxPrefix {
+		buildName = strings.TrimRight(buildName[:maxPrefix], "-.")
+	}
+	return fmt.Sprintf("%s-%s", buildName, hash)
+}
+
+// buildRunRevision returns the revision the BuildRun should build, pull-requests may build the
+// merge reference instead of the head commit when annotated on the Build.
+func buildRunRevision(event *Event, b *buildapi.Build) string {
+	if event.IsPullRequest() &&
+		b.GetAnnotations()[filter.BuildPullRequestRevision] == filter.PullRequestRevisionMerge &&
+		event.MergeRef != "" {
+		return event.MergeRef
+	}
+	return event.HeadSHA
+}
+
+// generateBuildRun generates a BuildRun instance for the informed Build, annotated with the event
+// attributes which triggered it. When the event carries the head commit SHA, the BuildRun name is
+// derived from the event hash, so replays of the same event resolve to the same BuildRun, and the
+// BuildRun embeds a copy of the Build spec pinned to the commit. Otherwise, or when running
+// pull-request commands, the name is randomly generated using the Build name as base, and the
+// Build is referred by name. Pull-request BuildRuns are labeled with the repository and
+// pull-request number.
+func generateBuildRun(event *Event, b *buildapi.Build) *buildapi.BuildRun {
+	buildName := b.GetName()
+	br := &buildapi.BuildRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    b.GetNamespace(),
+			GenerateName: fmt.Sprintf("%s-", buildName),
+			Labels: map[string]string{
+				filter.BuildName: buildName,
+			},
+			Annotations: map[string]string{
+				filter.WebHookProvider:  event.Provider,
+				filter.WebHookEventName: string(event.Name),
+				filter.WebHookRepoURL:   event.RepoURL,
+				filter.WebHookGitRef:    event.Ref,
+			},
+		},
+	}
+	if event.Branch != "" {
+		br.Annotations[filter.WebHookBranch] = event.Branch
+	}
+	if event.IsTag() {
+		br.Annotations[filter.WebHookGitTag] = event.Tag
+	}
+	if event.IsPullRequest() {
+		for k, v := range pullRequestLabels(event) {
+			br.Labels[k] = v
+		}
+	}
+
+	// the Build name and spec are mutually exclusive, a pinned BuildRun only carries the spec
+	if event.HeadSHA != "" {
+		br.Annotations[filter.WebHookCommitSHA] = event.HeadSHA
+		// commands explicitly ask for new BuildRuns of the same commit
+		if !event.IsPullRequestCommand() {
+			hash := eventHash(event, b)
+			br.Labels[filter.WebHookEventHash] = hash
+			br.SetName(buildRunName(buildName, hash))
+			br.SetGenerateName("")
+		}
+		if spec := pinBuildSpecToRevision(b, buildRunRevision(event, b)); spec != nil {
+			br.Spec.Build.Spec = spec
+			return br
+		}
+	}
+	br.Spec.Build.Name = &buildName
+	return br
+}
+
+// templateData returns the event attributes available to the Build template.
+func templateData(event *Event) *template.Data {
+	data := &template.Data{Event: &template.Event{
+		Provider: event.Provider,
+		Name:     string(event.Name),
+		RepoURL:  event.RepoURL,
+		Ref:      event.Ref,
+		Branch:   event.Branch,
+		Tag:      event.Tag,
+		HeadSHA:  event.HeadSHA,
+		ShortSHA: template.ShortSHA(event.HeadSHA),
+		Author:   event.Author,
+	}}
+	if event.IsPullRequest() {
+		data.PR = &template.PullRequest{Number: event.PullRequest}
+	}
+	return data
+}
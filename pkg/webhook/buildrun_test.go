@@ -0,0 +1,203 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+func TestGenerateBuildRun(t *testing.T) {
+	revision := "main"
+	b := stubs.ShipwrightBuild("ghcr.io/shipwright-io", "build")
+	b.Spec.Source.Git.Revision = &revision
+
+	t.Run("event with head commit SHA embeds the pinned Build spec", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		br := generateBuildRun(&Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.RepoURL,
+			Ref:      stubs.GitRef,
+			Branch:   stubs.Branch,
+			HeadSHA:  stubs.HeadCommitID,
+		}, b)
+
+		g.Expect(br.GetNamespace()).To(gomega.Equal(b.GetNamespace()))
+		g.Expect(br.GetGenerateName()).To(gomega.BeEmpty())
+		g.Expect(br.GetName()).To(gomega.HavePrefix("build-"))
+		g.Expect(br.GetLabels()).To(gomega.HaveKeyWithValue(
+			filter.WebHookEventHash, br.GetName()[len("build-"):]))
+		g.Expect(br.Spec.Build.Name).To(gomega.BeNil())
+		g.Expect(br.Spec.Build.Spec).ToNot(gomega.BeNil())
+		g.Expect(*br.Spec.Build.Spec.Source.Git.Revision).To(gomega.Equal(stubs.HeadCommitID))
+		g.Expect(br.GetLabels()).To(gomega.HaveKeyWithValue(filter.BuildName, "build"))
+		g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+			filter.WebHookCommitSHA, stubs.HeadCommitID))
+
+		// the original Build must be left untouched
+		g.Expect(*b.Spec.Source.Git.Revision).To(gomega.Equal(revision))
+	})
+
+	t.Run("event without head commit SHA refers to the Build by name", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		br := generateBuildRun(&Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.RepoURL,
+			Ref:      stubs.GitRef,
+			Branch:   stubs.Branch,
+		}, b)
+
+		g.Expect(br.GetGenerateName()).To(gomega.Equal("build-"))
+		g.Expect(br.Spec.Build.Spec).To(gomega.BeNil())
+		g.Expect(*br.Spec.Build.Name).To(gomega.Equal("build"))
+		g.Expect(br.GetAnnotations()).ToNot(gomega.HaveKey(filter.WebHookCommitSHA))
+	})
+}
+
+func TestGenerateBuildRunPullRequest(t *testing.T) {
+	event := &Event{
+		Provider:    GitHubProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     stubs.RepoURL,
+		Ref:         "refs/pull/1/head",
+		Branch:      stubs.Branch,
+		HeadSHA:     stubs.HeadCommitID,
+		PullRequest: stubs.PullRequestNumber,
+		Action:      PullRequestOpened,
+		MergeRef:    "refs/pull/1/merge",
+	}
+
+	t.Run("pull-request BuildRun builds the head commit", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := stubs.ShipwrightBuild("ghcr.io/shipwright-io", "build")
+		br := generateBuildRun(event, b)
+
+		g.Expect(*br.Spec.Build.Spec.Source.Git.Revision).To(gomega.Equal(stubs.HeadCommitID))
+		g.Expect(br.GetLabels()).To(gomega.HaveKeyWithValue(filter.WebHookPullRequest, "1"))
+		g.Expect(br.GetLabels()).To(gomega.HaveKeyWithValue(
+			filter.WebHookRepo, repoLabelValue(stubs.RepoURL)))
+	})
+
+	t.Run("pull-request BuildRun builds the merge reference when annotated", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := stubs.ShipwrightBuild("ghcr.io/shipwright-io", "build")
+		b.SetAnnotations(map[string]string{
+			filter.BuildPullRequestRevision: filter.PullRequestRevisionMerge,
+		})
+		br := generateBuildRun(event, b)
+
+		g.Expect(*br.Spec.Build.Spec.Source.Git.Revision).To(gomega.Equal("refs/pull/1/merge"))
+		g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+			filter.WebHookCommitSHA, stubs.HeadCommitID))
+	})
+}
+
+func TestEventHash(t *testing.T) {
+	b := stubs.ShipwrightBuild("ghcr.io/shipwright-io", "build")
+	push := &Event{
+		Name:    buildapi.GitHubPushEvent,
+		Ref:     stubs.GitRef,
+		HeadSHA: stubs.HeadCommitID,
+	}
+	pullRequest := &Event{
+		Name:    buildapi.GitHubPullRequestEvent,
+		Ref:     "refs/pull/1/head",
+		HeadSHA: stubs.HeadCommitID,
+	}
+	anotherCommit := &Event{
+		Name:    buildapi.GitHubPushEvent,
+		Ref:     stubs.GitRef,
+		HeadSHA: "another-commit-id",
+	}
+	anotherBuild := stubs.ShipwrightBuild("ghcr.io/shipwright-io", "another-build")
+
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		equal bool
+	}{{
+		name:  "same event and Build",
+		a:     eventHash(push, b),
+		b:     eventHash(push, b.DeepCopy()),
+		equal: true,
+	}, {
+		name: "different event kind",
+		a:    eventHash(push, b),
+		b:    eventHash(pullRequest, b),
+	}, {
+		name: "different head commit",
+		a:    eventHash(push, b),
+		b:    eventHash(anotherCommit, b),
+	}, {
+		name: "different Build",
+		a:    eventHash(push, b),
+		b:    eventHash(push, anotherBuild),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a == tt.b; got != tt.equal {
+				t.Errorf("eventHash() %q == %q is %v, want %v", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestBuildRunName(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(buildRunName("build", "0123456789ab")).To(gomega.Equal("build-0123456789ab"))
+
+	name := buildRunName(strings.Repeat("a", 49)+"-build", "0123456789ab")
+	g.Expect(name).To(gomega.HaveLen(62))
+	g.Expect(name).To(gomega.Equal(strings.Repeat("a", 49) + "-0123456789ab"))
+}
+
+func TestRepoLabelValue(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	value := repoLabelValue("https://github.com/shipwright-io/sample-nodejs")
+	g.Expect(value).To(gomega.HaveLen(repoLabelValueLength))
+	g.Expect(repoLabelValue("git@github.com:shipwright-io/sample-nodejs.git")).To(gomega.Equal(value))
+	g.Expect(repoLabelValue("https://github.com/shipwright-io/another")).ToNot(gomega.Equal(value))
+}
+
+func TestTemplateData(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	push := &Event{
+		Provider: GitHubProvider,
+		Name:     buildapi.GitHubPushEvent,
+		Ref:      stubs.GitRef,
+		Branch:   stubs.Branch,
+		HeadSHA:  "0123456789abcdef",
+	}
+	data := templateData(push)
+	g.Expect(data.Event.HeadSHA).To(gomega.Equal("0123456789abcdef"))
+	g.Expect(data.Event.ShortSHA).To(gomega.Equal("0123456"))
+	g.Expect(data.Event.Ref).To(gomega.Equal(stubs.GitRef))
+	g.Expect(data.PR).To(gomega.BeNil())
+	g.Expect(data.PipelineRun).To(gomega.BeNil())
+
+	pullRequest := &Event{
+		Name:        buildapi.GitHubPullRequestEvent,
+		PullRequest: stubs.PullRequestNumber,
+	}
+	data = templateData(pullRequest)
+	g.Expect(data.PR).ToNot(gomega.BeNil())
+	g.Expect(data.PR.Number).To(gomega.Equal(stubs.PullRequestNumber))
+}
@@ -0,0 +1,267 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// commandPrefix prefix of the ChatOps commands, each command is informed on its own comment line.
+const commandPrefix = "/shp"
+
+// CommandName ChatOps command name.
+type CommandName string
+
+const (
+	// CommandBuild issues BuildRuns for the pull-request head, optionally only for the Build names
+	// informed as arguments.
+	CommandBuild CommandName = "build"
+	// CommandRetest issues the last failed pull-request BuildRuns again.
+	CommandRetest CommandName = "retest"
+	// CommandCancel cancels the pull-request BuildRuns still running.
+	CommandCancel CommandName = "cancel"
+)
+
+// commandNames ChatOps commands supported.
+var commandNames = map[CommandName]bool{
+	CommandBuild:  true,
+	CommandRetest: true,
+	CommandCancel: true,
+}
+
+// Command ChatOps command commented on a pull-request, i.e. "/shp build build-name".
+type Command struct {
+	Name CommandName `json:"name"`           // command name
+	Args []string    `json:"args,omitempty"` // command arguments
+}
+
+// ParseCommands extracts the ChatOps commands from the comment body, one command per line, lines
+// which are not commands, or carry unknown commands, are skipped.
+func ParseCommands(body string) []Command {
+	var commands []Command
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != commandPrefix {
+			continue
+		}
+		name := CommandName(fields[1])
+		if !commandNames[name] {
+			continue
+		}
+		commands = append(commands, Command{Name: name, Args: fields[2:]})
+	}
+	return commands
+}
+
+// latestPullRequestBuildRun returns the BuildRun created last out of the informed list, or nil when
+// the list is empty.
+func latestPullRequestBuildRun(brs []buildapi.BuildRun) *buildapi.BuildRun {
+	var latest *buildapi.BuildRun
+	for i := range brs {
+		br := &brs[i]
+		if latest == nil || latest.CreationTimestamp.Before(&br.CreationTimestamp) {
+			latest = br
+		}
+	}
+	return latest
+}
+
+// selectResultsByName returns the search results for the informed Build names, or all of them when
+// no names are informed.
+func selectResultsByName(results []inventory.SearchResult, names []string) []inventory.SearchResult {
+	if len(names) == 0 {
+		return results
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	selected := []inventory.SearchResult{}
+	for _, result := range results {
+		if wanted[result.BuildName.Name] {
+			selected = append(selected, result)
+		}
+	}
+	return selected
+}
+
+// retestPullRequestBuildRuns issues again the last pull-request BuildRun of each Build in the
+// search results, when it has failed, for the same commit. BuildRuns cancelled are not retested.
+// Returns the BuildRun names created.
+func (w *WebHook) retestPullRequestBuildRuns(
+	ctx context.Context,
+	logger logr.Logger,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	var created []string
+	for _, result := range results {
+		matchingLabels := client.MatchingLabels(pullRequestLabels(event))
+		matchingLabels[filter.BuildName] = result.BuildName.Name
+
+		var brs buildapi.BuildRunList
+		err := w.List(ctx, &brs, client.InNamespace(result.BuildName.Namespace), matchingLabels)
+		if err != nil {
+			return created, err
+		}
+		latest := latestPullRequestBuildRun(brs.Items)
+		if latest == nil || latest.IsCanceled() || !latest.Status.IsFailed(buildapi.Succeeded) {
+			continue
+		}
+		logger.V(0).Info("Retesting failed pull-request BuildRun", "buildrun", latest.GetName())
+
+		var b buildapi.Build
+		if err = w.Get(ctx, result.BuildName, &b); err != nil {
+			return created, err
+		}
+		// the BuildRun is generated again for the commit it has built, instead of copying it, so
+		// the status reported and the event identity are not carried over
+		retest := *event
+		annotations := latest.GetAnnotations()
+		if sha := annotations[filter.WebHookCommitSHA]; sha != "" {
+			retest.HeadSHA = sha
+		}
+		if ref := annotations[filter.WebHookGitRef]; ref != "" {
+			retest.Ref = ref
+		}
+		br := generateBuildRun(&retest, &b)
+		if err = createBuildRun(ctx, w.Client, w.limiter, &retest, &b, br); err != nil {
+			return created, err
+		}
+		created = append(created, br.GetName())
+	}
+	return created, nil
+}
+
+// groupResultsByNamespace groups the search results by the Build namespace, returns the namespaces
+// sorted and the results indexed by namespace.
+func groupResultsByNamespace(
+	results []inventory.SearchResult,
+) ([]string, map[string][]inventory.SearchResult) {
+	grouped := map[string][]inventory.SearchResult{}
+	var namespaces []string
+	for _, result := range results {
+		namespace := result.BuildName.Namespace
+		if _, ok := grouped[namespace]; !ok {
+			namespaces = append(namespaces, namespace)
+		}
+		grouped[namespace] = append(grouped[namespace], result)
+	}
+	sort.Strings(namespaces)
+	return namespaces, grouped
+}
+
+// runCommands executes the ChatOps commands, in the order informed, against the authorized search
+// results. Returns the BuildRun names issued or cancelled.
+func (w *WebHook) runCommands(
+	ctx context.Context,
+	logger logr.Logger,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	var buildRuns []string
+	for _, command := range event.Commands {
+		logger.V(0).Info("Running pull-request command", "command", command.Name, "args", command.Args)
+
+		var names []string
+		var err error
+		switch command.Name {
+		case CommandBuild:
+			names, err = w.issueBuildRuns(ctx, event, selectResultsByName(results, command.Args))
+		case CommandRetest:
+			names, err = w.retestPullRequestBuildRuns(ctx, logger, event, results)
+		case CommandCancel:
+			names, err = w.cancelPullRequestBuildRuns(ctx, logger, event, results)
+		}
+		buildRuns = append(buildRuns, names...)
+		if err != nil {
+			return buildRuns, err
+		}
+	}
+	return buildRuns, nil
+}
+
+// serveCommands handles the pull-request comments carrying ChatOps commands. The pull-request head
+// is resolved per namespace, using the head recorded from the pull-request events authorized on the
+// namespace Builds, and the Builds are searched in the Inventory using the pull-request base
+// repository and branch, only the Builds on the namespace are affected. The request is validated
+// against each Build's TriggerSecret, and the commands only affect the authorized Builds. The
+// commands are run inline or on the queue.
+func (w *WebHook) serveCommands(
+	rw http.ResponseWriter,
+	r *http.Request,
+	logger logr.Logger,
+	key string,
+	validateFn validateFn,
+	event *Event,
+) {
+	heads, err := w.searchPullRequestHeads(r.Context(), event)
+	if err != nil {
+		logger.V(0).Error(err, "Searching pull-request heads")
+		w.respond(rw, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	if len(heads) == 0 {
+		logger.V(0).Info("Pull-request head is unknown, skipping commands")
+		w.respond(rw, http.StatusOK, "pull-request head is unknown", nil)
+		return
+	}
+
+	resolved := map[string]*Event{}
+	results := []inventory.SearchResult{}
+	for namespace, head := range heads {
+		resolved[namespace] = head.resolve(event)
+		for _, result := range w.buildInventory.SearchForGit(
+			buildapi.GitHubWebHookTrigger,
+			buildapi.GitHubPullRequestEvent,
+			head.RepoURL,
+			head.Branch,
+		) {
+			if result.BuildName.Namespace == namespace {
+				results = append(results, result)
+			}
+		}
+	}
+	if len(results) == 0 {
+		logger.V(0).Info("No Builds matching pull-request command")
+		w.respond(rw, http.StatusOK, "no builds matching event", nil)
+		return
+	}
+
+	authorized := w.authorizeResults(r.Context(), logger, validateFn, results)
+	if len(authorized) == 0 {
+		logger.V(0).Info("Pull-request command is not authorized on any Build")
+		w.respond(rw, http.StatusUnauthorized, "request validation failed", nil)
+		return
+	}
+
+	w.dispatch(rw, r, logger, key, func(ctx context.Context) *outcome {
+		var buildRuns []string
+		namespaces, grouped := groupResultsByNamespace(authorized)
+		for _, namespace := range namespaces {
+			event := resolved[namespace]
+			logger := logger.WithValues("namespace", namespace, "branch", event.Branch,
+				"head-sha", event.HeadSHA)
+			names, err := w.runCommands(ctx, logger, event, grouped[namespace])
+			buildRuns = append(buildRuns, names...)
+			if err != nil {
+				logger.V(0).Error(err, "trying to run pull-request commands", "buildruns", buildRuns)
+				return &outcome{http.StatusInternalServerError, err.Error(), buildRuns}
+			}
+		}
+		logger.V(0).Info("Pull-request commands executed", "buildruns", buildRuns)
+		return &outcome{http.StatusOK, "commands executed", buildRuns}
+	})
+}
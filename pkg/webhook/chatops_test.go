@@ -0,0 +1,262 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Command
+	}{{
+		name: "build command without arguments",
+		body: "/shp build",
+		want: []Command{{Name: CommandBuild, Args: []string{}}},
+	}, {
+		name: "build command with Build names",
+		body: "/shp build build-a build-b",
+		want: []Command{{Name: CommandBuild, Args: []string{"build-a", "build-b"}}},
+	}, {
+		name: "several commands among regular lines",
+		body: "flaky test, trying again\r\n/shp cancel\r\n/shp retest\r\n",
+		want: []Command{
+			{Name: CommandCancel, Args: []string{}},
+			{Name: CommandRetest, Args: []string{}},
+		},
+	}, {
+		name: "unknown commands and commands in the middle of a line are skipped",
+		body: "/shp bogus\nplease /shp build",
+		want: nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCommands(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommands() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pullRequestHeadConfigMap returns the pull-request heads ConfigMap for the namespace, carrying the
+// stub pull-request head.
+func pullRequestHeadConfigMap(t *testing.T, namespace string) client.Object {
+	head := &PullRequestHead{
+		RepoURL:     stubs.RepoURL,
+		PullRequest: stubs.PullRequestNumber,
+		Branch:      stubs.Branch,
+		Ref:         "refs/pull/1/head",
+		HeadSHA:     stubs.HeadCommitID,
+		UpdatedAt:   metav1.Now(),
+	}
+	key := pullRequestHeadKey(&Event{RepoURL: stubs.RepoURL, PullRequest: stubs.PullRequestNumber})
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      PullRequestHeadsConfigMapName,
+			Labels:    map[string]string{filter.PullRequestHeads: "true"},
+		},
+		Data: map[string]string{key: string(marshalOrFail(t, head))},
+	}
+}
+
+func TestWebHook_ServeHTTPCommands(t *testing.T) {
+	buildWithPullRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-pull-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+
+	// pullRequestBuildRun returns a BuildRun previously issued for the stub pull-request, created
+	// minutes ago, with the informed succeeded condition status
+	pullRequestBuildRun := func(name string, minutes int, status corev1.ConditionStatus) client.Object {
+		br := stubs.ShipwrightBuildRun(name)
+		br.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Duration(minutes) * time.Minute))
+		br.SetLabels(map[string]string{
+			filter.BuildName:          buildWithPullRequestTrigger.GetName(),
+			filter.WebHookRepo:        repoLabelValue(stubs.RepoURL),
+			filter.WebHookPullRequest: "1",
+			filter.WebHookEventHash:   name,
+		})
+		br.SetAnnotations(map[string]string{
+			filter.WebHookBranch:         stubs.Branch,
+			filter.WebHookCommitSHA:      stubs.HeadCommitID,
+			filter.WebHookGitRef:         "refs/pull/1/head",
+			filter.WebHookReportedStatus: "failure",
+		})
+		buildName := buildWithPullRequestTrigger.GetName()
+		br.Spec.Build.Name = &buildName
+		br.Status.Conditions = buildapi.Conditions{{Type: buildapi.Succeeded, Status: status}}
+		return br
+	}
+
+	tests := []struct {
+		name          string
+		comment       string
+		objs          []client.Object
+		wantMessage   string
+		wantBuildRuns []string
+		wantCancelled []string
+		wantTotal     int
+	}{{
+		name:        "build command issues a BuildRun for the pull-request head",
+		comment:     "/shp build",
+		objs:        []client.Object{pullRequestHeadConfigMap(t, stubs.Namespace)},
+		wantMessage: "commands executed",
+		wantTotal:   1,
+	}, {
+		name:    "build command for another Build name does not issue BuildRuns",
+		comment: "/shp build another-build",
+		objs: []client.Object{
+			pullRequestHeadConfigMap(t, stubs.Namespace),
+			pullRequestBuildRun("previous", 10, corev1.ConditionTrue),
+		},
+		wantMessage: "commands executed",
+		wantTotal:   1,
+	}, {
+		name:    "retest command issues the last failed BuildRun again",
+		comment: "/shp retest",
+		objs: []client.Object{
+			pullRequestHeadConfigMap(t, stubs.Namespace),
+			pullRequestBuildRun("older", 20, corev1.ConditionTrue),
+			pullRequestBuildRun("failed", 10, corev1.ConditionFalse),
+		},
+		wantMessage: "commands executed",
+		wantTotal:   3,
+	}, {
+		name:    "retest command skips pull-requests whose last BuildRun succeeded",
+		comment: "/shp retest",
+		objs: []client.Object{
+			pullRequestHeadConfigMap(t, stubs.Namespace),
+			pullRequestBuildRun("failed", 20, corev1.ConditionFalse),
+			pullRequestBuildRun("succeeded", 10, corev1.ConditionTrue),
+		},
+		wantMessage: "commands executed",
+		wantTotal:   2,
+	}, {
+		name:    "cancel command cancels the running BuildRuns",
+		comment: "/shp cancel",
+		objs: []client.Object{
+			pullRequestHeadConfigMap(t, stubs.Namespace),
+			pullRequestBuildRun("running", 10, corev1.ConditionUnknown),
+			pullRequestBuildRun("succeeded", 20, corev1.ConditionTrue),
+		},
+		wantMessage:   "commands executed",
+		wantBuildRuns: []string{"running"},
+		wantCancelled: []string{"running"},
+		wantTotal:     2,
+	}, {
+		name:        "pull-request head recorded on another namespace does not affect the Build",
+		comment:     "/shp build",
+		objs:        []client.Object{pullRequestHeadConfigMap(t, "other")},
+		wantMessage: "no builds matching event",
+	}, {
+		name:        "pull-request without a recorded head is skipped",
+		comment:     "/shp build",
+		objs:        []client.Object{pullRequestBuildRun("previous", 10, corev1.ConditionTrue)},
+		wantMessage: "pull-request head is unknown",
+		wantTotal:   1,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildWithPullRequestTrigger)
+
+			c := newFakeClient(t, append(tt.objs, buildWithPullRequestTrigger)...)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, newGitHubRequest(t, "issue_comment",
+				stubs.GitHubIssueCommentEvent(tt.comment, "MEMBER")))
+			g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+
+			var response Response
+			g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+			g.Expect(response.Message).To(gomega.Equal(tt.wantMessage))
+			if tt.wantBuildRuns != nil {
+				g.Expect(response.BuildRuns).To(gomega.Equal(tt.wantBuildRuns))
+			}
+
+			brs := listBuildRuns(t, c)
+			g.Expect(brs).To(gomega.HaveLen(tt.wantTotal))
+
+			var cancelled []string
+			for _, br := range brs {
+				if br.IsCanceled() {
+					cancelled = append(cancelled, br.GetName())
+				}
+				// BuildRuns issued by commands do not carry the status reported, or the event hash,
+				// of the BuildRuns issued before
+				if br.GetGenerateName() != "" {
+					g.Expect(br.GetAnnotations()).ToNot(gomega.HaveKey(filter.WebHookReportedStatus))
+					g.Expect(br.GetLabels()).ToNot(gomega.HaveKey(filter.WebHookEventHash))
+					g.Expect(br.Spec.Build.Spec).ToNot(gomega.BeNil())
+				}
+				if br.Spec.Build.Spec != nil {
+					g.Expect(*br.Spec.Build.Spec.Source.Git.Revision).
+						To(gomega.Equal(stubs.HeadCommitID))
+				}
+			}
+			g.Expect(cancelled).To(gomega.Equal(tt.wantCancelled))
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPPullRequestHead(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithPullRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-pull-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithPullRequestTrigger)
+
+	c := newFakeClient(t, buildWithPullRequestTrigger)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// serve sends the GitHub event, returning the response message
+	serve := func(eventType string, event interface{}) string {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newGitHubRequest(t, eventType, event))
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return response.Message
+	}
+	comment := stubs.GitHubIssueCommentEvent("/shp build", "MEMBER")
+
+	// the head is recorded by the pull-request event, even when it does not issue BuildRuns, the
+	// build command issues a BuildRun for it
+	serve("pull_request", stubs.GitHubPullRequestEvent("opened"))
+	issued := len(listBuildRuns(t, c))
+	g.Expect(serve("issue_comment", comment)).To(gomega.Equal("commands executed"))
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(issued + 1))
+
+	// closing the pull-request removes the head recorded
+	serve("pull_request", stubs.GitHubPullRequestEvent("closed"))
+	g.Expect(serve("issue_comment", comment)).To(gomega.Equal("pull-request head is unknown"))
+}
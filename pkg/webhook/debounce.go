@@ -0,0 +1,328 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/store"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DebounceConfigMapName name of the ConfigMap storing the triggers waiting for the debounce
+	// window to close, one instance per namespace.
+	DebounceConfigMapName = "shipwright-triggers-debounce"
+	// maxDebounceWindows maximum amount of windows a trigger waits since the first event, so a
+	// steady stream of events does not hold the BuildRun forever.
+	maxDebounceWindows = 10
+	// debounceRetryBaseDelay initial delay to flush the trigger again after a failure.
+	debounceRetryBaseDelay = time.Second
+	// debounceRetryMaxDelay maximum delay to flush the trigger again after a failure.
+	debounceRetryMaxDelay = 5 * time.Minute
+)
+
+var (
+	// debouncePending amount of triggers waiting for the debounce window to close.
+	debouncePending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shipwright_triggers_debounce_pending",
+		Help: "Amount of triggers waiting for the debounce window to close.",
+	})
+	// debounceCoalesced amount of events coalesced into a trigger already waiting.
+	debounceCoalesced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "shipwright_triggers_debounce_coalesced_total",
+		Help: "Amount of events coalesced into a trigger already waiting.",
+	})
+	// debounceFlushed amount of triggers flushed, by outcome (success, discarded or error).
+	debounceFlushed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shipwright_triggers_debounce_flushed_total",
+		Help: "Amount of debounced triggers flushed, by outcome.",
+	}, []string{"outcome"})
+	// debounceDelay amount of time since the first event until the BuildRun is issued.
+	debounceDelay = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "shipwright_triggers_debounce_delay_seconds",
+		Help:    "Amount of time since the first event until the debounced BuildRun is issued.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		debouncePending,
+		debounceCoalesced,
+		debounceFlushed,
+		debounceDelay,
+	)
+}
+
+// DebouncedTrigger trigger waiting for the debounce window to close, stored as JSON on the
+// namespace's debounce ConfigMap.
+type DebouncedTrigger struct {
+	BuildName   string      `json:"buildName"`   // build matching the events
+	Event       *Event      `json:"event"`       // newest event, trimmed to issue the BuildRun
+	Coalesced   int         `json:"coalesced"`   // amount of events coalesced into the trigger
+	FirstSeenAt metav1.Time `json:"firstSeenAt"` // moment the first event has been received
+	DueAt       metav1.Time `json:"dueAt"`       // moment the window closes
+}
+
+// debounceWindow returns the Build debounce window, zero when the Build is not debounced.
+func debounceWindow(b *buildapi.Build) (time.Duration, error) {
+	value, ok := b.GetAnnotations()[filter.BuildDebounce]
+	if !ok {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid debounce window %q: %w", value, err)
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("invalid debounce window %q: must be positive", value)
+	}
+	return window, nil
+}
+
+// debounceKey returns the ConfigMap key for the Build and Git reference.
+func debounceKey(buildName, ref string) string {
+	return fmt.Sprintf("%s.%s", buildName, concurrency.Group(ref))
+}
+
+// debounceStore stores the triggers of debounced Builds, invalid triggers are purged.
+var debounceStore = store.NewStore(
+	DebounceConfigMapName,
+	filter.DebouncedTriggers,
+	func(trigger *DebouncedTrigger) bool { return trigger.Event != nil },
+)
+
+// debouncer delay queue holding the triggers of debounced Builds, keyed by Build and Git
+// reference, until the window closes. Triggers are persisted on ConfigMaps, so they survive
+// restarts, each replica flushes the triggers it has debounced.
+type debouncer struct {
+	client  client.Client        // kubernetes client
+	limiter *concurrency.Limiter // creates BuildRuns honoring the Build concurrency policy
+	logger  logr.Logger          // component logger
+	// queue namespace and ConfigMap key of the triggers waiting, delayed until the window closes
+	queue workqueue.TypedRateLimitingInterface[types.NamespacedName]
+}
+
+// update retrieves, or creates, the namespace's debounce ConfigMap and applies the mutate function
+// on the triggers stored. The update is retried on conflicts.
+func (d *debouncer) update(
+	ctx context.Context,
+	namespace string,
+	mutateFn func(map[string]*DebouncedTrigger),
+) error {
+	return debounceStore.Update(ctx, d.client, namespace,
+		func(triggers map[string]*DebouncedTrigger) (bool, error) {
+			mutateFn(triggers)
+			return true, nil
+		})
+}
+
+// debounce stores the event as the newest trigger for each debounced Build in the search results,
+// extending the window. Returns the search results not debounced, and the Build names debounced.
+func (d *debouncer) debounce(
+	ctx context.Context,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]inventory.SearchResult, []string, error) {
+	var remaining []inventory.SearchResult
+	var debounced []string
+	stored := event.trimmed()
+	for i, result := range results {
+		// Builds failing to be retrieved are not debounced, the delivery records the failure and
+		// is retried later on
+		var b buildapi.Build
+		var window time.Duration
+		err := d.client.Get(ctx, result.BuildName, &b)
+		if err == nil {
+			if window, err = debounceWindow(&b); err != nil {
+				d.logger.V(0).Error(err, "Build is not debounced", "build", result.BuildName.String())
+			}
+		}
+		if window == 0 {
+			remaining = append(remaining, result)
+			continue
+		}
+
+		now := time.Now()
+		key := debounceKey(b.GetName(), event.Ref)
+		var due time.Time
+		var coalesced bool
+		err = d.update(ctx, b.GetNamespace(), func(triggers map[string]*DebouncedTrigger) {
+			trigger, ok := triggers[key]
+			if coalesced = ok; ok {
+				trigger.Coalesced++
+			} else {
+				trigger = &DebouncedTrigger{BuildName: b.GetName(), FirstSeenAt: metav1.NewTime(now)}
+			}
+			trigger.Event = stored
+
+			due = now.Add(window)
+			if limit := trigger.FirstSeenAt.Add(maxDebounceWindows * window); limit.Before(due) {
+				due = limit
+			}
+			trigger.DueAt = metav1.NewTime(due)
+			triggers[key] = trigger
+		})
+		if err != nil {
+			return append(remaining, results[i:]...), debounced, err
+		}
+
+		if coalesced {
+			debounceCoalesced.Inc()
+		} else {
+			debouncePending.Inc()
+		}
+		d.queue.AddAfter(types.NamespacedName{Namespace: b.GetNamespace(), Name: key}, due.Sub(now))
+		debounced = append(debounced, result.BuildName.String())
+	}
+	return remaining, debounced, nil
+}
+
+// drop removes the triggers waiting for the event Git reference, for each Build in the search
+// results.
+func (d *debouncer) drop(ctx context.Context, event *Event, results []inventory.SearchResult) error {
+	for _, result := range results {
+		key := debounceKey(result.BuildName.Name, event.Ref)
+		err := d.update(ctx, result.BuildName.Namespace, func(triggers map[string]*DebouncedTrigger) {
+			if _, ok := triggers[key]; ok {
+				delete(triggers, key)
+				debouncePending.Dec()
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush issues the BuildRun for the newest event of the trigger when the window is closed, and
+// removes the trigger, unless a newer event has been coalesced in the meantime. Triggers whose
+// window has been extended are delayed again.
+func (d *debouncer) flush(ctx context.Context, item types.NamespacedName) error {
+	triggers, err := debounceStore.Get(ctx, d.client, item.Namespace)
+	if err != nil {
+		return err
+	}
+	trigger, ok := triggers[item.Name]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(trigger.DueAt.Time) {
+		d.queue.AddAfter(item, trigger.DueAt.Sub(now))
+		return nil
+	}
+
+	logger := d.logger.WithValues("namespace", item.Namespace, "build", trigger.BuildName)
+	var b buildapi.Build
+	err = d.client.Get(ctx, types.NamespacedName{Namespace: item.Namespace, Name: trigger.BuildName},
+		&b)
+	switch {
+	case apierrors.IsNotFound(err):
+		debounceFlushed.WithLabelValues("discarded").Inc()
+		logger.V(0).Info("Build not found, discarding debounced trigger")
+	case err != nil:
+		return err
+	default:
+		br := generateBuildRun(trigger.Event, &b)
+		err = createBuildRun(ctx, d.client, d.limiter, trigger.Event, &b, br)
+		if concurrency.IsQueueFull(err) {
+			debounceFlushed.WithLabelValues("rejected").Inc()
+			logger.V(0).Error(err, "BuildRun rejected by the concurrency queue, discarding trigger")
+			break
+		}
+		if err != nil {
+			return err
+		}
+		debounceFlushed.WithLabelValues("success").Inc()
+		debounceDelay.Observe(now.Sub(trigger.FirstSeenAt.Time).Seconds())
+		logger.V(0).Info("Debounced trigger flushed", "buildrun", br.GetName(),
+			"commit-sha", trigger.Event.HeadSHA, "coalesced", trigger.Coalesced)
+	}
+
+	return d.update(ctx, item.Namespace, func(triggers map[string]*DebouncedTrigger) {
+		if current, ok := triggers[item.Name]; ok && current.DueAt.Equal(&trigger.DueAt) {
+			delete(triggers, item.Name)
+			debouncePending.Dec()
+		}
+	})
+}
+
+// resync enqueues the triggers persisted on all namespaces, triggers whose window is already
+// closed are flushed right away.
+func (d *debouncer) resync(ctx context.Context) error {
+	namespaces, err := debounceStore.List(ctx, d.client)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	pending := 0
+	for namespace, triggers := range namespaces {
+		for key, trigger := range triggers {
+			item := types.NamespacedName{Namespace: namespace, Name: key}
+			d.queue.AddAfter(item, trigger.DueAt.Sub(now))
+			pending++
+		}
+	}
+	debouncePending.Set(float64(pending))
+	return nil
+}
+
+// run resyncs the triggers persisted and flushes the triggers as their window closes, until the
+// context is done. Failures are retried with exponential backoff.
+func (d *debouncer) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		d.queue.ShutDown()
+	}()
+
+	if err := d.resync(ctx); err != nil {
+		d.logger.V(0).Error(err, "Unable to resync debounced triggers")
+	}
+	for {
+		item, shutdown := d.queue.Get()
+		if shutdown {
+			return
+		}
+		if err := d.flush(ctx, item); err != nil {
+			debounceFlushed.WithLabelValues("error").Inc()
+			d.logger.V(0).Error(err, "Unable to flush debounced trigger", "trigger", item.String())
+			d.queue.AddRateLimited(item)
+		} else {
+			d.queue.Forget(item)
+		}
+		d.queue.Done(item)
+	}
+}
+
+// newDebouncer instantiate the debouncer.
+func newDebouncer(c client.Client, limiter *concurrency.Limiter, logger logr.Logger) *debouncer {
+	return &debouncer{
+		client:  c,
+		limiter: limiter,
+		logger:  logger,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[types.NamespacedName](
+				debounceRetryBaseDelay, debounceRetryMaxDelay),
+			workqueue.TypedRateLimitingQueueConfig[types.NamespacedName]{Name: "webhook-debounce"},
+		),
+	}
+}
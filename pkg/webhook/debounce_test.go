@@ -0,0 +1,140 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDebounceWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+		wantErr     bool
+	}{{
+		name: "not debounced",
+		want: 0,
+	}, {
+		name:        "debounce window",
+		annotations: map[string]string{filter.BuildDebounce: "30s"},
+		want:        30 * time.Second,
+	}, {
+		name:        "invalid duration",
+		annotations: map[string]string{filter.BuildDebounce: "soon"},
+		wantErr:     true,
+	}, {
+		name:        "negative duration",
+		annotations: map[string]string{filter.BuildDebounce: "-1m"},
+		wantErr:     true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &buildapi.Build{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			got, err := debounceWindow(b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("debounceWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("debounceWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPDebounce(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	b := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-debounce",
+		stubs.TriggerWhenPushToMain,
+	)
+	b.SetAnnotations(map[string]string{filter.BuildDebounce: "30s"})
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(b)
+	c := newFakeClient(t, b)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// serve sends a push event for the informed head commit, returning the response
+	serve := func(headSHA string) (int, Response) {
+		event := stubs.GitHubPushEvent()
+		event.HeadCommit.ID = github.String(headSHA)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newGitHubRequest(t, "push", event))
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	// loadTriggers decodes the debounced triggers stored in the test namespace
+	key := types.NamespacedName{Namespace: stubs.Namespace, Name: DebounceConfigMapName}
+	loadTriggers := func() (*corev1.ConfigMap, map[string]*DebouncedTrigger) {
+		var cm corev1.ConfigMap
+		g.Expect(c.Get(context.TODO(), key, &cm)).To(gomega.Succeed())
+		return &cm, debounceStore.Decode(&cm)
+	}
+
+	for _, headSHA := range []string{"first-commit-id", "second-commit-id"} {
+		code, response := serve(headSHA)
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+		g.Expect(response.Message).To(gomega.Equal("triggers debounced"))
+	}
+	g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+	cm, triggers := loadTriggers()
+	g.Expect(cm.GetLabels()).To(gomega.HaveKeyWithValue(filter.DebouncedTriggers, "true"))
+	item := types.NamespacedName{
+		Namespace: stubs.Namespace,
+		Name:      debounceKey(b.GetName(), stubs.GitRef),
+	}
+	g.Expect(triggers).To(gomega.HaveKey(item.Name))
+	trigger := triggers[item.Name]
+	g.Expect(trigger.Coalesced).To(gomega.Equal(1))
+	g.Expect(trigger.Event.HeadSHA).To(gomega.Equal("second-commit-id"))
+	g.Expect(trigger.Event.Ref).To(gomega.Equal(stubs.GitRef))
+	g.Expect(trigger.Event.Branch).To(gomega.Equal(stubs.Branch))
+	// only the attributes needed to issue the BuildRun are stored
+	g.Expect(trigger.Event.HeadMessage).To(gomega.BeEmpty())
+	g.Expect(trigger.Event.ChangedFiles).To(gomega.BeEmpty())
+	g.Expect(cm.Data[item.Name]).ToNot(gomega.ContainSubstring(stubs.HeadCommitMsg))
+
+	// the window is still open, nothing is issued
+	g.Expect(w.debouncer.flush(context.TODO(), item)).To(gomega.Succeed())
+	g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+	// closing the window issues a single BuildRun for the newest commit
+	trigger.DueAt = metav1.NewTime(time.Now().Add(-time.Second))
+	value, err := json.Marshal(trigger)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	cm.Data[item.Name] = string(value)
+	g.Expect(c.Update(context.TODO(), cm)).To(gomega.Succeed())
+
+	g.Expect(w.debouncer.flush(context.TODO(), item)).To(gomega.Succeed())
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	g.Expect(brs[0].GetAnnotations()).To(
+		gomega.HaveKeyWithValue(filter.WebHookCommitSHA, "second-commit-id"))
+
+	_, triggers = loadTriggers()
+	g.Expect(triggers).To(gomega.BeEmpty())
+}
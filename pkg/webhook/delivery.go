@@ -0,0 +1,79 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// DefaultDeliveryCacheSize amount of delivery IDs remembered to detect duplicated deliveries.
+const DefaultDeliveryCacheSize = 1024
+
+// deliveryCache bounded set of the delivery IDs handled, the oldest entries are evicted when the
+// cache is full.
+type deliveryCache struct {
+	m       sync.Mutex               // protects the attributes below
+	size    int                      // maximum amount of entries
+	order   *list.List               // delivery keys, oldest first
+	entries map[string]*list.Element // delivery keys index
+}
+
+// deliveryKey identifies the delivery by provider, returns empty when the delivery ID is not
+// informed.
+func deliveryKey(provider, id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", provider, id)
+}
+
+// seen asserts the delivery key is recorded.
+func (c *deliveryCache) seen(key string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, ok := c.entries[key]
+	return ok
+}
+
+// record records the delivery key, evicting the oldest keys when the cache is full. Returns false
+// when the key is already recorded.
+func (c *deliveryCache) record(key string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return false
+	}
+	c.entries[key] = c.order.PushBack(key)
+	for c.order.Len() > c.size {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+	}
+	return true
+}
+
+// forget removes the delivery key, so the delivery is handled again.
+func (c *deliveryCache) forget(key string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// newDeliveryCache instantiate the cache holding up to the informed amount of delivery keys.
+func newDeliveryCache(size int) *deliveryCache {
+	return &deliveryCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
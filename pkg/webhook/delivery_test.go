@@ -0,0 +1,152 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+func TestDeliveryCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	c := newDeliveryCache(2)
+	g.Expect(c.seen("github/a")).To(gomega.BeFalse())
+	g.Expect(c.record("github/a")).To(gomega.BeTrue())
+	g.Expect(c.seen("github/a")).To(gomega.BeTrue())
+	g.Expect(c.record("github/a")).To(gomega.BeFalse())
+	g.Expect(c.record("gitlab/a")).To(gomega.BeTrue())
+	g.Expect(c.entries).To(gomega.HaveLen(2))
+
+	// forgotten entries are recorded again
+	c.forget("gitlab/a")
+	g.Expect(c.seen("gitlab/a")).To(gomega.BeFalse())
+	g.Expect(c.order.Len()).To(gomega.Equal(1))
+	g.Expect(c.record("gitlab/a")).To(gomega.BeTrue())
+
+	// the oldest entry is evicted when the cache is full
+	c.record("github/b")
+	g.Expect(c.entries).To(gomega.HaveLen(2))
+	g.Expect(c.seen("github/a")).To(gomega.BeFalse())
+	g.Expect(c.seen("gitlab/a")).To(gomega.BeTrue())
+}
+
+func TestWebHook_dispatchRecordsDeliveries(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	w := NewWebHook(newFakeClient(t), inventory.NewInventory(), Options{Workers: 1, QueueSize: 2})
+	dispatch := func(key string, code int) int {
+		rec := httptest.NewRecorder()
+		w.dispatch(rec, httptest.NewRequest(http.MethodPost, "/", nil), w.logger, key,
+			func(context.Context) *outcome {
+				return &outcome{code: code}
+			})
+		return rec.Code
+	}
+
+	// events are recorded when accepted on the queue, and forgotten when the processing fails
+	g.Expect(dispatch("github/failed", http.StatusInternalServerError)).
+		To(gomega.Equal(http.StatusAccepted))
+	g.Expect(dispatch("github/succeeded", http.StatusOK)).To(gomega.Equal(http.StatusAccepted))
+	g.Expect(w.deliveries.seen("github/failed")).To(gomega.BeTrue())
+	g.Expect(w.deliveries.seen("github/succeeded")).To(gomega.BeTrue())
+
+	// events rejected because the queue is full are not recorded
+	g.Expect(dispatch("github/rejected", http.StatusOK)).
+		To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(w.deliveries.seen("github/rejected")).To(gomega.BeFalse())
+
+	w.queue.start(context.TODO())
+	w.queue.drain()
+	g.Expect(w.deliveries.seen("github/failed")).To(gomega.BeFalse())
+	g.Expect(w.deliveries.seen("github/succeeded")).To(gomega.BeTrue())
+	g.Expect(dispatch("github/succeeded", http.StatusOK)).To(gomega.Equal(http.StatusOK))
+}
+
+func TestWebHook_ServeHTTPUnauthorizedDelivery(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	b := stubs.ShipwrightBuildWithTriggers("ghcr.io/shipwright-io", "build-push",
+		stubs.TriggerWhenPushToMain)
+	secretName := "secret"
+	b.Spec.Trigger.TriggerSecret = &secretName
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(b)
+	c := newFakeClient(t, b, triggerSecret(secretName, "token"))
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// serve sends the push event signed with the informed token, reusing the same delivery ID
+	serve := func(token string) (int, Response) {
+		payload := marshalOrFail(t, stubs.GitHubPushEvent())
+		r := newGitHubRequest(t, "push", stubs.GitHubPushEvent())
+		r.Header.Set("X-GitHub-Delivery", "delivery-unauthorized")
+		r.Header.Set(gitHubSignatureHeader, signPayload(payload, []byte(token)))
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, r)
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	// a request failing validation does not prevent the genuine delivery
+	code, _ := serve("forged")
+	g.Expect(code).To(gomega.Equal(http.StatusUnauthorized))
+
+	code, response := serve("token")
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(response.Message).To(gomega.Equal("buildruns issued"))
+
+	_, response = serve("token")
+	g.Expect(response.Message).To(gomega.Equal("duplicated delivery"))
+}
+
+func TestWebHook_ServeHTTPReplays(t *testing.T) {
+	buildWithPushTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-push",
+		stubs.TriggerWhenPushToMain,
+	)
+
+	g := gomega.NewWithT(t)
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithPushTrigger)
+	c := newFakeClient(t, buildWithPushTrigger)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// serve sends the push event using the informed delivery ID
+	serve := func(deliveryID string) Response {
+		r := newGitHubRequest(t, "push", stubs.GitHubPushEvent())
+		r.Header.Set("X-GitHub-Delivery", deliveryID)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, r)
+		g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return response
+	}
+
+	first := serve("delivery-a")
+	g.Expect(first.Message).To(gomega.Equal("buildruns issued"))
+	g.Expect(first.BuildRuns).To(gomega.HaveLen(1))
+
+	// the same delivery is skipped
+	g.Expect(serve("delivery-a").Message).To(gomega.Equal("duplicated delivery"))
+
+	// a new delivery of the same event resolves to the BuildRun issued before, i.e. after the
+	// controller restarts
+	replay := serve("delivery-b")
+	g.Expect(replay.Message).To(gomega.Equal("buildruns issued"))
+	g.Expect(replay.BuildRuns).To(gomega.Equal(first.BuildRuns))
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+}
@@ -0,0 +1,66 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shipwright-io/triggers/pkg/inventory"
+
+	"github.com/go-logr/logr"
+)
+
+var (
+	// skipDirectiveRE matches the directives suppressing the BuildRuns, i.e. "[skip ci]".
+	skipDirectiveRE = regexp.MustCompile(`(?i)\[\s*(skip ci|ci skip|shp skip)\s*\]`)
+	// onlyDirectiveRE matches the directive limiting the Builds triggered, i.e. "[shp only: a,b]".
+	onlyDirectiveRE = regexp.MustCompile(`(?i)\[\s*shp only\s*:([^\]]*)\]`)
+)
+
+// Directives trigger directives informed on the head commit message.
+type Directives struct {
+	Skip bool     // no BuildRuns must be issued
+	Only []string // only the Build names listed must be triggered, when informed
+}
+
+// ParseDirectives extracts the trigger directives from the commit message. The Build names listed
+// on all "[shp only: ...]" directives are combined.
+func ParseDirectives(message string) Directives {
+	directives := Directives{Skip: skipDirectiveRE.MatchString(message)}
+	for _, match := range onlyDirectiveRE.FindAllStringSubmatch(message, -1) {
+		for _, name := range strings.Split(match[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				directives.Only = append(directives.Only, name)
+			}
+		}
+	}
+	return directives
+}
+
+// filterByOnlyDirective returns the search results listed on the "only" directive, logging each
+// Build skipped. All results are returned when the directive is not informed.
+func filterByOnlyDirective(
+	logger logr.Logger,
+	event *Event,
+	directives Directives,
+	results []inventory.SearchResult,
+) []inventory.SearchResult {
+	if len(directives.Only) == 0 {
+		return results
+	}
+	selected := selectResultsByName(results, directives.Only)
+	listed := map[string]bool{}
+	for _, result := range selected {
+		listed[result.BuildName.String()] = true
+	}
+	for _, result := range results {
+		if !listed[result.BuildName.String()] {
+			logger.V(0).Info("Skipping Build not listed on the head commit message directive",
+				"build-name", result.BuildName, "head-sha", event.HeadSHA, "only", directives.Only)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,50 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    Directives
+	}{{
+		name:    "message without directives",
+		message: "fix the skip ci documentation",
+		want:    Directives{},
+	}, {
+		name:    "skip ci directive",
+		message: "update docs [skip ci]",
+		want:    Directives{Skip: true},
+	}, {
+		name:    "ci skip directive on the message body",
+		message: "update docs\n\n[CI SKIP]",
+		want:    Directives{Skip: true},
+	}, {
+		name:    "shp skip directive",
+		message: "[shp skip] update docs",
+		want:    Directives{Skip: true},
+	}, {
+		name:    "only directive",
+		message: "fix api [shp only: build-a, build-b]",
+		want:    Directives{Only: []string{"build-a", "build-b"}},
+	}, {
+		name:    "several only directives are combined",
+		message: "[shp only: build-a]\n[shp only:build-b,]",
+		want:    Directives{Only: []string{"build-a", "build-b"}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDirectives(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDirectives() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"errors"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+)
+
+// ErrEventIgnored the webhook event is valid, although it's not meant to trigger Builds.
+var ErrEventIgnored = errors.New("event ignored")
+
+const (
+	// branchRefPrefix prefix employed by Git references pointing to a branch.
+	branchRefPrefix = "refs/heads/"
+	// tagRefPrefix prefix employed by Git references pointing to a tag.
+	tagRefPrefix = "refs/tags/"
+)
+
+// PullRequestAction provider-neutral pull-request action.
+type PullRequestAction string
+
+const (
+	// PullRequestOpened the pull-request has been opened or reopened.
+	PullRequestOpened PullRequestAction = "opened"
+	// PullRequestSynchronized new commits have been pushed to the pull-request.
+	PullRequestSynchronized PullRequestAction = "synchronized"
+	// PullRequestClosed the pull-request has been closed or merged.
+	PullRequestClosed PullRequestAction = "closed"
+	// PullRequestApproved a maintainer approved testing the pull-request, either by commenting or
+	// labeling it.
+	PullRequestApproved PullRequestAction = "approved"
+	// PullRequestCommand a maintainer commented ChatOps commands on the pull-request.
+	PullRequestCommand PullRequestAction = "command"
+)
+
+const (
+	// OkToTestLabel pull-request label approving the triggers held for approval.
+	OkToTestLabel = "ok-to-test"
+	// okToTestCommand pull-request comment approving the triggers held for approval.
+	okToTestCommand = "/ok-to-test"
+)
+
+// approvalProviders providers transforming the ok-to-test label, or comment, into approval events,
+// pull-requests are held for approval only for these providers. Bitbucket does not support labels,
+// and its comment events don't inform the author permissions, thus untrusted pull-requests are
+// ignored instead.
+var approvalProviders = map[string]bool{
+	GitHubProvider: true,
+	GitLabProvider: true,
+	GiteaProvider:  true,
+}
+
+// trustedAuthorAssociations author associations (GitHub) allowed to trigger Builds directly, to
+// approve the pull-requests held, and to comment ChatOps commands.
+var trustedAuthorAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// Event represents the webhook payload attributes needed to search the Inventory and issue the
+// BuildRuns, regardless of the Git provider which sent it. Providers normalize their payloads into
+// this provider-neutral representation, downstream features only consume the Event. Triggers held
+// for approval store the Event serialized as JSON.
+type Event struct {
+	Provider     string                   `json:"provider"`               // git provider name
+	Name         buildapi.GitHubEventName `json:"name"`                   // event kind, push or pull-request
+	RepoURL      string                   `json:"repoURL"`                // repository URL
+	Ref          string                   `json:"ref"`                    // full git reference, i.e. "refs/heads/main"
+	Branch       string                   `json:"branch,omitempty"`       // branch name extracted from the reference
+	Tag          string                   `json:"tag,omitempty"`          // tag name extracted from the reference
+	HeadSHA      string                   `json:"headSHA,omitempty"`      // head commit SHA
+	HeadMessage  string                   `json:"headMessage,omitempty"`  // head commit message, when informed
+	Author       string                   `json:"author,omitempty"`       // user who originated the event
+	ChangedFiles []string                 `json:"changedFiles,omitempty"` // files changed by the event commits, when informed
+	PullRequest  int                      `json:"pullRequest,omitempty"`  // pull-request number, zero for push events
+	Action       PullRequestAction        `json:"action,omitempty"`       // pull-request action, empty for push events
+	MergeRef     string                   `json:"mergeRef,omitempty"`     // pull-request merge reference, when the provider has one
+	Fork         bool                     `json:"fork,omitempty"`         // pull-request head comes from a fork
+	Labels       []string                 `json:"labels,omitempty"`       // pull-request labels, when informed
+	Commands     []Command                `json:"commands,omitempty"`     // chatops commands commented
+
+	// AuthorAssociation the author relationship with the repository, i.e. "COLLABORATOR", only
+	// informed by GitHub
+	AuthorAssociation string `json:"authorAssociation,omitempty"`
+	// ChangedFilesTruncated the payload does not inform all commits pushed, thus the changed files
+	// list is incomplete
+	ChangedFilesTruncated bool `json:"changedFilesTruncated,omitempty"`
+}
+
+// HasCompleteChangedFiles asserts the event informs the complete list of changed files, events
+// without it are not filtered by path.
+func (e *Event) HasCompleteChangedFiles() bool {
+	return len(e.ChangedFiles) > 0 && !e.ChangedFilesTruncated
+}
+
+// IsPullRequest asserts the event refers to a pull-request.
+func (e *Event) IsPullRequest() bool {
+	return e.Name == buildapi.GitHubPullRequestEvent
+}
+
+// IsPullRequestClosed asserts the event refers to a pull-request being closed.
+func (e *Event) IsPullRequestClosed() bool {
+	return e.IsPullRequest() && e.Action == PullRequestClosed
+}
+
+// IsPullRequestUpdated asserts the event refers to new commits pushed to the pull-request, or to
+// the pull-request being closed, both cancel the pull-request BuildRuns still running.
+func (e *Event) IsPullRequestUpdated() bool {
+	return e.IsPullRequest() &&
+		(e.Action == PullRequestSynchronized || e.Action == PullRequestClosed)
+}
+
+// IsPullRequestApproved asserts the event approves the pull-request triggers held.
+func (e *Event) IsPullRequestApproved() bool {
+	return e.IsPullRequest() && e.Action == PullRequestApproved
+}
+
+// IsPullRequestCommand asserts the event carries ChatOps commands for the pull-request.
+func (e *Event) IsPullRequestCommand() bool {
+	return e.IsPullRequest() && e.Action == PullRequestCommand && len(e.Commands) > 0
+}
+
+// isUntrusted asserts the pull-request event carries new commits from a fork, or from an author
+// who is not a collaborator, unless the pull-request is already labeled as ok-to-test.
+func (e *Event) isUntrusted() bool {
+	if !e.IsPullRequest() || (e.Action != PullRequestOpened && e.Action != PullRequestSynchronized) {
+		return false
+	}
+	if hasOkToTestLabel(e.Labels) {
+		return false
+	}
+	return e.Fork || (e.AuthorAssociation != "" && !trustedAuthorAssociations[e.AuthorAssociation])
+}
+
+// RequiresApproval asserts the pull-request event must be approved by a maintainer before issuing
+// BuildRuns, that's the case for untrusted pull-requests on providers able to approve them.
+func (e *Event) RequiresApproval() bool {
+	return approvalProviders[e.Provider] && e.isUntrusted()
+}
+
+// IsUnapprovable asserts the pull-request event is untrusted, but the provider is not able to
+// approve it, the event must not issue BuildRuns.
+func (e *Event) IsUnapprovable() bool {
+	return !approvalProviders[e.Provider] && e.isUntrusted()
+}
+
+// Variables returns the event as the CEL filter expression variables, the event attributes are
+// named after the JSON fields (i.e. "event.author") and are always present, attributes not informed
+// carry their zero value.
+func (e *Event) Variables() map[string]interface{} {
+	commands := make([]interface{}, 0, len(e.Commands))
+	for _, c := range e.Commands {
+		commands = append(commands, map[string]interface{}{
+			"name": string(c.Name),
+			"args": nonNilStrings(c.Args),
+		})
+	}
+	return map[string]interface{}{inventory.EventVariable: map[string]interface{}{
+		"provider":              e.Provider,
+		"name":                  string(e.Name),
+		"repoURL":               e.RepoURL,
+		"ref":                   e.Ref,
+		"branch":                e.Branch,
+		"tag":                   e.Tag,
+		"headSHA":               e.HeadSHA,
+		"headMessage":           e.HeadMessage,
+		"author":                e.Author,
+		"changedFiles":          nonNilStrings(e.ChangedFiles),
+		"pullRequest":           e.PullRequest,
+		"action":                string(e.Action),
+		"mergeRef":              e.MergeRef,
+		"fork":                  e.Fork,
+		"labels":                nonNilStrings(e.Labels),
+		"commands":              commands,
+		"authorAssociation":     e.AuthorAssociation,
+		"changedFilesTruncated": e.ChangedFilesTruncated,
+	}}
+}
+
+// nonNilStrings returns an empty slice instead of nil, so list attributes are always present.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// trimmed returns a copy of the event carrying only the attributes needed to issue the BuildRuns,
+// events stored on ConfigMaps are trimmed so they stay small regardless of the amount of files
+// changed, and the size of the commit messages, informed on the events.
+func (e *Event) trimmed() *Event {
+	return &Event{
+		Provider:    e.Provider,
+		Name:        e.Name,
+		RepoURL:     e.RepoURL,
+		Ref:         e.Ref,
+		Branch:      e.Branch,
+		Tag:         e.Tag,
+		HeadSHA:     e.HeadSHA,
+		Author:      e.Author,
+		PullRequest: e.PullRequest,
+		Action:      e.Action,
+		MergeRef:    e.MergeRef,
+	}
+}
+
+// IsTag asserts the event refers to a tag instead of a branch.
+func (e *Event) IsTag() bool {
+	return e.Tag != ""
+}
+
+// BranchFromRef extracts the branch name from the informed Git reference, returns empty when the
+// reference does not point to a branch.
+func BranchFromRef(ref string) string {
+	if !strings.HasPrefix(ref, branchRefPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, branchRefPrefix)
+}
+
+// TagFromRef extracts the tag name from the informed Git reference, returns empty when the reference
+// does not point to a tag.
+func TagFromRef(ref string) string {
+	if !strings.HasPrefix(ref, tagRefPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, tagRefPrefix)
+}
+
+// isFork asserts the pull-request head repository differs from the base repository, when both are
+// informed.
+func isFork(headRepoURL, baseRepoURL string) bool {
+	if headRepoURL == "" || baseRepoURL == "" {
+		return false
+	}
+	return !inventory.CompareURLs(headRepoURL, baseRepoURL)
+}
+
+// hasOkToTestLabel asserts the pull-request labels carry the ok-to-test label.
+func hasOkToTestLabel(labels []string) bool {
+	for _, label := range labels {
+		if label == OkToTestLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOkToTestCommand asserts the comment body carries the ok-to-test command on its own line.
+func hasOkToTestCommand(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimSpace(line) == okToTestCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeChangedFiles merges the lists of added, removed and modified files, informed per commit,
+// into a single list without duplicates, keeping the order files are first seen.
+func mergeChangedFiles(lists ...[]string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, f := range list {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	return files
+}
@@ -0,0 +1,85 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"testing"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+)
+
+func TestEvent_Variables(t *testing.T) {
+	pullRequest := &Event{
+		Provider:    GitHubProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		Ref:         "refs/heads/main",
+		Branch:      "main",
+		PullRequest: 1,
+		Action:      PullRequestOpened,
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		expr  string
+		want  bool
+	}{{
+		name:  "absent fork",
+		event: pullRequest,
+		expr:  `!event.fork`,
+		want:  true,
+	}, {
+		name:  "absent author",
+		event: pullRequest,
+		expr:  `event.author != "dependabot[bot]"`,
+		want:  true,
+	}, {
+		name:  "absent labels",
+		event: pullRequest,
+		expr:  `!("skip-ci" in event.labels)`,
+		want:  true,
+	}, {
+		name:  "absent tag",
+		event: pullRequest,
+		expr:  `event.tag == "" && event.branch == "main"`,
+		want:  true,
+	}, {
+		name:  "absent commands",
+		event: pullRequest,
+		expr:  `event.commands.size() == 0 && event.changedFiles.size() == 0`,
+		want:  true,
+	}, {
+		name: "informed attributes",
+		event: &Event{
+			Provider:    GitHubProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			Tag:         "v1.0.0",
+			Author:      "dependabot[bot]",
+			PullRequest: 1,
+			Fork:        true,
+			Labels:      []string{"skip-ci"},
+			Commands:    []Command{{Name: CommandBuild, Args: []string{"build"}}},
+		},
+		expr: `event.fork && event.author == "dependabot[bot]" && "skip-ci" in event.labels && ` +
+			`event.tag == "v1.0.0" && event.pullRequest == 1 && event.commands[0].name == "build"`,
+		want: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := inventory.NewExpressionFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("NewExpressionFilter() error = %v", err)
+			}
+			got, err := f.Matches(tt.event.Variables())
+			if err != nil {
+				t.Fatalf("Matches() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+)
+
+const (
+	// GiteaProvider Gitea provider name, Forgejo deliveries carry the same headers and payloads.
+	GiteaProvider = "gitea"
+
+	// giteaEventHeader header carrying the Gitea event type.
+	giteaEventHeader = "X-Gitea-Event"
+	// giteaDeliveryHeader header carrying the Gitea delivery identifier.
+	giteaDeliveryHeader = "X-Gitea-Delivery"
+	// giteaSignatureHeader header carrying the payload HMAC-SHA256 signature, hex encoded.
+	giteaSignatureHeader = "X-Gitea-Signature"
+
+	// giteaPushEvent Gitea push event type.
+	giteaPushEvent = "push"
+	// giteaCreateEvent Gitea branch or tag creation event type.
+	giteaCreateEvent = "create"
+	// giteaPullRequestEvent Gitea pull-request event type.
+	giteaPullRequestEvent = "pull_request"
+	// giteaPullRequestLabelEvent Gitea pull-request labels updated event type.
+	giteaPullRequestLabelEvent = "pull_request_label"
+
+	// giteaBlankSHA commit SHA informed when the reference is deleted.
+	giteaBlankSHA = "0000000000000000000000000000000000000000"
+)
+
+// ErrSignatureMismatch the payload signature does not match the expected signature.
+var ErrSignatureMismatch = errors.New("payload signature does not match")
+
+// giteaPullRequestActions pull-request actions handled, and the respective neutral action.
+var giteaPullRequestActions = map[string]PullRequestAction{
+	"opened":       PullRequestOpened,
+	"reopened":     PullRequestOpened,
+	"synchronized": PullRequestSynchronized,
+	"closed":       PullRequestClosed,
+}
+
+// GiteaRepository Gitea repository attributes informed on events.
+type GiteaRepository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+}
+
+// GiteaCommit Gitea commit attributes informed on events.
+type GiteaCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// GiteaUser Gitea user attributes informed on events.
+type GiteaUser struct {
+	Login string `json:"login"`
+}
+
+// GiteaPushEvent Gitea "push" payload.
+type GiteaPushEvent struct {
+	Ref     string        `json:"ref"`
+	Before  string        `json:"before"`
+	After   string        `json:"after"`
+	Commits []GiteaCommit `json:"commits"`
+	// TotalCommits amount of commits pushed, the commits list may be limited
+	TotalCommits int             `json:"total_commits"`
+	HeadCommit   *GiteaCommit    `json:"head_commit"`
+	Repository   GiteaRepository `json:"repository"`
+	Pusher       GiteaUser       `json:"pusher"`
+}
+
+// GiteaCreateEvent Gitea "create" payload, the reference is informed using its short name.
+type GiteaCreateEvent struct {
+	SHA        string          `json:"sha"`
+	Ref        string          `json:"ref"`
+	RefType    string          `json:"ref_type"`
+	Repository GiteaRepository `json:"repository"`
+	Sender     GiteaUser       `json:"sender"`
+}
+
+// GiteaPullRequestBranch Gitea pull-request head or base branch.
+type GiteaPullRequestBranch struct {
+	Ref  string          `json:"ref"`
+	SHA  string          `json:"sha"`
+	Repo GiteaRepository `json:"repo"`
+}
+
+// GiteaLabel Gitea label attributes informed on events.
+type GiteaLabel struct {
+	Name string `json:"name"`
+}
+
+// GiteaPullRequestEvent Gitea "pull_request" and "pull_request_label" payload.
+type GiteaPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head   GiteaPullRequestBranch `json:"head"`
+		Base   GiteaPullRequestBranch `json:"base"`
+		Labels []GiteaLabel           `json:"labels"`
+	} `json:"pull_request"`
+	Repository GiteaRepository `json:"repository"`
+	Sender     GiteaUser       `json:"sender"`
+}
+
+// giteaPushEventToEvent transforms the push event, deletions are ignored as well as tags, Gitea
+// informs new tags through the "create" event.
+func giteaPushEventToEvent(push *GiteaPushEvent) (*Event, error) {
+	if push.After == giteaBlankSHA {
+		return nil, fmt.Errorf("%w: reference %q deleted", ErrEventIgnored, push.Ref)
+	}
+	if TagFromRef(push.Ref) != "" {
+		return nil, fmt.Errorf("%w: tags are handled on %q events", ErrEventIgnored, giteaCreateEvent)
+	}
+	headSHA := push.After
+	var headMessage string
+	if push.HeadCommit != nil && push.HeadCommit.ID != "" {
+		headSHA = push.HeadCommit.ID
+		headMessage = push.HeadCommit.Message
+	}
+	var lists [][]string
+	for _, c := range push.Commits {
+		lists = append(lists, c.Added, c.Removed, c.Modified)
+	}
+	return &Event{
+		Provider:     GiteaProvider,
+		Name:         buildapi.GitHubPushEvent,
+		RepoURL:      push.Repository.HTMLURL,
+		Ref:          push.Ref,
+		Branch:       BranchFromRef(push.Ref),
+		HeadSHA:      headSHA,
+		HeadMessage:  headMessage,
+		Author:       push.Pusher.Login,
+		ChangedFiles: mergeChangedFiles(lists...),
+
+		ChangedFilesTruncated: push.TotalCommits > len(push.Commits),
+	}, nil
+}
+
+// giteaCreateEventToEvent transforms the create event, only tags are considered since new branches
+// are informed through the "push" event as well.
+func giteaCreateEventToEvent(create *GiteaCreateEvent) (*Event, error) {
+	if create.RefType != "tag" {
+		return nil, fmt.Errorf("%w: created %q reference", ErrEventIgnored, create.RefType)
+	}
+	ref := fmt.Sprintf("%s%s", tagRefPrefix, create.Ref)
+	return &Event{
+		Provider: GiteaProvider,
+		Name:     buildapi.GitHubPushEvent,
+		RepoURL:  create.Repository.HTMLURL,
+		Ref:      ref,
+		Tag:      create.Ref,
+		HeadSHA:  create.SHA,
+		Author:   create.Sender.Login,
+	}, nil
+}
+
+// giteaPullRequestEventToEvent transforms the pull-request event, Builds are matched against the
+// base branch. Labels updated events carrying the ok-to-test label are transformed into approval
+// events, only users with write access to the repository can label pull-requests.
+func giteaPullRequestEventToEvent(eventType string, pr *GiteaPullRequestEvent) (*Event, error) {
+	var labels []string
+	for _, label := range pr.PullRequest.Labels {
+		labels = append(labels, label.Name)
+	}
+
+	action, ok := giteaPullRequestActions[pr.Action]
+	if eventType == giteaPullRequestLabelEvent {
+		action, ok = PullRequestApproved, pr.Action == "label_updated" && hasOkToTestLabel(labels)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: pull-request action %q", ErrEventIgnored, pr.Action)
+	}
+	repoURL := pr.PullRequest.Base.Repo.HTMLURL
+	if repoURL == "" {
+		repoURL = pr.Repository.HTMLURL
+	}
+	return &Event{
+		Provider:    GiteaProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     repoURL,
+		Ref:         fmt.Sprintf("refs/pull/%d/head", pr.Number),
+		Branch:      pr.PullRequest.Base.Ref,
+		HeadSHA:     pr.PullRequest.Head.SHA,
+		Author:      pr.Sender.Login,
+		PullRequest: pr.Number,
+		Action:      action,
+		Fork:        isFork(pr.PullRequest.Head.Repo.HTMLURL, pr.PullRequest.Base.Repo.HTMLURL),
+		Labels:      labels,
+	}, nil
+}
+
+// ParseGiteaEvent parses the informed payload based on the Gitea event type (header), only push,
+// tag creation, pull-request and pull-request labels events are transformed, others are ignored.
+func ParseGiteaEvent(eventType string, payload []byte) (*Event, error) {
+	switch eventType {
+	case giteaPushEvent:
+		var push GiteaPushEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return nil, err
+		}
+		return giteaPushEventToEvent(&push)
+	case giteaCreateEvent:
+		var create GiteaCreateEvent
+		if err := json.Unmarshal(payload, &create); err != nil {
+			return nil, err
+		}
+		return giteaCreateEventToEvent(&create)
+	case giteaPullRequestEvent, giteaPullRequestLabelEvent:
+		var pr GiteaPullRequestEvent
+		if err := json.Unmarshal(payload, &pr); err != nil {
+			return nil, err
+		}
+		return giteaPullRequestEventToEvent(eventType, &pr)
+	default:
+		return nil, fmt.Errorf("%w: event type %q", ErrEventIgnored, eventType)
+	}
+}
+
+// validateGiteaSignature validates the request payload signature against the token, Gitea informs
+// the hex encoded HMAC-SHA256 without the algorithm prefix.
+func validateGiteaSignature(r *http.Request, payload, token []byte) error {
+	signature := r.Header.Get(giteaSignatureHeader)
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+	received, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSignatureMismatch, err)
+	}
+	mac := hmac.New(sha256.New, token)
+	mac.Write(payload)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
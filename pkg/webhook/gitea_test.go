@@ -0,0 +1,231 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+// signGiteaPayload returns the hex encoded HMAC-SHA256 signature of the payload, as Gitea would.
+func signGiteaPayload(payload, token []byte) string {
+	mac := hmac.New(sha256.New, token)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseGiteaEvent(t *testing.T) {
+	pushDeleted := []byte(strings.Replace(
+		string(stubs.GiteaPushEvent(stubs.GitRef)),
+		`"after": "`+stubs.HeadCommitID+`"`,
+		`"after": "`+giteaBlankSHA+`"`,
+		1,
+	))
+
+	tests := []struct {
+		name        string
+		eventType   string
+		payload     []byte
+		want        *Event
+		wantErr     bool
+		wantIgnored bool
+	}{{
+		name:      "push event",
+		eventType: "push",
+		payload:   stubs.GiteaPushEvent(stubs.GitRef),
+		want: &Event{
+			Provider:     GiteaProvider,
+			Name:         buildapi.GitHubPushEvent,
+			RepoURL:      stubs.GiteaRepoURL,
+			Ref:          stubs.GitRef,
+			Branch:       stubs.Branch,
+			HeadSHA:      stubs.HeadCommitID,
+			HeadMessage:  stubs.HeadCommitMsg,
+			Author:       stubs.PusherLogin,
+			ChangedFiles: stubs.ChangedFiles,
+		},
+	}, {
+		name:        "push event deleting a branch is ignored",
+		eventType:   "push",
+		payload:     pushDeleted,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "push event on a tag is ignored",
+		eventType:   "push",
+		payload:     stubs.GiteaPushEvent("refs/tags/v1.2.3"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "create event for a tag",
+		eventType: "create",
+		payload:   stubs.GiteaCreateEvent("tag", "v1.2.3"),
+		want: &Event{
+			Provider: GiteaProvider,
+			Name:     buildapi.GitHubPushEvent,
+			RepoURL:  stubs.GiteaRepoURL,
+			Ref:      "refs/tags/v1.2.3",
+			Tag:      "v1.2.3",
+			HeadSHA:  stubs.HeadCommitID,
+			Author:   stubs.PusherLogin,
+		},
+	}, {
+		name:        "create event for a branch is ignored",
+		eventType:   "create",
+		payload:     stubs.GiteaCreateEvent("branch", "feature"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "pull-request synchronized event",
+		eventType: "pull_request",
+		payload:   stubs.GiteaPullRequestEvent("synchronized"),
+		want: &Event{
+			Provider:    GiteaProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GiteaRepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestSynchronized,
+		},
+	}, {
+		name:      "pull-request closed event",
+		eventType: "pull_request",
+		payload:   stubs.GiteaPullRequestEvent("closed"),
+		want: &Event{
+			Provider:    GiteaProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GiteaRepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestClosed,
+		},
+	}, {
+		name:      "pull-request labeled as ok-to-test event",
+		eventType: "pull_request_label",
+		payload:   stubs.GiteaPullRequestLabelEvent("label_updated", "bug", OkToTestLabel),
+		want: &Event{
+			Provider:    GiteaProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GiteaRepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestApproved,
+			Labels:      []string{"bug", OkToTestLabel},
+		},
+	}, {
+		name:        "pull-request labeled without ok-to-test event is ignored",
+		eventType:   "pull_request_label",
+		payload:     stubs.GiteaPullRequestLabelEvent("label_updated", "bug"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "pull-request synchronized event carrying labels",
+		eventType: "pull_request",
+		payload:   stubs.GiteaPullRequestLabelEvent("synchronized", OkToTestLabel),
+		want: &Event{
+			Provider:    GiteaProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GiteaRepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestSynchronized,
+			Labels:      []string{OkToTestLabel},
+		},
+	}, {
+		name:        "pull-request edited event is ignored",
+		eventType:   "pull_request",
+		payload:     stubs.GiteaPullRequestEvent("edited"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "unsupported event type is ignored",
+		eventType:   "issues",
+		payload:     []byte("{}"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "bogus payload",
+		eventType: "push",
+		payload:   []byte("bogus"),
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGiteaEvent(tt.eventType, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGiteaEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantIgnored != errors.Is(err, ErrEventIgnored) {
+				t.Errorf("ParseGiteaEvent() error = %v, wantIgnored %v", err, tt.wantIgnored)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGiteaEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGiteaSignature(t *testing.T) {
+	payload := stubs.GiteaPushEvent(stubs.GitRef)
+	token := []byte("token")
+
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   bool
+	}{{
+		name:      "valid signature",
+		signature: signGiteaPayload(payload, token),
+		wantErr:   false,
+	}, {
+		name:      "signature using another token",
+		signature: signGiteaPayload(payload, []byte("another-token")),
+		wantErr:   true,
+	}, {
+		name:      "GitHub style signature",
+		signature: signPayload(payload, token),
+		wantErr:   true,
+	}, {
+		name:      "missing signature",
+		signature: "",
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(giteaSignatureHeader, tt.signature)
+			err := validateGiteaSignature(r, payload, token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGiteaSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"fmt"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+
+	"github.com/google/go-github/v53/github"
+)
+
+// GitHubProvider GitHub provider name.
+const GitHubProvider = "github"
+
+// gitHubMaxPushCommits maximum amount of commits informed on push events, pushes with more
+// commits are truncated.
+const gitHubMaxPushCommits = 2048
+
+// gitHubPullRequestActions pull-request actions handled, and the respective neutral action.
+var gitHubPullRequestActions = map[string]PullRequestAction{
+	"opened":      PullRequestOpened,
+	"reopened":    PullRequestOpened,
+	"synchronize": PullRequestSynchronized,
+	"closed":      PullRequestClosed,
+}
+
+// gitHubPushEventToEvent transforms the informed push event, ignoring reference deletions. The
+// reference may either point to a branch or a tag.
+func gitHubPushEventToEvent(push *github.PushEvent) (*Event, error) {
+	if push.GetDeleted() {
+		return nil, fmt.Errorf("%w: reference %q deleted", ErrEventIgnored, push.GetRef())
+	}
+	return &Event{
+		Provider:     GitHubProvider,
+		Name:         buildapi.GitHubPushEvent,
+		RepoURL:      push.GetRepo().GetHTMLURL(),
+		Ref:          push.GetRef(),
+		Branch:       BranchFromRef(push.GetRef()),
+		Tag:          TagFromRef(push.GetRef()),
+		HeadSHA:      gitHubPushHeadSHA(push),
+		HeadMessage:  push.GetHeadCommit().GetMessage(),
+		Author:       gitHubPushAuthor(push),
+		ChangedFiles: gitHubPushChangedFiles(push),
+
+		ChangedFilesTruncated: len(push.Commits) >= gitHubMaxPushCommits,
+	}, nil
+}
+
+// gitHubPushAuthor extracts the user who pushed, falling back to the head commit author name.
+func gitHubPushAuthor(push *github.PushEvent) string {
+	if login := push.GetSender().GetLogin(); login != "" {
+		return login
+	}
+	return push.GetHeadCommit().GetAuthor().GetName()
+}
+
+// gitHubPushChangedFiles extracts the files changed by all commits pushed.
+func gitHubPushChangedFiles(push *github.PushEvent) []string {
+	var lists [][]string
+	for _, c := range push.Commits {
+		lists = append(lists, c.Added, c.Removed, c.Modified)
+	}
+	return mergeChangedFiles(lists...)
+}
+
+// gitHubPushHeadSHA extracts the head commit SHA, falling back to the "after" attribute.
+func gitHubPushHeadSHA(push *github.PushEvent) string {
+	if sha := push.GetHeadCommit().GetID(); sha != "" {
+		return sha
+	}
+	return push.GetAfter()
+}
+
+// gitHubPullRequestEventToEvent transforms the informed pull-request event, the branch is the
+// pull-request base (target) branch, thus Builds are matched against the branch receiving changes.
+func gitHubPullRequestEventToEvent(pr *github.PullRequestEvent) (*Event, error) {
+	action, ok := gitHubPullRequestActions[pr.GetAction()]
+	if pr.GetAction() == "labeled" && pr.GetLabel().GetName() == OkToTestLabel {
+		action, ok = PullRequestApproved, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: pull-request action %q", ErrEventIgnored, pr.GetAction())
+	}
+
+	var labels []string
+	for _, label := range pr.GetPullRequest().Labels {
+		labels = append(labels, label.GetName())
+	}
+	base := pr.GetPullRequest().GetBase()
+	return &Event{
+		Provider:    GitHubProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     base.GetRepo().GetHTMLURL(),
+		Ref:         fmt.Sprintf("refs/pull/%d/head", pr.GetNumber()),
+		Branch:      base.GetRef(),
+		HeadSHA:     pr.GetPullRequest().GetHead().GetSHA(),
+		Author:      pr.GetPullRequest().GetUser().GetLogin(),
+		PullRequest: pr.GetNumber(),
+		Action:      action,
+		MergeRef:    fmt.Sprintf("refs/pull/%d/merge", pr.GetNumber()),
+		Fork: isFork(
+			pr.GetPullRequest().GetHead().GetRepo().GetHTMLURL(),
+			base.GetRepo().GetHTMLURL(),
+		),
+		Labels:            labels,
+		AuthorAssociation: pr.GetPullRequest().GetAuthorAssociation(),
+	}, nil
+}
+
+// gitHubIssueCommentEventToEvent transforms the comment created on a pull-request by a trusted user
+// into an approval event, when it carries the ok-to-test command, or into a command event when it
+// carries ChatOps commands. Other comments are ignored. The comment payload does not carry the
+// pull-request branches, only its number.
+func gitHubIssueCommentEventToEvent(comment *github.IssueCommentEvent) (*Event, error) {
+	if comment.GetAction() != "created" || comment.GetIssue().GetPullRequestLinks() == nil {
+		return nil, fmt.Errorf("%w: comment is not created on a pull-request", ErrEventIgnored)
+	}
+	body := comment.GetComment().GetBody()
+	commands := ParseCommands(body)
+	action := PullRequestCommand
+	if hasOkToTestCommand(body) {
+		action = PullRequestApproved
+	} else if len(commands) == 0 {
+		return nil, fmt.Errorf("%w: comment does not carry commands", ErrEventIgnored)
+	}
+	association := comment.GetComment().GetAuthorAssociation()
+	if !trustedAuthorAssociations[association] {
+		return nil, fmt.Errorf("%w: comment author association %q is not allowed to run commands",
+			ErrEventIgnored, association)
+	}
+
+	number := comment.GetIssue().GetNumber()
+	event := &Event{
+		Provider:          GitHubProvider,
+		Name:              buildapi.GitHubPullRequestEvent,
+		RepoURL:           comment.GetRepo().GetHTMLURL(),
+		Ref:               fmt.Sprintf("refs/pull/%d/head", number),
+		Author:            comment.GetComment().GetUser().GetLogin(),
+		PullRequest:       number,
+		Action:            action,
+		MergeRef:          fmt.Sprintf("refs/pull/%d/merge", number),
+		AuthorAssociation: association,
+	}
+	if action == PullRequestCommand {
+		event.Commands = commands
+	}
+	return event, nil
+}
+
+// ParseGitHubEvent parses the informed payload based on the GitHub event type (header), only push,
+// pull-request and pull-request comment events are transformed, others are ignored.
+func ParseGitHubEvent(eventType string, payload []byte) (*Event, error) {
+	parsed, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e := parsed.(type) {
+	case *github.PushEvent:
+		return gitHubPushEventToEvent(e)
+	case *github.PullRequestEvent:
+		return gitHubPullRequestEventToEvent(e)
+	case *github.IssueCommentEvent:
+		return gitHubIssueCommentEventToEvent(e)
+	default:
+		return nil, fmt.Errorf("%w: event type %q", ErrEventIgnored, eventType)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	"github.com/google/go-github/v53/github"
+)
+
+// marshalOrFail marshals the informed object as JSON, failing the test on error.
+func marshalOrFail(t *testing.T, obj interface{}) []byte {
+	payload, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+	return payload
+}
+
+func TestParseGitHubEvent(t *testing.T) {
+	pushDeleted := stubs.GitHubPushEvent()
+	deleted := true
+	pushDeleted.Deleted = &deleted
+
+	pushTag := stubs.GitHubPushEvent()
+	tagRef := "refs/tags/v1.2.3"
+	pushTag.Ref = &tagRef
+
+	prFork := stubs.GitHubPullRequestEvent("synchronize")
+	prFork.PullRequest.Head.Repo = &github.Repository{HTMLURL: github.String(stubs.ForkRepoURL)}
+	prFork.PullRequest.AuthorAssociation = github.String("CONTRIBUTOR")
+	prFork.PullRequest.Labels = []*github.Label{{Name: github.String("bug")}}
+
+	prOkToTest := stubs.GitHubPullRequestEvent("labeled")
+	prOkToTest.Label = &github.Label{Name: github.String(OkToTestLabel)}
+
+	tests := []struct {
+		name        string
+		eventType   string
+		payload     interface{}
+		want        *Event
+		wantErr     bool
+		wantIgnored bool
+	}{{
+		name:      "push event",
+		eventType: "push",
+		payload:   stubs.GitHubPushEvent(),
+		want: &Event{
+			Provider:     GitHubProvider,
+			Name:         buildapi.GitHubPushEvent,
+			RepoURL:      stubs.RepoURL,
+			Ref:          stubs.GitRef,
+			Branch:       stubs.Branch,
+			HeadSHA:      stubs.HeadCommitID,
+			HeadMessage:  stubs.HeadCommitMsg,
+			Author:       stubs.HeadCommitAuthorName,
+			ChangedFiles: stubs.ChangedFiles,
+		},
+	}, {
+		name:      "push event on a tag",
+		eventType: "push",
+		payload:   pushTag,
+		want: &Event{
+			Provider:     GitHubProvider,
+			Name:         buildapi.GitHubPushEvent,
+			RepoURL:      stubs.RepoURL,
+			Ref:          tagRef,
+			Tag:          "v1.2.3",
+			HeadSHA:      stubs.HeadCommitID,
+			HeadMessage:  stubs.HeadCommitMsg,
+			Author:       stubs.HeadCommitAuthorName,
+			ChangedFiles: stubs.ChangedFiles,
+		},
+	}, {
+		name:        "push event deleting a branch is ignored",
+		eventType:   "push",
+		payload:     pushDeleted,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "pull-request opened event",
+		eventType: "pull_request",
+		payload:   stubs.GitHubPullRequestEvent("opened"),
+		want: &Event{
+			Provider:    GitHubProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.RepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestOpened,
+			MergeRef:    "refs/pull/1/merge",
+		},
+	}, {
+		name:      "pull-request synchronize event from a fork",
+		eventType: "pull_request",
+		payload:   prFork,
+		want: &Event{
+			Provider:          GitHubProvider,
+			Name:              buildapi.GitHubPullRequestEvent,
+			RepoURL:           stubs.RepoURL,
+			Ref:               "refs/pull/1/head",
+			Branch:            stubs.Branch,
+			HeadSHA:           stubs.HeadCommitID,
+			Author:            stubs.PullRequestAuthor,
+			PullRequest:       stubs.PullRequestNumber,
+			Action:            PullRequestSynchronized,
+			MergeRef:          "refs/pull/1/merge",
+			Fork:              true,
+			Labels:            []string{"bug"},
+			AuthorAssociation: "CONTRIBUTOR",
+		},
+	}, {
+		name:      "pull-request labeled as ok-to-test approves it",
+		eventType: "pull_request",
+		payload:   prOkToTest,
+		want: &Event{
+			Provider:    GitHubProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.RepoURL,
+			Ref:         "refs/pull/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestApproved,
+			MergeRef:    "refs/pull/1/merge",
+		},
+	}, {
+		name:      "ok-to-test comment from a collaborator approves the pull-request",
+		eventType: "issue_comment",
+		payload:   stubs.GitHubIssueCommentEvent("looks safe\r\n/ok-to-test", "COLLABORATOR"),
+		want: &Event{
+			Provider:          GitHubProvider,
+			Name:              buildapi.GitHubPullRequestEvent,
+			RepoURL:           stubs.RepoURL,
+			Ref:               "refs/pull/1/head",
+			Author:            "maintainer",
+			PullRequest:       stubs.PullRequestNumber,
+			Action:            PullRequestApproved,
+			MergeRef:          "refs/pull/1/merge",
+			AuthorAssociation: "COLLABORATOR",
+		},
+	}, {
+		name:      "chatops comment from a member carries the commands",
+		eventType: "issue_comment",
+		payload:   stubs.GitHubIssueCommentEvent("/shp build build-a", "MEMBER"),
+		want: &Event{
+			Provider:          GitHubProvider,
+			Name:              buildapi.GitHubPullRequestEvent,
+			RepoURL:           stubs.RepoURL,
+			Ref:               "refs/pull/1/head",
+			Author:            "maintainer",
+			PullRequest:       stubs.PullRequestNumber,
+			Action:            PullRequestCommand,
+			MergeRef:          "refs/pull/1/merge",
+			Commands:          []Command{{Name: CommandBuild, Args: []string{"build-a"}}},
+			AuthorAssociation: "MEMBER",
+		},
+	}, {
+		name:        "chatops comment from a contributor is ignored",
+		eventType:   "issue_comment",
+		payload:     stubs.GitHubIssueCommentEvent("/shp cancel", "CONTRIBUTOR"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "ok-to-test comment from a contributor is ignored",
+		eventType:   "issue_comment",
+		payload:     stubs.GitHubIssueCommentEvent("/ok-to-test", "CONTRIBUTOR"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "comment without commands is ignored",
+		eventType:   "issue_comment",
+		payload:     stubs.GitHubIssueCommentEvent("please run /ok-to-test", "OWNER"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "pull-request labeled event is ignored",
+		eventType:   "pull_request",
+		payload:     stubs.GitHubPullRequestEvent("labeled"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "ping event is ignored",
+		eventType:   "ping",
+		payload:     stubs.GitHubPingEvent(),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "unknown event type",
+		eventType: "bogus",
+		payload:   stubs.GitHubPingEvent(),
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitHubEvent(tt.eventType, marshalOrFail(t, tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGitHubEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if errors.Is(err, ErrEventIgnored) != tt.wantIgnored {
+				t.Errorf("ParseGitHubEvent() error = %v, wantIgnored %v", err, tt.wantIgnored)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitHubEvent() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
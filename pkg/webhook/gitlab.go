@@ -0,0 +1,253 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+)
+
+const (
+	// GitLabProvider GitLab provider name.
+	GitLabProvider = "gitlab"
+
+	// gitLabEventHeader header carrying the GitLab event type.
+	gitLabEventHeader = "X-Gitlab-Event"
+	// gitLabDeliveryHeader header carrying the GitLab delivery identifier.
+	gitLabDeliveryHeader = "X-Gitlab-Event-UUID"
+	// gitLabTokenHeader header carrying the GitLab webhook secret token.
+	gitLabTokenHeader = "X-Gitlab-Token"
+
+	// gitLabPushHook GitLab push event type.
+	gitLabPushHook = "Push Hook"
+	// gitLabTagPushHook GitLab tag push event type.
+	gitLabTagPushHook = "Tag Push Hook"
+	// gitLabMergeRequestHook GitLab merge-request event type.
+	gitLabMergeRequestHook = "Merge Request Hook"
+
+	// gitLabBlankSHA commit SHA informed when the reference is created or deleted.
+	gitLabBlankSHA = "0000000000000000000000000000000000000000"
+)
+
+// ErrTokenMismatch the request secret token does not match the expected token.
+var ErrTokenMismatch = errors.New("secret token does not match")
+
+// gitLabMergeRequestActions merge-request actions handled, and the respective neutral action,
+// "update" is only considered when new commits are pushed.
+var gitLabMergeRequestActions = map[string]PullRequestAction{
+	"open":   PullRequestOpened,
+	"reopen": PullRequestOpened,
+	"update": PullRequestSynchronized,
+	"close":  PullRequestClosed,
+	"merge":  PullRequestClosed,
+}
+
+// GitLabProject GitLab project attributes informed on events.
+type GitLabProject struct {
+	WebURL            string `json:"web_url"`
+	GitHTTPURL        string `json:"git_http_url"`
+	GitSSHURL         string `json:"git_ssh_url"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+// GitLabPushEvent GitLab "Push Hook" and "Tag Push Hook" payload.
+type GitLabPushEvent struct {
+	ObjectKind   string         `json:"object_kind"`
+	Before       string         `json:"before"`
+	After        string         `json:"after"`
+	Ref          string         `json:"ref"`
+	CheckoutSHA  string         `json:"checkout_sha"`
+	UserName     string         `json:"user_name"`
+	UserUsername string         `json:"user_username"`
+	Project      GitLabProject  `json:"project"`
+	Commits      []GitLabCommit `json:"commits"`
+	// TotalCommitsCount amount of commits pushed, the commits list is limited to 20 entries
+	TotalCommitsCount int `json:"total_commits_count"`
+}
+
+// GitLabCommit GitLab commit attributes informed on events.
+type GitLabCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// GitLabUser GitLab user attributes informed on events.
+type GitLabUser struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+// GitLabMergeRequestAttributes GitLab merge-request attributes.
+type GitLabMergeRequestAttributes struct {
+	IID          int           `json:"iid"`
+	Action       string        `json:"action"`
+	OldRev       string        `json:"oldrev"`
+	SourceBranch string        `json:"source_branch"`
+	TargetBranch string        `json:"target_branch"`
+	LastCommit   GitLabCommit  `json:"last_commit"`
+	Source       GitLabProject `json:"source"`
+	Target       GitLabProject `json:"target"`
+}
+
+// GitLabLabel GitLab label attributes informed on events.
+type GitLabLabel struct {
+	Title string `json:"title"`
+}
+
+// GitLabLabelsChange GitLab labels before and after the merge-request update.
+type GitLabLabelsChange struct {
+	Previous []GitLabLabel `json:"previous"`
+	Current  []GitLabLabel `json:"current"`
+}
+
+// GitLabMergeRequestEvent GitLab "Merge Request Hook" payload.
+type GitLabMergeRequestEvent struct {
+	ObjectKind       string                       `json:"object_kind"`
+	User             GitLabUser                   `json:"user"`
+	Project          GitLabProject                `json:"project"`
+	ObjectAttributes GitLabMergeRequestAttributes `json:"object_attributes"`
+	Labels           []GitLabLabel                `json:"labels"`
+	Changes          struct {
+		Labels *GitLabLabelsChange `json:"labels"`
+	} `json:"changes"`
+}
+
+// gitLabLabelTitles extracts the label titles.
+func gitLabLabelTitles(labels []GitLabLabel) []string {
+	var titles []string
+	for _, label := range labels {
+		titles = append(titles, label.Title)
+	}
+	return titles
+}
+
+// gitLabOkToTestLabeled asserts the merge-request update adds the ok-to-test label, only project
+// members allowed to change the merge-request metadata can label it.
+func gitLabOkToTestLabeled(mr *GitLabMergeRequestEvent) bool {
+	change := mr.Changes.Labels
+	if change == nil {
+		return false
+	}
+	return hasOkToTestLabel(gitLabLabelTitles(change.Current)) &&
+		!hasOkToTestLabel(gitLabLabelTitles(change.Previous))
+}
+
+// gitLabPushEventToEvent transforms the push event, branch and tag deletions are ignored.
+func gitLabPushEventToEvent(push *GitLabPushEvent) (*Event, error) {
+	if push.After == gitLabBlankSHA {
+		return nil, fmt.Errorf("%w: reference %q deleted", ErrEventIgnored, push.Ref)
+	}
+	headSHA := push.CheckoutSHA
+	if headSHA == "" {
+		headSHA = push.After
+	}
+	author := push.UserUsername
+	if author == "" {
+		author = push.UserName
+	}
+	var lists [][]string
+	var headMessage string
+	for _, c := range push.Commits {
+		lists = append(lists, c.Added, c.Removed, c.Modified)
+		if c.ID == headSHA {
+			headMessage = c.Message
+		}
+	}
+	return &Event{
+		Provider:     GitLabProvider,
+		Name:         buildapi.GitHubPushEvent,
+		RepoURL:      push.Project.WebURL,
+		Ref:          push.Ref,
+		Branch:       BranchFromRef(push.Ref),
+		Tag:          TagFromRef(push.Ref),
+		HeadSHA:      headSHA,
+		HeadMessage:  headMessage,
+		Author:       author,
+		ChangedFiles: mergeChangedFiles(lists...),
+
+		ChangedFilesTruncated: push.TotalCommitsCount > len(push.Commits),
+	}, nil
+}
+
+// gitLabMergeRequestEventToEvent transforms the merge-request event, Builds are matched against the
+// merge-request target branch. Adding the ok-to-test label is transformed into an approval event.
+func gitLabMergeRequestEventToEvent(mr *GitLabMergeRequestEvent) (*Event, error) {
+	attrs := mr.ObjectAttributes
+	action, ok := gitLabMergeRequestActions[attrs.Action]
+	if !ok {
+		return nil, fmt.Errorf("%w: merge-request action %q", ErrEventIgnored, attrs.Action)
+	}
+	// updates without "oldrev" are changes on the merge-request attributes, not new commits, only
+	// labeling the merge-request as ok-to-test is taken into account, approving the triggers held
+	if attrs.Action == "update" && attrs.OldRev == "" {
+		if !gitLabOkToTestLabeled(mr) {
+			return nil, fmt.Errorf("%w: merge-request updated without new commits", ErrEventIgnored)
+		}
+		action = PullRequestApproved
+	}
+
+	repoURL := attrs.Target.WebURL
+	if repoURL == "" {
+		repoURL = mr.Project.WebURL
+	}
+	return &Event{
+		Provider:    GitLabProvider,
+		Name:        buildapi.GitHubPullRequestEvent,
+		RepoURL:     repoURL,
+		Ref:         fmt.Sprintf("refs/merge-requests/%d/head", attrs.IID),
+		Branch:      attrs.TargetBranch,
+		HeadSHA:     attrs.LastCommit.ID,
+		HeadMessage: attrs.LastCommit.Message,
+		Author:      mr.User.Username,
+		PullRequest: attrs.IID,
+		Action:      action,
+		MergeRef:    fmt.Sprintf("refs/merge-requests/%d/merge", attrs.IID),
+		Fork:        isFork(attrs.Source.WebURL, attrs.Target.WebURL),
+		Labels:      gitLabLabelTitles(mr.Labels),
+	}, nil
+}
+
+// ParseGitLabEvent parses the informed payload based on the GitLab event type (header), only push,
+// tag push and merge-request events are transformed, others are ignored.
+func ParseGitLabEvent(eventType string, payload []byte) (*Event, error) {
+	switch eventType {
+	case gitLabPushHook, gitLabTagPushHook:
+		var push GitLabPushEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return nil, err
+		}
+		return gitLabPushEventToEvent(&push)
+	case gitLabMergeRequestHook:
+		var mr GitLabMergeRequestEvent
+		if err := json.Unmarshal(payload, &mr); err != nil {
+			return nil, err
+		}
+		return gitLabMergeRequestEventToEvent(&mr)
+	default:
+		return nil, fmt.Errorf("%w: event type %q", ErrEventIgnored, eventType)
+	}
+}
+
+// validateGitLabToken compares the request token with the expected token, GitLab sends the secret
+// token as is instead of signing the payload.
+func validateGitLabToken(r *http.Request, _, token []byte) error {
+	requestToken := strings.TrimSpace(r.Header.Get(gitLabTokenHeader))
+	if requestToken == "" {
+		return ErrSignatureMissing
+	}
+	if subtle.ConstantTimeCompare([]byte(requestToken), token) != 1 {
+		return ErrTokenMismatch
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+func TestParseGitLabEvent(t *testing.T) {
+	pushDeleted := stubs.GitLabPushEvent(stubs.GitRef)
+	pushDeleted["after"] = gitLabBlankSHA
+
+	mrUpdatedWithoutCommits := stubs.GitLabMergeRequestEvent("update")
+	delete(mrUpdatedWithoutCommits["object_attributes"].(map[string]interface{}), "oldrev")
+
+	mrLabeled := stubs.GitLabMergeRequestEvent("update")
+	delete(mrLabeled["object_attributes"].(map[string]interface{}), "oldrev")
+	mrLabeled["labels"] = []interface{}{map[string]interface{}{"title": OkToTestLabel}}
+	mrLabeled["changes"] = map[string]interface{}{"labels": map[string]interface{}{
+		"previous": []interface{}{},
+		"current":  []interface{}{map[string]interface{}{"title": OkToTestLabel}},
+	}}
+
+	mrLabeledOther := stubs.GitLabMergeRequestEvent("update")
+	delete(mrLabeledOther["object_attributes"].(map[string]interface{}), "oldrev")
+	mrLabeledOther["changes"] = map[string]interface{}{"labels": map[string]interface{}{
+		"previous": []interface{}{map[string]interface{}{"title": OkToTestLabel}},
+		"current": []interface{}{
+			map[string]interface{}{"title": OkToTestLabel},
+			map[string]interface{}{"title": "bug"},
+		},
+	}}
+
+	tests := []struct {
+		name        string
+		eventType   string
+		payload     interface{}
+		want        *Event
+		wantErr     bool
+		wantIgnored bool
+	}{{
+		name:      "push event",
+		eventType: gitLabPushHook,
+		payload:   stubs.GitLabPushEvent(stubs.GitRef),
+		want: &Event{
+			Provider:     GitLabProvider,
+			Name:         buildapi.GitHubPushEvent,
+			RepoURL:      stubs.GitLabRepoURL,
+			Ref:          stubs.GitRef,
+			Branch:       stubs.Branch,
+			HeadSHA:      stubs.HeadCommitID,
+			HeadMessage:  stubs.HeadCommitMsg,
+			Author:       stubs.HeadCommitAuthorName,
+			ChangedFiles: stubs.ChangedFiles,
+		},
+	}, {
+		name:      "tag push event",
+		eventType: gitLabTagPushHook,
+		payload:   stubs.GitLabPushEvent("refs/tags/v1.2.3"),
+		want: &Event{
+			Provider:     GitLabProvider,
+			Name:         buildapi.GitHubPushEvent,
+			RepoURL:      stubs.GitLabRepoURL,
+			Ref:          "refs/tags/v1.2.3",
+			Tag:          "v1.2.3",
+			HeadSHA:      stubs.HeadCommitID,
+			HeadMessage:  stubs.HeadCommitMsg,
+			Author:       stubs.HeadCommitAuthorName,
+			ChangedFiles: stubs.ChangedFiles,
+		},
+	}, {
+		name:        "push event deleting a branch is ignored",
+		eventType:   gitLabPushHook,
+		payload:     pushDeleted,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "merge-request opened event",
+		eventType: gitLabMergeRequestHook,
+		payload:   stubs.GitLabMergeRequestEvent("open"),
+		want: &Event{
+			Provider:    GitLabProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GitLabRepoURL,
+			Ref:         "refs/merge-requests/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			HeadMessage: stubs.HeadCommitMsg,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestOpened,
+			MergeRef:    "refs/merge-requests/1/merge",
+		},
+	}, {
+		name:        "merge-request updated without new commits is ignored",
+		eventType:   gitLabMergeRequestHook,
+		payload:     mrUpdatedWithoutCommits,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "merge-request labeled as ok-to-test event",
+		eventType: gitLabMergeRequestHook,
+		payload:   mrLabeled,
+		want: &Event{
+			Provider:    GitLabProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GitLabRepoURL,
+			Ref:         "refs/merge-requests/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			HeadMessage: stubs.HeadCommitMsg,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestApproved,
+			MergeRef:    "refs/merge-requests/1/merge",
+			Labels:      []string{OkToTestLabel},
+		},
+	}, {
+		name:        "merge-request labeled when already ok-to-test is ignored",
+		eventType:   gitLabMergeRequestHook,
+		payload:     mrLabeledOther,
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "merge-request merged event",
+		eventType: gitLabMergeRequestHook,
+		payload:   stubs.GitLabMergeRequestEvent("merge"),
+		want: &Event{
+			Provider:    GitLabProvider,
+			Name:        buildapi.GitHubPullRequestEvent,
+			RepoURL:     stubs.GitLabRepoURL,
+			Ref:         "refs/merge-requests/1/head",
+			Branch:      stubs.Branch,
+			HeadSHA:     stubs.HeadCommitID,
+			HeadMessage: stubs.HeadCommitMsg,
+			Author:      stubs.PullRequestAuthor,
+			PullRequest: stubs.PullRequestNumber,
+			Action:      PullRequestClosed,
+			MergeRef:    "refs/merge-requests/1/merge",
+		},
+	}, {
+		name:        "merge-request approved event is ignored",
+		eventType:   gitLabMergeRequestHook,
+		payload:     stubs.GitLabMergeRequestEvent("approved"),
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:        "unsupported event type is ignored",
+		eventType:   "Pipeline Hook",
+		payload:     map[string]interface{}{},
+		wantErr:     true,
+		wantIgnored: true,
+	}, {
+		name:      "bogus payload",
+		eventType: gitLabPushHook,
+		payload:   "bogus",
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitLabEvent(tt.eventType, marshalOrFail(t, tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGitLabEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantIgnored != errors.Is(err, ErrEventIgnored) {
+				t.Errorf("ParseGitLabEvent() error = %v, wantIgnored %v", err, tt.wantIgnored)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitLabEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGitLabToken(t *testing.T) {
+	token := []byte("token")
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{{
+		name:    "valid token",
+		header:  "token",
+		wantErr: false,
+	}, {
+		name:    "another token",
+		header:  "another-token",
+		wantErr: true,
+	}, {
+		name:    "missing token",
+		header:  "",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(gitLabTokenHeader, tt.header)
+			err := validateGitLabToken(r, nil, token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGitLabToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
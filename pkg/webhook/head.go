@@ -0,0 +1,142 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/store"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PullRequestHeadsConfigMapName name of the ConfigMap storing the head commit of the open
+	// pull-requests, one instance per namespace.
+	PullRequestHeadsConfigMapName = "shipwright-triggers-pull-request-heads"
+	// pullRequestHeadTTL amount of time a pull-request head is kept without new events, so heads of
+	// pull-requests closed while the webhook was unreachable are eventually purged.
+	pullRequestHeadTTL = 30 * 24 * time.Hour
+)
+
+// PullRequestHead pull-request head commit recorded from the events authorized on the namespace
+// Builds, stored as JSON on the namespace's pull-request heads ConfigMap.
+type PullRequestHead struct {
+	RepoURL     string      `json:"repoURL"`            // pull-request base repository URL
+	PullRequest int         `json:"pullRequest"`        // pull-request number
+	Branch      string      `json:"branch"`             // pull-request base branch
+	Ref         string      `json:"ref"`                // pull-request head reference
+	HeadSHA     string      `json:"headSHA"`            // pull-request head commit SHA
+	MergeRef    string      `json:"mergeRef,omitempty"` // pull-request merge reference
+	UpdatedAt   metav1.Time `json:"updatedAt"`          // moment the head has been recorded
+}
+
+// isExpired asserts the head is expired at the informed moment.
+func (h *PullRequestHead) isExpired(now time.Time) bool {
+	return !now.Before(h.UpdatedAt.Add(pullRequestHeadTTL))
+}
+
+// resolve completes the command event with the pull-request base branch and head commit,
+// attributes not informed on comments.
+func (h *PullRequestHead) resolve(event *Event) *Event {
+	resolved := *event
+	resolved.Branch = h.Branch
+	resolved.Ref = h.Ref
+	resolved.HeadSHA = h.HeadSHA
+	resolved.MergeRef = h.MergeRef
+	return &resolved
+}
+
+// pullRequestHeadKey returns the ConfigMap key for the event repository and pull-request.
+func pullRequestHeadKey(event *Event) string {
+	return fmt.Sprintf("%s.%d", repoLabelValue(event.RepoURL), event.PullRequest)
+}
+
+// headStore stores the pull-request heads recorded, invalid and expired heads are purged.
+var headStore = store.NewStore(
+	PullRequestHeadsConfigMapName,
+	filter.PullRequestHeads,
+	func(head *PullRequestHead) bool { return head.HeadSHA != "" && !head.isExpired(time.Now()) },
+)
+
+// updatePullRequestHeads retrieves, or creates, the namespace's pull-request heads ConfigMap and
+// applies the mutate function on the heads stored. Invalid and expired heads are purged on every
+// update. The update is retried on conflicts.
+func (w *WebHook) updatePullRequestHeads(
+	ctx context.Context,
+	namespace string,
+	mutateFn func(map[string]*PullRequestHead),
+) error {
+	return headStore.Update(ctx, w.Client, namespace,
+		func(heads map[string]*PullRequestHead) (bool, error) {
+			mutateFn(heads)
+			return true, nil
+		})
+}
+
+// recordPullRequestHead records the event pull-request head on the namespaces of the authorized
+// Builds matching the event, closing the pull-request removes it. The Builds are searched before
+// directives and filters are applied, so commands act on the newest commit even when it has not
+// triggered any Build.
+func (w *WebHook) recordPullRequestHead(
+	ctx context.Context,
+	logger logr.Logger,
+	validateFn validateFn,
+	event *Event,
+) error {
+	namespaces := map[string]bool{}
+	for _, result := range w.authorizeResults(ctx, logger, validateFn, w.search(event)) {
+		namespaces[result.BuildName.Namespace] = true
+	}
+
+	key := pullRequestHeadKey(event)
+	head := &PullRequestHead{
+		RepoURL:     event.RepoURL,
+		PullRequest: event.PullRequest,
+		Branch:      event.Branch,
+		Ref:         event.Ref,
+		HeadSHA:     event.HeadSHA,
+		MergeRef:    event.MergeRef,
+		UpdatedAt:   metav1.Now(),
+	}
+	for namespace := range namespaces {
+		err := w.updatePullRequestHeads(ctx, namespace, func(heads map[string]*PullRequestHead) {
+			if event.IsPullRequestClosed() || event.HeadSHA == "" {
+				delete(heads, key)
+				return
+			}
+			heads[key] = head
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchPullRequestHeads searches all namespaces for the event pull-request head, returns the heads
+// recorded indexed by namespace.
+func (w *WebHook) searchPullRequestHeads(
+	ctx context.Context,
+	event *Event,
+) (map[string]*PullRequestHead, error) {
+	namespaces, err := headStore.List(ctx, w.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	key := pullRequestHeadKey(event)
+	heads := map[string]*PullRequestHead{}
+	for namespace, entries := range namespaces {
+		if head, ok := entries[key]; ok {
+			heads[namespace] = head
+		}
+	}
+	return heads, nil
+}
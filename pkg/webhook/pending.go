@@ -0,0 +1,199 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/store"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// PendingConfigMapName name of the ConfigMap storing the pull-request triggers held for
+	// approval, one instance per namespace.
+	PendingConfigMapName = "shipwright-triggers-pending"
+	// DefaultPendingTTL default amount of time triggers are held waiting for approval.
+	DefaultPendingTTL = 24 * time.Hour
+)
+
+// PendingTrigger pull-request trigger held for approval, stored as JSON on the namespace's pending
+// triggers ConfigMap.
+type PendingTrigger struct {
+	BuildName string      `json:"buildName"` // build matching the event
+	Event     *Event      `json:"event"`     // pull-request event held, trimmed
+	CreatedAt metav1.Time `json:"createdAt"` // moment the trigger has been held
+	ExpiresAt metav1.Time `json:"expiresAt"` // moment the trigger is discarded
+}
+
+// isExpired asserts the trigger is expired at the informed moment.
+func (p *PendingTrigger) isExpired(now time.Time) bool {
+	return !now.Before(p.ExpiresAt.Time)
+}
+
+// pendingKey returns the ConfigMap key for the Build and pull-request, new commits pushed to the
+// pull-request replace the trigger held.
+func pendingKey(buildName string, event *Event) string {
+	return fmt.Sprintf("%s.%s.%d", buildName, repoLabelValue(event.RepoURL), event.PullRequest)
+}
+
+// pendingStore stores the pull-request triggers held, invalid and expired triggers are purged.
+var pendingStore = store.NewStore(
+	PendingConfigMapName,
+	filter.PendingTriggers,
+	func(trigger *PendingTrigger) bool {
+		return trigger.Event != nil && !trigger.isExpired(time.Now())
+	},
+)
+
+// updatePendingTriggers retrieves, or creates, the namespace's pending triggers ConfigMap and
+// applies the mutate function on the triggers stored. Invalid and expired triggers are purged on
+// every update. The update is retried on conflicts.
+func (w *WebHook) updatePendingTriggers(
+	ctx context.Context,
+	namespace string,
+	mutateFn func(map[string]*PendingTrigger),
+) error {
+	return pendingStore.Update(ctx, w.Client, namespace,
+		func(triggers map[string]*PendingTrigger) (bool, error) {
+			mutateFn(triggers)
+			return true, nil
+		})
+}
+
+// holdPullRequestTriggers stores the pull-request event as pending for each Build in the search
+// results, instead of issuing BuildRuns. Returns the Build names held.
+func (w *WebHook) holdPullRequestTriggers(
+	ctx context.Context,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	now := time.Now()
+	stored := event.trimmed()
+	var held []string
+	for _, result := range results {
+		trigger := &PendingTrigger{
+			BuildName: result.BuildName.Name,
+			Event:     stored,
+			CreatedAt: metav1.NewTime(now),
+			ExpiresAt: metav1.NewTime(now.Add(w.pendingTTL)),
+		}
+		err := w.updatePendingTriggers(ctx, result.BuildName.Namespace,
+			func(triggers map[string]*PendingTrigger) {
+				triggers[pendingKey(result.BuildName.Name, event)] = trigger
+			})
+		if err != nil {
+			return held, err
+		}
+		held = append(held, result.BuildName.String())
+	}
+	return held, nil
+}
+
+// dropPullRequestTriggers removes the triggers held for the event pull-request, for each Build in
+// the search results.
+func (w *WebHook) dropPullRequestTriggers(
+	ctx context.Context,
+	event *Event,
+	results []inventory.SearchResult,
+) error {
+	for _, result := range results {
+		err := w.updatePendingTriggers(ctx, result.BuildName.Namespace,
+			func(triggers map[string]*PendingTrigger) {
+				delete(triggers, pendingKey(result.BuildName.Name, event))
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchPendingTriggers searches all namespaces for the triggers held for the event pull-request,
+// returning the search results for the respective Builds, and the triggers indexed by Build.
+// Builds which no longer exist are skipped, their triggers expire eventually.
+func (w *WebHook) searchPendingTriggers(
+	ctx context.Context,
+	logger logr.Logger,
+	event *Event,
+) ([]inventory.SearchResult, map[types.NamespacedName]*PendingTrigger, error) {
+	namespaces, err := pendingStore.List(ctx, w.Client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := []inventory.SearchResult{}
+	pending := map[types.NamespacedName]*PendingTrigger{}
+	for namespace, triggers := range namespaces {
+		for _, trigger := range triggers {
+			if trigger.Event.PullRequest != event.PullRequest ||
+				!inventory.CompareURLs(trigger.Event.RepoURL, event.RepoURL) {
+				continue
+			}
+
+			buildName := types.NamespacedName{Namespace: namespace, Name: trigger.BuildName}
+			var b buildapi.Build
+			if err := w.Get(ctx, buildName, &b); err != nil {
+				if apierrors.IsNotFound(err) {
+					logger.V(0).Info("Build held for approval is not found", "build-name", buildName)
+					continue
+				}
+				return nil, nil, err
+			}
+
+			result := inventory.SearchResult{BuildName: buildName}
+			if b.Spec.Trigger != nil && b.Spec.Trigger.TriggerSecret != nil {
+				result.SecretName = types.NamespacedName{
+					Namespace: buildName.Namespace,
+					Name:      *b.Spec.Trigger.TriggerSecret,
+				}
+			}
+			results = append(results, result)
+			pending[buildName] = trigger
+		}
+	}
+	return results, pending, nil
+}
+
+// releasePendingTriggers issues the BuildRuns for the triggers held, using the original event, for
+// each Build in the search results, and removes the triggers released. Returns the BuildRun names
+// created.
+func (w *WebHook) releasePendingTriggers(
+	ctx context.Context,
+	pending map[types.NamespacedName]*PendingTrigger,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	var created []string
+	for _, result := range results {
+		trigger, ok := pending[result.BuildName]
+		if !ok {
+			continue
+		}
+		var b buildapi.Build
+		if err := w.Get(ctx, result.BuildName, &b); err != nil {
+			return created, err
+		}
+		br := generateBuildRun(trigger.Event, &b)
+		if err := createBuildRun(ctx, w.Client, w.limiter, trigger.Event, &b, br); err != nil {
+			return created, err
+		}
+		created = append(created, br.GetName())
+
+		err := w.dropPullRequestTriggers(ctx, trigger.Event, []inventory.SearchResult{result})
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
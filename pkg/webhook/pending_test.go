@@ -0,0 +1,404 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	"github.com/google/go-github/v53/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getPendingTriggers retrieves the pending triggers stored in the test namespace, skipping the
+// expired ones.
+func getPendingTriggers(t *testing.T, c client.Client) map[string]*PendingTrigger {
+	var cm corev1.ConfigMap
+	err := c.Get(context.TODO(), types.NamespacedName{
+		Namespace: stubs.Namespace,
+		Name:      PendingConfigMapName,
+	}, &cm)
+	if err != nil {
+		t.Fatalf("failed to get pending triggers ConfigMap: %v", err)
+	}
+	return pendingStore.Decode(&cm)
+}
+
+func TestEvent_RequiresApproval(t *testing.T) {
+	tests := []struct {
+		name             string
+		event            Event
+		want             bool
+		wantUnapprovable bool
+	}{{
+		name:  "push event",
+		event: Event{Provider: GitHubProvider, Name: buildapi.GitHubPushEvent},
+		want:  false,
+	}, {
+		name: "pull-request from the same repository",
+		event: Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestOpened,
+		},
+		want: false,
+	}, {
+		name: "pull-request from a fork",
+		event: Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestOpened,
+			Fork:     true,
+		},
+		want: true,
+	}, {
+		name: "pull-request from a fork labeled as ok-to-test",
+		event: Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestSynchronized,
+			Fork:     true,
+			Labels:   []string{OkToTestLabel},
+		},
+		want: false,
+	}, {
+		name: "pull-request from a collaborator",
+		event: Event{
+			Provider:          GitHubProvider,
+			Name:              buildapi.GitHubPullRequestEvent,
+			Action:            PullRequestSynchronized,
+			AuthorAssociation: "COLLABORATOR",
+		},
+		want: false,
+	}, {
+		name: "pull-request from a first time contributor",
+		event: Event{
+			Provider:          GitHubProvider,
+			Name:              buildapi.GitHubPullRequestEvent,
+			Action:            PullRequestOpened,
+			AuthorAssociation: "FIRST_TIME_CONTRIBUTOR",
+		},
+		want: true,
+	}, {
+		name: "pull-request from a fork on gitlab",
+		event: Event{
+			Provider: GitLabProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestOpened,
+			Fork:     true,
+		},
+		want: true,
+	}, {
+		name: "pull-request from a fork on gitea",
+		event: Event{
+			Provider: GiteaProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestOpened,
+			Fork:     true,
+		},
+		want: true,
+	}, {
+		name: "pull-request from a fork on bitbucket, which can't approve triggers held",
+		event: Event{
+			Provider: BitbucketProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestOpened,
+			Fork:     true,
+		},
+		want:             false,
+		wantUnapprovable: true,
+	}, {
+		name: "pull-request from a fork on bitbucket server, which can't approve triggers held",
+		event: Event{
+			Provider: BitbucketServerProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestSynchronized,
+			Fork:     true,
+		},
+		want:             false,
+		wantUnapprovable: true,
+	}, {
+		name: "closed pull-request from a fork",
+		event: Event{
+			Provider: GitHubProvider,
+			Name:     buildapi.GitHubPullRequestEvent,
+			Action:   PullRequestClosed,
+			Fork:     true,
+		},
+		want: false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.RequiresApproval(); got != tt.want {
+				t.Errorf("RequiresApproval() = %v, want %v", got, tt.want)
+			}
+			if got := tt.event.IsUnapprovable(); got != tt.wantUnapprovable {
+				t.Errorf("IsUnapprovable() = %v, want %v", got, tt.wantUnapprovable)
+			}
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPPendingTriggers(t *testing.T) {
+	buildWithPullRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-pull-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	key := pendingKey(buildWithPullRequestTrigger.GetName(), &Event{
+		RepoURL:     stubs.RepoURL,
+		PullRequest: stubs.PullRequestNumber,
+	})
+
+	// forkPullRequest returns the stub pull-request event coming from a fork
+	forkPullRequest := func(action string) github.PullRequestEvent {
+		pr := stubs.GitHubPullRequestEvent(action)
+		pr.PullRequest.Head.Repo = &github.Repository{HTMLURL: github.String(stubs.ForkRepoURL)}
+		return pr
+	}
+
+	// setup instantiates the WebHook with the Build in the Inventory
+	setup := func(t *testing.T) (client.Client, *WebHook) {
+		buildInventory := inventory.NewInventory()
+		buildInventory.Add(buildWithPullRequestTrigger)
+		c := newFakeClient(t, buildWithPullRequestTrigger)
+		return c, NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+	}
+
+	// serve sends the GitHub event to the WebHook, returning the response
+	serve := func(t *testing.T, w *WebHook, r *http.Request) (int, Response) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, r)
+		var response Response
+		g := gomega.NewWithT(t)
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	t.Run("pull-request from a fork is held until approved", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		c, w := setup(t)
+
+		code, _ := serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("opened")))
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+		g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+		pending := getPendingTriggers(t, c)
+		g.Expect(pending).To(gomega.HaveKey(key))
+		g.Expect(pending[key].BuildName).To(gomega.Equal(buildWithPullRequestTrigger.GetName()))
+		g.Expect(pending[key].Event.HeadSHA).To(gomega.Equal(stubs.HeadCommitID))
+		// only the event attributes needed to issue the BuildRun are stored
+		g.Expect(pending[key].Event.Fork).To(gomega.BeFalse())
+		g.Expect(pending[key].Event.Labels).To(gomega.BeEmpty())
+		g.Expect(pending[key].ExpiresAt.Sub(pending[key].CreatedAt.Time)).
+			To(gomega.Equal(DefaultPendingTTL))
+
+		// comments from users who are not collaborators are ignored
+		code, _ = serve(t, w, newGitHubRequest(t, "issue_comment",
+			stubs.GitHubIssueCommentEvent(okToTestCommand, "NONE")))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+		code, response := serve(t, w, newGitHubRequest(t, "issue_comment",
+			stubs.GitHubIssueCommentEvent(okToTestCommand, "MEMBER")))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(response.BuildRuns).To(gomega.HaveLen(1))
+		g.Expect(getPendingTriggers(t, c)).To(gomega.BeEmpty())
+
+		brs := listBuildRuns(t, c)
+		g.Expect(brs).To(gomega.HaveLen(1))
+		g.Expect(*brs[0].Spec.Build.Spec.Source.Git.Revision).To(gomega.Equal(stubs.HeadCommitID))
+		g.Expect(brs[0].GetLabels()).To(gomega.HaveKeyWithValue(filter.WebHookPullRequest, "1"))
+	})
+
+	t.Run("labeling the pull-request releases the triggers held", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		c, w := setup(t)
+
+		code, _ := serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("opened")))
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+
+		labeled := forkPullRequest("labeled")
+		labeled.Label = &github.Label{Name: github.String(OkToTestLabel)}
+		code, _ = serve(t, w, newGitHubRequest(t, "pull_request", labeled))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+
+		// the pull-request carries the label from now on, new commits are not held anymore
+		synchronized := forkPullRequest("synchronize")
+		synchronized.PullRequest.Labels = []*github.Label{{Name: github.String(OkToTestLabel)}}
+		synchronized.PullRequest.Head.SHA = github.String("another-commit-id")
+		code, _ = serve(t, w, newGitHubRequest(t, "pull_request", synchronized))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(2))
+	})
+
+	t.Run("closing the pull-request drops the triggers held", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		c, w := setup(t)
+
+		code, _ := serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("opened")))
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+		g.Expect(getPendingTriggers(t, c)).To(gomega.HaveKey(key))
+
+		code, _ = serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("closed")))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(getPendingTriggers(t, c)).To(gomega.BeEmpty())
+	})
+
+	t.Run("closing the pull-request drops the triggers held by Builds filtered out", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		c, w := setup(t)
+
+		code, _ := serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("opened")))
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+		g.Expect(getPendingTriggers(t, c)).To(gomega.HaveKey(key))
+
+		// the Build filter expression does not match closing the pull-request anymore
+		b := buildWithPullRequestTrigger.DeepCopy()
+		b.SetAnnotations(map[string]string{filter.BuildFilter: `event.action == "opened"`})
+		w.buildInventory.Add(b)
+
+		code, _ = serve(t, w, newGitHubRequest(t, "pull_request", forkPullRequest("closed")))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(getPendingTriggers(t, c)).To(gomega.BeEmpty())
+	})
+
+	t.Run("expired triggers are not released", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		c, w := setup(t)
+
+		now := time.Now()
+		value, err := json.Marshal(&PendingTrigger{
+			BuildName: buildWithPullRequestTrigger.GetName(),
+			Event:     &Event{RepoURL: stubs.RepoURL, PullRequest: stubs.PullRequestNumber},
+			CreatedAt: metav1.NewTime(now.Add(-2 * time.Hour)),
+			ExpiresAt: metav1.NewTime(now.Add(-time.Hour)),
+		})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(c.Create(context.TODO(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: stubs.Namespace,
+				Name:      PendingConfigMapName,
+				Labels:    map[string]string{filter.PendingTriggers: "true"},
+			},
+			Data: map[string]string{key: string(value)},
+		})).To(gomega.Succeed())
+
+		code, response := serve(t, w, newGitHubRequest(t, "issue_comment",
+			stubs.GitHubIssueCommentEvent(okToTestCommand, "OWNER")))
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+		g.Expect(response.BuildRuns).To(gomega.BeEmpty())
+		g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+	})
+}
+
+func TestWebHook_ServeHTTPPendingTriggersGitLab(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	b := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-gitlab",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	b.Spec.Source.Git.URL = stubs.GitLabRepoURL
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(b)
+	c := newFakeClient(t, b)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// serve sends the merge-request event to the WebHook, returning the response code
+	serve := func(payload map[string]interface{}) int {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(marshalOrFail(t, payload)))
+		r.Header.Set(gitLabEventHeader, gitLabMergeRequestHook)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// the merge-request comes from a fork, thus it's held until labeled as ok-to-test
+	opened := stubs.GitLabMergeRequestEvent("open")
+	attrs := opened["object_attributes"].(map[string]interface{})
+	attrs["source"] = map[string]interface{}{"web_url": "https://gitlab.com/contributor/sample-nodejs"}
+	g.Expect(serve(opened)).To(gomega.Equal(http.StatusAccepted))
+	g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+	g.Expect(getPendingTriggers(t, c)).To(gomega.HaveLen(1))
+
+	labeled := stubs.GitLabMergeRequestEvent("update")
+	delete(labeled["object_attributes"].(map[string]interface{}), "oldrev")
+	labeled["changes"] = map[string]interface{}{"labels": map[string]interface{}{
+		"previous": []interface{}{},
+		"current":  []interface{}{map[string]interface{}{"title": OkToTestLabel}},
+	}}
+	g.Expect(serve(labeled)).To(gomega.Equal(http.StatusOK))
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+	g.Expect(getPendingTriggers(t, c)).To(gomega.BeEmpty())
+}
+
+func TestWebHook_ServeHTTPUnapprovablePullRequest(t *testing.T) {
+	b := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-bitbucket",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	b.Spec.Source.Git.URL = stubs.BitbucketCloudRepoURL + ".git"
+
+	tests := []struct {
+		name          string
+		sourceURL     string
+		wantCode      int
+		wantBuildRuns int
+	}{{
+		name:          "pull-request from the same repository issues a BuildRun",
+		sourceURL:     stubs.BitbucketCloudRepoURL,
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name:          "pull-request from a fork is ignored",
+		sourceURL:     "https://bitbucket.org/contributor/sample-nodejs",
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(b)
+			c := newFakeClient(t, b)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			payload := stubs.BitbucketCloudPullRequestEvent()
+			source := payload["pullrequest"].(map[string]interface{})["source"].(map[string]interface{})
+			source["repository"] = map[string]interface{}{"links": map[string]interface{}{
+				"html": map[string]interface{}{"href": tt.sourceURL},
+			}}
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(marshalOrFail(t, payload)))
+			r.Header.Set(bitbucketEventHeader, "pullrequest:created")
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, r)
+			g.Expect(rec.Code).To(gomega.Equal(tt.wantCode))
+			g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(tt.wantBuildRuns))
+		})
+	}
+}
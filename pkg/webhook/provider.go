@@ -0,0 +1,154 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownProvider the informed provider name is not registered.
+var ErrUnknownProvider = errors.New("unknown webhook provider")
+
+// Provider handles the webhook requests sent by a Git provider (forge), it identifies the requests
+// belonging to the provider, verifies their authenticity and normalizes the payload into the
+// provider-neutral Event, consumed by the rest of the webhook subsystem.
+type Provider interface {
+	// Name returns the provider name.
+	Name() string
+	// Detect asserts the request has been sent by the provider.
+	Detect(r *http.Request) bool
+	// DeliveryID returns the request delivery identifier, when informed.
+	DeliveryID(r *http.Request) string
+	// Verify validates the request authenticity using the token stored on the Build TriggerSecret.
+	Verify(r *http.Request, payload, token []byte) error
+	// Parse transforms the request payload into an Event, returns ErrEventIgnored for events which
+	// are not meant to trigger Builds.
+	Parse(r *http.Request, payload []byte) (*Event, error)
+}
+
+// provider describes the providers identified by an event type header.
+type provider struct {
+	name            string                                    // provider name
+	eventHeader     string                                    // header carrying the event type
+	deliveryHeaders []string                                  // headers carrying the delivery ID
+	parseFn         func(string, []byte) (*Event, error)      // transforms the payload into Event
+	validateFn      func(*http.Request, []byte, []byte) error // validates the request with token
+}
+
+var _ Provider = &provider{}
+
+// Name returns the provider name.
+func (p *provider) Name() string {
+	return p.name
+}
+
+// Detect asserts the request carries the provider event header.
+func (p *provider) Detect(r *http.Request) bool {
+	return r.Header.Get(p.eventHeader) != ""
+}
+
+// DeliveryID returns the first delivery header informed.
+func (p *provider) DeliveryID(r *http.Request) string {
+	for _, header := range p.deliveryHeaders {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// Verify validates the request using the provider validation function.
+func (p *provider) Verify(r *http.Request, payload, token []byte) error {
+	return p.validateFn(r, payload, token)
+}
+
+// Parse parses the payload based on the event type header.
+func (p *provider) Parse(r *http.Request, payload []byte) (*Event, error) {
+	return p.parseFn(r.Header.Get(p.eventHeader), payload)
+}
+
+// builtinProviders providers shipped with the project, in detection order. Gitea also sends GitHub
+// headers, therefore it must be detected first.
+var builtinProviders = []Provider{
+	&provider{
+		name:            GiteaProvider,
+		eventHeader:     giteaEventHeader,
+		deliveryHeaders: []string{giteaDeliveryHeader},
+		parseFn:         ParseGiteaEvent,
+		validateFn:      validateGiteaSignature,
+	},
+	&provider{
+		name:            GitHubProvider,
+		eventHeader:     "X-GitHub-Event",
+		deliveryHeaders: []string{"X-GitHub-Delivery"},
+		parseFn:         ParseGitHubEvent,
+		validateFn:      validateGitHubSignature,
+	},
+	&provider{
+		name:            GitLabProvider,
+		eventHeader:     gitLabEventHeader,
+		deliveryHeaders: []string{gitLabDeliveryHeader},
+		parseFn:         ParseGitLabEvent,
+		validateFn:      validateGitLabToken,
+	},
+	&provider{
+		name:            BitbucketProvider,
+		eventHeader:     bitbucketEventHeader,
+		deliveryHeaders: []string{bitbucketCloudDeliveryHeader, bitbucketServerDeliveryHeader},
+		parseFn:         ParseBitbucketEvent,
+		validateFn:      validateBitbucketSignature,
+	},
+}
+
+// ProviderNames returns the names of the builtin providers.
+func ProviderNames() []string {
+	names := make([]string, 0, len(builtinProviders))
+	for _, p := range builtinProviders {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// NewProviders returns the builtin providers matching the informed names, keeping the detection
+// order regardless of the names order. Returns error when a name is not registered.
+func NewProviders(names []string) ([]Provider, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[name] = true
+		}
+	}
+
+	providers := []Provider{}
+	for _, p := range builtinProviders {
+		if wanted[p.Name()] {
+			providers = append(providers, p)
+			delete(wanted, p.Name())
+		}
+	}
+	if len(wanted) > 0 {
+		unknown := make([]string, 0, len(wanted))
+		for name := range wanted {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, strings.Join(unknown, ", "))
+	}
+	return providers, nil
+}
+
+// detectProvider finds the provider for the informed request, returns nil when not supported.
+func (w *WebHook) detectProvider(r *http.Request) Provider {
+	for _, p := range w.providers {
+		if p.Detect(r) {
+			return p
+		}
+	}
+	return nil
+}
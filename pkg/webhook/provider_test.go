@@ -0,0 +1,59 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{{
+		name:  "all builtin providers",
+		names: ProviderNames(),
+		want:  []string{GiteaProvider, GitHubProvider, GitLabProvider, BitbucketProvider},
+	}, {
+		name:  "detection order is kept",
+		names: []string{GitHubProvider, " gitea", ""},
+		want:  []string{GiteaProvider, GitHubProvider},
+	}, {
+		name:  "no providers",
+		names: []string{},
+		want:  []string{},
+	}, {
+		name:    "unknown provider",
+		names:   []string{GitHubProvider, "azure-devops"},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers, err := NewProviders(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewProviders() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !errors.Is(err, ErrUnknownProvider) {
+					t.Errorf("NewProviders() error = %v, want %v", err, ErrUnknownProvider)
+				}
+				return
+			}
+			got := []string{}
+			for _, p := range providers {
+				got = append(got, p.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewProviders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
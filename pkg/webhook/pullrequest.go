@@ -0,0 +1,59 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cancelPullRequestBuildRuns cancels the in-flight BuildRuns issued for the event pull-request, for
+// each Build in the search results. When new commits supersede the BuildRuns, the ones building the
+// event head commit are kept, as replays of the event resolve to them. Returns the BuildRun names
+// cancelled.
+func (w *WebHook) cancelPullRequestBuildRuns(
+	ctx context.Context,
+	logger logr.Logger,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	keepHead := event.HeadSHA != "" && !event.IsPullRequestClosed() && !event.IsPullRequestCommand()
+	var cancelled []string
+	for _, result := range results {
+		matchingLabels := client.MatchingLabels(pullRequestLabels(event))
+		matchingLabels[filter.BuildName] = result.BuildName.Name
+
+		var brs buildapi.BuildRunList
+		err := w.List(ctx, &brs, client.InNamespace(result.BuildName.Namespace), matchingLabels)
+		if err != nil {
+			return cancelled, err
+		}
+
+		for i := range brs.Items {
+			br := &brs.Items[i]
+			if br.IsDone() || br.IsCanceled() {
+				continue
+			}
+			if keepHead && br.GetAnnotations()[filter.WebHookCommitSHA] == event.HeadSHA {
+				continue
+			}
+			logger.V(0).Info("Cancelling in-flight pull-request BuildRun", "buildrun", br.GetName())
+
+			originalBr := br.DeepCopy()
+			br.Spec.State = buildapi.BuildRunRequestedStatePtr(buildapi.BuildRunStateCancel)
+			if err = w.Patch(ctx, br, client.MergeFrom(originalBr)); err != nil {
+				return cancelled, err
+			}
+			cancelled = append(cancelled, br.GetName())
+		}
+	}
+	return cancelled, nil
+}
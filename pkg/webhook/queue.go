@@ -0,0 +1,211 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DefaultWorkers default amount of workers processing the queued events.
+	DefaultWorkers = 4
+	// DefaultQueueSize default maximum amount of events waiting on the queue.
+	DefaultQueueSize = 100
+)
+
+var (
+	// queueDepth amount of events waiting on the queue.
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shipwright_triggers_webhook_queue_depth",
+		Help: "Amount of webhook events waiting on the queue.",
+	})
+	// queueInFlight amount of events being processed by the workers.
+	queueInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shipwright_triggers_webhook_queue_in_flight",
+		Help: "Amount of webhook events being processed.",
+	})
+	// queueRejected amount of events rejected because the queue is full.
+	queueRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "shipwright_triggers_webhook_queue_rejected_total",
+		Help: "Amount of webhook events rejected because the queue is full.",
+	})
+	// queueProcessed amount of events processed, by response status code class.
+	queueProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shipwright_triggers_webhook_queue_processed_total",
+		Help: "Amount of webhook events processed, by outcome.",
+	}, []string{"outcome"})
+	// queueWait amount of time the events wait on the queue before processing.
+	queueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "shipwright_triggers_webhook_queue_wait_seconds",
+		Help:    "Amount of time webhook events wait on the queue.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+	// queueDuration amount of time processing the events.
+	queueDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "shipwright_triggers_webhook_queue_duration_seconds",
+		Help:    "Amount of time processing webhook events.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		queueDepth,
+		queueInFlight,
+		queueRejected,
+		queueProcessed,
+		queueWait,
+		queueDuration,
+	)
+}
+
+// outcome result of processing the event, informed as response when processed inline.
+type outcome struct {
+	code      int      // response status code
+	message   string   // response message
+	buildRuns []string // buildruns issued or cancelled
+}
+
+// processFn processes the event, issuing or cancelling BuildRuns.
+type processFn func(context.Context) *outcome
+
+// task event waiting on the queue.
+type task struct {
+	logger     logr.Logger // event logger
+	process    processFn   // event processing
+	enqueuedAt time.Time   // moment the event has been enqueued
+}
+
+// taskQueue bounded queue of events processed by a pool of workers.
+type taskQueue struct {
+	m       sync.RWMutex // protects closed, and sending tasks
+	closed  bool         // the queue does not accept tasks anymore
+	tasks   chan *task   // tasks waiting
+	workers int          // amount of workers
+	wg      sync.WaitGroup
+}
+
+// enqueue adds the task on the queue, returns false when the queue is full or closed.
+func (q *taskQueue) enqueue(t *task) bool {
+	q.m.RLock()
+	defer q.m.RUnlock()
+
+	if q.closed {
+		return false
+	}
+	select {
+	case q.tasks <- t:
+		queueDepth.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+// work processes the tasks until the queue is closed and drained.
+func (q *taskQueue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for t := range q.tasks {
+		queueDepth.Dec()
+		queueInFlight.Inc()
+		queueWait.Observe(time.Since(t.enqueuedAt).Seconds())
+
+		start := time.Now()
+		o := t.process(ctx)
+		queueDuration.Observe(time.Since(start).Seconds())
+		queueInFlight.Dec()
+
+		result := "success"
+		if o.code >= http.StatusInternalServerError {
+			result = "error"
+		}
+		queueProcessed.WithLabelValues(result).Inc()
+		t.logger.V(0).Info("Queued webhook event processed", "code", o.code, "message", o.message,
+			"buildruns", o.buildRuns)
+	}
+}
+
+// start starts the workers, the tasks are processed using the informed context.
+func (q *taskQueue) start(ctx context.Context) {
+	q.wg.Add(q.workers)
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// drain stops accepting tasks, and waits for the workers to process the tasks enqueued.
+func (q *taskQueue) drain() {
+	q.m.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.tasks)
+	}
+	q.m.Unlock()
+	q.wg.Wait()
+}
+
+// newTaskQueue instantiate the queue holding up to size tasks, processed by the informed amount of
+// workers.
+func newTaskQueue(workers, size int) *taskQueue {
+	return &taskQueue{
+		tasks:   make(chan *task, size),
+		workers: workers,
+	}
+}
+
+// dispatch processes the event inline, responding with the outcome, when the queue is not
+// enabled. Otherwise, the event is enqueued and the request is accepted right away, when the queue
+// is full the request is rejected with "503 Service Unavailable". Only authorized events are
+// dispatched, deliveries already handled or waiting on the queue are skipped. The delivery key is
+// recorded before processing, and forgotten when the processing fails or the queue is full, so
+// deliveries failing, inline or on the queue, can be delivered again.
+func (w *WebHook) dispatch(
+	rw http.ResponseWriter,
+	r *http.Request,
+	logger logr.Logger,
+	key string,
+	process processFn,
+) {
+	if key != "" {
+		if !w.deliveries.record(key) {
+			logger.V(0).Info("Skipping duplicated webhook delivery")
+			w.respond(rw, http.StatusOK, "duplicated delivery", nil)
+			return
+		}
+		inner := process
+		process = func(ctx context.Context) *outcome {
+			o := inner(ctx)
+			if o.code < 200 || o.code > 299 {
+				w.deliveries.forget(key)
+			}
+			return o
+		}
+	}
+
+	if w.queue == nil {
+		o := process(r.Context())
+		w.respond(rw, o.code, o.message, o.buildRuns)
+		return
+	}
+
+	if !w.queue.enqueue(&task{logger: logger, process: process, enqueuedAt: time.Now()}) {
+		if key != "" {
+			w.deliveries.forget(key)
+		}
+		queueRejected.Inc()
+		logger.V(0).Info("Webhook queue is full, rejecting event")
+		w.respond(rw, http.StatusServiceUnavailable, "webhook queue is full", nil)
+		return
+	}
+	logger.V(0).Info("Webhook event queued")
+	w.respond(rw, http.StatusAccepted, "event queued", nil)
+}
@@ -0,0 +1,127 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+)
+
+func TestTaskQueue(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	var processed atomic.Int64
+	newTask := func() *task {
+		return &task{process: func(context.Context) *outcome {
+			processed.Add(1)
+			return &outcome{code: http.StatusOK}
+		}}
+	}
+
+	q := newTaskQueue(2, 2)
+	g.Expect(q.enqueue(newTask())).To(gomega.BeTrue())
+	g.Expect(q.enqueue(newTask())).To(gomega.BeTrue())
+	// the queue is full, workers are not started yet
+	g.Expect(q.enqueue(newTask())).To(gomega.BeFalse())
+
+	// draining processes the tasks enqueued, and stops accepting new ones
+	q.start(context.TODO())
+	q.drain()
+	g.Expect(processed.Load()).To(gomega.Equal(int64(2)))
+	g.Expect(q.enqueue(newTask())).To(gomega.BeFalse())
+}
+
+func TestWebHook_ServeHTTPQueue(t *testing.T) {
+	buildWithPushTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-push",
+		stubs.TriggerWhenPushToMain,
+	)
+
+	g := gomega.NewWithT(t)
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithPushTrigger)
+	c := newFakeClient(t, buildWithPushTrigger)
+	w := NewWebHook(c, buildInventory, Options{
+		Providers: builtinProviders,
+		Workers:   1,
+		QueueSize: 1,
+	})
+
+	// serve sends the push event to the WebHook, returning the response
+	serve := func() (int, Response) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newGitHubRequest(t, "push", stubs.GitHubPushEvent()))
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	// the workers are not started, the event waits on the queue
+	code, response := serve()
+	g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+	g.Expect(response.Message).To(gomega.Equal("event queued"))
+	g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+	code, response = serve()
+	g.Expect(code).To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(response.Message).To(gomega.Equal("webhook queue is full"))
+
+	w.queue.start(context.TODO())
+	w.queue.drain()
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+}
+
+func TestWebHook_ServeHTTPQueueDuplicatedDelivery(t *testing.T) {
+	buildWithPushTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-push",
+		stubs.TriggerWhenPushToMain,
+	)
+
+	g := gomega.NewWithT(t)
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithPushTrigger)
+	c := newFakeClient(t, buildWithPushTrigger)
+	w := NewWebHook(c, buildInventory, Options{
+		Providers: builtinProviders,
+		Workers:   1,
+		QueueSize: 2,
+	})
+
+	// serve sends the push event to the WebHook reusing the same delivery ID
+	serve := func() (int, Response) {
+		r := newGitHubRequest(t, "push", stubs.GitHubPushEvent())
+		r.Header.Set("X-GitHub-Delivery", "delivery-queued")
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, r)
+		var response Response
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	// the workers are not started, the retry arrives while the first delivery is queued
+	code, response := serve()
+	g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+	g.Expect(response.Message).To(gomega.Equal("event queued"))
+
+	code, response = serve()
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(response.Message).To(gomega.Equal("duplicated delivery"))
+
+	w.queue.start(context.TODO())
+	w.queue.drain()
+	g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+}
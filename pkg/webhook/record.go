@@ -0,0 +1,275 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DeliveryConfigMapPrefix name prefix of the ConfigMaps recording the webhook deliveries, one
+	// instance per delivery and namespace.
+	DeliveryConfigMapPrefix = "shipwright-triggers-delivery-"
+	// DefaultDeliveryTTL default amount of time the delivery records are kept.
+	DefaultDeliveryTTL = 24 * time.Hour
+	// MaxDeliveryAttempts maximum amount of attempts issuing the BuildRuns for a delivery, manual
+	// replays are not limited.
+	MaxDeliveryAttempts = 5
+	// deliveryRecordKey ConfigMap key storing the delivery record as JSON.
+	deliveryRecordKey = "delivery.json"
+	// deliveryBackoff interval before the first retry, doubled on every attempt.
+	deliveryBackoff = 10 * time.Second
+	// maxDeliveryBackoff maximum interval between retries.
+	maxDeliveryBackoff = 10 * time.Minute
+)
+
+// ErrInvalidDeliveryRecord the ConfigMap does not carry a valid delivery record.
+var ErrInvalidDeliveryRecord = errors.New("invalid delivery record")
+
+// DeliveryOutcome outcome of the last attempt issuing the delivery BuildRuns.
+type DeliveryOutcome string
+
+const (
+	// DeliverySucceeded all BuildRuns have been issued.
+	DeliverySucceeded DeliveryOutcome = "Succeeded"
+	// DeliveryFailed one or more BuildRuns could not be issued, and are retried.
+	DeliveryFailed DeliveryOutcome = "Failed"
+	// DeliveryRejected one or more BuildRuns have been rejected by the Build concurrency queue
+	// being full, and are not retried.
+	DeliveryRejected DeliveryOutcome = "Rejected"
+)
+
+// DeliveryRecord webhook delivery accepted for the Builds of a namespace, stored as JSON on the
+// delivery ConfigMap with the normalized event and the outcome.
+type DeliveryRecord struct {
+	Provider      string            `json:"provider"`                // git provider name
+	DeliveryID    string            `json:"deliveryID,omitempty"`    // provider delivery id
+	Event         *Event            `json:"event"`                   // normalized event, trimmed
+	Builds        []string          `json:"builds"`                  // build names matched
+	BuildRuns     map[string]string `json:"buildRuns,omitempty"`     // buildruns issued per build
+	Rejected      []string          `json:"rejected,omitempty"`      // builds rejected, queue full
+	Errors        []string          `json:"errors,omitempty"`        // last attempt errors
+	Outcome       DeliveryOutcome   `json:"outcome"`                 // last attempt outcome
+	Attempts      int               `json:"attempts"`                // amount of attempts
+	CreatedAt     metav1.Time       `json:"createdAt"`               // delivery moment
+	LastAttemptAt metav1.Time       `json:"lastAttemptAt"`           // last attempt moment
+	NextAttemptAt *metav1.Time      `json:"nextAttemptAt,omitempty"` // next retry, when failed
+	ExpiresAt     metav1.Time       `json:"expiresAt"`               // moment the record is purged
+}
+
+// IsExpired asserts the record is expired at the informed moment.
+func (d *DeliveryRecord) IsExpired(now time.Time) bool {
+	return !now.Before(d.ExpiresAt.Time)
+}
+
+// IsRetryDue asserts the failed delivery must be retried at the informed moment.
+func (d *DeliveryRecord) IsRetryDue(now time.Time) bool {
+	return d.NextAttemptAt != nil && !now.Before(d.NextAttemptAt.Time)
+}
+
+// deliveryBackoffFor returns the interval before the next retry, after the informed amount of
+// attempts.
+func deliveryBackoffFor(attempts int) time.Duration {
+	backoff := deliveryBackoff
+	for i := 1; i < attempts && backoff < maxDeliveryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDeliveryBackoff {
+		return maxDeliveryBackoff
+	}
+	return backoff
+}
+
+// deliveryConfigMapName returns the delivery ConfigMap name, derived from the delivery ID, or from
+// the event when the provider does not inform it. Deliveries of the same ID share the record.
+func deliveryConfigMapName(provider, deliveryID string, event *Event) string {
+	parts := []string{provider, deliveryID}
+	if deliveryID == "" {
+		parts = append(parts, string(event.Name), event.Ref, event.HeadSHA)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return DeliveryConfigMapPrefix + hex.EncodeToString(sum[:])[:eventHashLength]
+}
+
+// DecodeDeliveryRecord decodes the delivery record stored on the ConfigMap.
+func DecodeDeliveryRecord(cm *corev1.ConfigMap) (*DeliveryRecord, error) {
+	value, ok := cm.Data[deliveryRecordKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q key not found", ErrInvalidDeliveryRecord, deliveryRecordKey)
+	}
+	var record DeliveryRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDeliveryRecord, err)
+	}
+	if record.Event == nil {
+		return nil, fmt.Errorf("%w: event is not informed", ErrInvalidDeliveryRecord)
+	}
+	return &record, nil
+}
+
+// EncodeDeliveryRecord stores the delivery record on the ConfigMap.
+func EncodeDeliveryRecord(cm *corev1.ConfigMap, record *DeliveryRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	cm.Data = map[string]string{deliveryRecordKey: string(value)}
+	return nil
+}
+
+// AttemptDelivery issues the BuildRuns for the recorded Builds still without BuildRun, in the
+// informed namespace. The outcome is recorded, and failed deliveries are scheduled for retry with
+// exponential backoff, until the maximum amount of attempts is reached. BuildRuns rejected by a
+// full concurrency queue are not retried, only replays attempt them again.
+func AttemptDelivery(
+	ctx context.Context,
+	c client.Client,
+	limiter *concurrency.Limiter,
+	namespace string,
+	record *DeliveryRecord,
+	now time.Time,
+) {
+	if record.BuildRuns == nil {
+		record.BuildRuns = map[string]string{}
+	}
+	record.Attempts++
+	record.LastAttemptAt = metav1.NewTime(now)
+	record.Rejected = nil
+	record.Errors = nil
+
+	for _, buildName := range record.Builds {
+		if _, ok := record.BuildRuns[buildName]; ok {
+			continue
+		}
+		var b buildapi.Build
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: buildName}, &b)
+		if err == nil {
+			br := generateBuildRun(record.Event, &b)
+			if err = createBuildRun(ctx, c, limiter, record.Event, &b, br); err == nil {
+				record.BuildRuns[buildName] = br.GetName()
+				continue
+			}
+		}
+		if concurrency.IsQueueFull(err) {
+			record.Rejected = append(record.Rejected, buildName)
+			continue
+		}
+		record.Errors = append(record.Errors, fmt.Sprintf("%s: %v", buildName, err))
+	}
+
+	record.NextAttemptAt = nil
+	if len(record.Errors) == 0 {
+		record.Outcome = DeliverySucceeded
+		if len(record.Rejected) > 0 {
+			record.Outcome = DeliveryRejected
+		}
+		return
+	}
+	record.Outcome = DeliveryFailed
+	if record.Attempts < MaxDeliveryAttempts {
+		next := metav1.NewTime(now.Add(deliveryBackoffFor(record.Attempts)))
+		record.NextAttemptAt = &next
+	}
+}
+
+// saveDeliveryRecord creates the namespace's delivery ConfigMap, or updates it when the same
+// delivery has been recorded before.
+func (w *WebHook) saveDeliveryRecord(
+	ctx context.Context,
+	namespace string,
+	name string,
+	record *DeliveryRecord,
+) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{filter.WebHookDelivery: "true"},
+		},
+	}
+	if err := EncodeDeliveryRecord(cm, record); err != nil {
+		return err
+	}
+	err := w.Create(ctx, cm)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing := &corev1.ConfigMap{}
+	if err = w.Get(ctx, client.ObjectKeyFromObject(cm), existing); err != nil {
+		return err
+	}
+	existing.Data = cm.Data
+	return w.Update(ctx, existing)
+}
+
+// deliver records the delivery for each namespace in the search results, and issues the BuildRuns
+// for the Builds. Returns the BuildRun names issued, and the overall outcome, failed deliveries
+// are retried by the delivery controller.
+func (w *WebHook) deliver(
+	ctx context.Context,
+	provider string,
+	deliveryID string,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, DeliveryOutcome, error) {
+	var namespaces []string
+	builds := map[string][]string{}
+	for _, result := range results {
+		ns := result.BuildName.Namespace
+		if _, ok := builds[ns]; !ok {
+			namespaces = append(namespaces, ns)
+		}
+		builds[ns] = append(builds[ns], result.BuildName.Name)
+	}
+
+	now := time.Now()
+	name := deliveryConfigMapName(provider, deliveryID, event)
+	var buildRuns []string
+	outcome := DeliverySucceeded
+	for _, ns := range namespaces {
+		record := &DeliveryRecord{
+			Provider:   provider,
+			DeliveryID: deliveryID,
+			Event:      event.trimmed(),
+			Builds:     builds[ns],
+			CreatedAt:  metav1.NewTime(now),
+			ExpiresAt:  metav1.NewTime(now.Add(w.deliveryTTL)),
+		}
+		AttemptDelivery(ctx, w.Client, w.limiter, ns, record, now)
+		for _, buildName := range record.Builds {
+			if br, ok := record.BuildRuns[buildName]; ok {
+				buildRuns = append(buildRuns, br)
+			}
+		}
+		// failures retried take precedence over rejections
+		if record.Outcome == DeliveryFailed ||
+			(record.Outcome == DeliveryRejected && outcome == DeliverySucceeded) {
+			outcome = record.Outcome
+		}
+		if err := w.saveDeliveryRecord(ctx, ns, name, record); err != nil {
+			return buildRuns, DeliveryFailed, err
+		}
+	}
+	return buildRuns, outcome, nil
+}
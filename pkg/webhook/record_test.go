@@ -0,0 +1,219 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// listDeliveryRecords lists the delivery records stored in the test namespace.
+func listDeliveryRecords(t *testing.T, c client.Client) []corev1.ConfigMap {
+	var cms corev1.ConfigMapList
+	err := c.List(context.TODO(), &cms, client.InNamespace(stubs.Namespace),
+		client.MatchingLabels{filter.WebHookDelivery: "true"})
+	if err != nil {
+		t.Fatalf("failed to list delivery ConfigMaps: %v", err)
+	}
+	return cms.Items
+}
+
+func TestDeliveryBackoffFor(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 4, want: 80 * time.Second},
+		{attempts: 10, want: maxDeliveryBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := deliveryBackoffFor(tt.attempts); got != tt.want {
+			t.Errorf("deliveryBackoffFor(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeDeliveryRecord(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	record := &DeliveryRecord{
+		Provider:   GitHubProvider,
+		DeliveryID: "delivery-id",
+		Event:      &Event{RepoURL: stubs.RepoURL, HeadSHA: stubs.HeadCommitID},
+		Builds:     []string{"build"},
+		Outcome:    DeliverySucceeded,
+	}
+	cm := &corev1.ConfigMap{}
+	g.Expect(EncodeDeliveryRecord(cm, record)).To(gomega.Succeed())
+
+	decoded, err := DecodeDeliveryRecord(cm)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(decoded.Event).To(gomega.Equal(record.Event))
+	g.Expect(decoded.Builds).To(gomega.Equal(record.Builds))
+
+	_, err = DecodeDeliveryRecord(&corev1.ConfigMap{})
+	g.Expect(err).To(gomega.MatchError(ErrInvalidDeliveryRecord))
+
+	_, err = DecodeDeliveryRecord(&corev1.ConfigMap{Data: map[string]string{deliveryRecordKey: "{}"}})
+	g.Expect(err).To(gomega.MatchError(ErrInvalidDeliveryRecord))
+}
+
+func TestWebHook_ServeHTTPDeliveryRecords(t *testing.T) {
+	buildWithPushTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-push",
+		stubs.TriggerWhenPushToMain,
+	)
+
+	// serve sends the push event to the WebHook, returning the response
+	serve := func(t *testing.T, w *WebHook) (int, Response) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newGitHubRequest(t, "push", stubs.GitHubPushEvent()))
+		var response Response
+		g := gomega.NewWithT(t)
+		g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+		return rec.Code, response
+	}
+
+	t.Run("successful delivery is recorded", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		buildInventory := inventory.NewInventory()
+		buildInventory.Add(buildWithPushTrigger)
+		c := newFakeClient(t, buildWithPushTrigger)
+		w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+		code, response := serve(t, w)
+		g.Expect(code).To(gomega.Equal(http.StatusOK))
+
+		cms := listDeliveryRecords(t, c)
+		g.Expect(cms).To(gomega.HaveLen(1))
+		record, err := DecodeDeliveryRecord(&cms[0])
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(record.Provider).To(gomega.Equal(GitHubProvider))
+		g.Expect(record.DeliveryID).ToNot(gomega.BeEmpty())
+		g.Expect(record.Event.HeadSHA).To(gomega.Equal(stubs.HeadCommitID))
+		// only the event attributes needed to issue the BuildRuns are stored
+		g.Expect(record.Event.HeadMessage).To(gomega.BeEmpty())
+		g.Expect(record.Event.ChangedFiles).To(gomega.BeEmpty())
+		g.Expect(record.Builds).To(gomega.Equal([]string{buildWithPushTrigger.GetName()}))
+		g.Expect(record.BuildRuns).To(gomega.HaveKeyWithValue(
+			buildWithPushTrigger.GetName(), response.BuildRuns[0]))
+		g.Expect(record.Outcome).To(gomega.Equal(DeliverySucceeded))
+		g.Expect(record.NextAttemptAt).To(gomega.BeNil())
+		g.Expect(record.ExpiresAt.Sub(record.CreatedAt.Time)).To(gomega.Equal(DefaultDeliveryTTL))
+	})
+
+	t.Run("failed delivery is recorded for retry", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		// the Build is only present on the Inventory, thus the BuildRun can't be issued
+		buildInventory := inventory.NewInventory()
+		buildInventory.Add(buildWithPushTrigger)
+		c := newFakeClient(t)
+		limiter := concurrency.NewLimiter(c, c)
+		w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+		code, response := serve(t, w)
+		g.Expect(code).To(gomega.Equal(http.StatusAccepted))
+		g.Expect(response.Message).To(gomega.Equal("delivery recorded for retry"))
+		g.Expect(listBuildRuns(t, c)).To(gomega.BeEmpty())
+
+		cms := listDeliveryRecords(t, c)
+		g.Expect(cms).To(gomega.HaveLen(1))
+		record, err := DecodeDeliveryRecord(&cms[0])
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(record.Outcome).To(gomega.Equal(DeliveryFailed))
+		g.Expect(record.Errors).To(gomega.HaveLen(1))
+		g.Expect(record.Attempts).To(gomega.Equal(1))
+		g.Expect(record.NextAttemptAt.Sub(record.LastAttemptAt.Time)).To(gomega.Equal(deliveryBackoff))
+
+		// once the Build is available the retry issues the BuildRun
+		b := buildWithPushTrigger.DeepCopy()
+		b.SetResourceVersion("")
+		g.Expect(c.Create(context.TODO(), b)).To(gomega.Succeed())
+		AttemptDelivery(context.TODO(), c, limiter, stubs.Namespace, record, record.NextAttemptAt.Time)
+		g.Expect(record.Outcome).To(gomega.Equal(DeliverySucceeded))
+		g.Expect(record.Errors).To(gomega.BeEmpty())
+		g.Expect(record.Attempts).To(gomega.Equal(2))
+		g.Expect(record.NextAttemptAt).To(gomega.BeNil())
+		g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+
+		// attempting the delivery again resolves to the same BuildRun
+		record.BuildRuns = nil
+		AttemptDelivery(context.TODO(), c, limiter, stubs.Namespace, record, time.Now())
+		g.Expect(record.Outcome).To(gomega.Equal(DeliverySucceeded))
+		g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+	})
+
+	t.Run("delivery rejected by the concurrency queue is not retried", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		b := buildWithPushTrigger.DeepCopy()
+		b.SetAnnotations(map[string]string{
+			filter.BuildConcurrency:         string(concurrency.PolicyQueue),
+			filter.BuildConcurrencyMaxQueue: "1",
+		})
+		buildInventory := inventory.NewInventory()
+		buildInventory.Add(b)
+		c := newFakeClient(t, b)
+		w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+		// a BuildRun is running for the branch, and another one is waiting on the queue
+		limiter := concurrency.NewLimiter(c, c)
+		for _, name := range []string{"running", "waiting"} {
+			buildName := b.GetName()
+			br := stubs.ShipwrightBuildRun(name)
+			br.Spec.Build.Name = &buildName
+			_, err := limiter.Create(context.TODO(), b, br, stubs.GitRef)
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+		}
+
+		code, response := serve(t, w)
+		g.Expect(code).To(gomega.Equal(http.StatusTooManyRequests))
+		g.Expect(response.Message).To(gomega.Equal("concurrency queue is full"))
+		g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(1))
+
+		cms := listDeliveryRecords(t, c)
+		g.Expect(cms).To(gomega.HaveLen(1))
+		record, err := DecodeDeliveryRecord(&cms[0])
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(record.Outcome).To(gomega.Equal(DeliveryRejected))
+		g.Expect(record.Rejected).To(gomega.Equal([]string{b.GetName()}))
+		g.Expect(record.Errors).To(gomega.BeEmpty())
+		g.Expect(record.NextAttemptAt).To(gomega.BeNil())
+	})
+
+	t.Run("failed delivery is not retried after the maximum attempts", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		c := newFakeClient(t)
+		limiter := concurrency.NewLimiter(c, c)
+		record := &DeliveryRecord{
+			Event:    &Event{RepoURL: stubs.RepoURL, HeadSHA: stubs.HeadCommitID},
+			Builds:   []string{buildWithPushTrigger.GetName()},
+			Attempts: MaxDeliveryAttempts - 1,
+		}
+		AttemptDelivery(context.TODO(), c, limiter, stubs.Namespace, record, time.Now())
+		g.Expect(record.Outcome).To(gomega.Equal(DeliveryFailed))
+		g.Expect(record.NextAttemptAt).To(gomega.BeNil())
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/shipwright-io/triggers/pkg/inventory"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v53/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// SecretTokenKey key on the Build's TriggerSecret carrying the webhook secret token.
+	SecretTokenKey = "token"
+	// gitHubSignatureHeader header carrying the GitHub payload HMAC-SHA256 signature.
+	gitHubSignatureHeader = "X-Hub-Signature-256"
+)
+
+var (
+	// ErrSecretTokenNotFound the TriggerSecret does not contain the expected token key.
+	ErrSecretTokenNotFound = errors.New("secret token not found")
+	// ErrSignatureMissing the request does not carry the payload signature header.
+	ErrSignatureMissing = errors.New("payload signature is missing")
+)
+
+// validateFn validates the current request using the informed secret token.
+type validateFn func(token []byte) error
+
+// secretTokenCache keeps the tokens already retrieved during a single request, Builds often share
+// the same TriggerSecret.
+type secretTokenCache map[types.NamespacedName][]byte
+
+// getSecretToken retrieves the TriggerSecret and extracts the token, the cache is employed to
+// avoid repeating the same API calls.
+func (w *WebHook) getSecretToken(
+	ctx context.Context,
+	cache secretTokenCache,
+	secretName types.NamespacedName,
+) ([]byte, error) {
+	if token, ok := cache[secretName]; ok {
+		return token, nil
+	}
+
+	var secret corev1.Secret
+	if err := w.Get(ctx, secretName, &secret); err != nil {
+		return nil, err
+	}
+	token, ok := secret.Data[SecretTokenKey]
+	if !ok || len(token) == 0 {
+		return nil, fmt.Errorf("%w: key %q on secret %q", ErrSecretTokenNotFound,
+			SecretTokenKey, secretName)
+	}
+	cache[secretName] = token
+	return token, nil
+}
+
+// validateGitHubSignature validates the request payload signature against the token.
+func validateGitHubSignature(r *http.Request, payload, token []byte) error {
+	signature := r.Header.Get(gitHubSignatureHeader)
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+	return github.ValidateSignature(signature, payload, token)
+}
+
+// authorizeResults validates the request against each Build's TriggerSecret, using the provider
+// validation function, returning only the search results which are authorized to be triggered by
+// the request. Builds without a TriggerSecret are not verified.
+func (w *WebHook) authorizeResults(
+	ctx context.Context,
+	logger logr.Logger,
+	validateFn validateFn,
+	results []inventory.SearchResult,
+) []inventory.SearchResult {
+	cache := secretTokenCache{}
+	authorized := []inventory.SearchResult{}
+	for _, result := range results {
+		if !result.HasSecret() {
+			logger.V(0).Info("Build does not have a TriggerSecret, skipping request validation",
+				"build-name", result.BuildName)
+			authorized = append(authorized, result)
+			continue
+		}
+
+		token, err := w.getSecretToken(ctx, cache, result.SecretName)
+		if err != nil {
+			logger.V(0).Error(err, "Unable to retrieve the TriggerSecret token, rejecting event",
+				"build-name", result.BuildName, "secret-name", result.SecretName)
+			continue
+		}
+		if err = validateFn(token); err != nil {
+			logger.V(0).Error(err, "Request validation failed, rejecting event",
+				"build-name", result.BuildName, "secret-name", result.SecretName)
+			continue
+		}
+		authorized = append(authorized, result)
+	}
+	return authorized
+}
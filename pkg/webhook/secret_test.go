@@ -0,0 +1,147 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// signPayload returns the HMAC-SHA256 signature of the payload, as GitHub would.
+func signPayload(payload, token []byte) string {
+	mac := hmac.New(sha256.New, token)
+	mac.Write(payload)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// triggerSecret returns a Secret carrying the informed token on the expected key.
+func triggerSecret(name, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: stubs.Namespace, Name: name},
+		Data:       map[string][]byte{SecretTokenKey: []byte(token)},
+	}
+}
+
+func TestValidateGitHubSignature(t *testing.T) {
+	payload := []byte("payload")
+	token := []byte("token")
+
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   bool
+	}{{
+		name:      "valid signature",
+		signature: signPayload(payload, token),
+		wantErr:   false,
+	}, {
+		name:      "signature using another token",
+		signature: signPayload(payload, []byte("another-token")),
+		wantErr:   true,
+	}, {
+		name:      "missing signature",
+		signature: "",
+		wantErr:   true,
+	}, {
+		name:      "bogus signature",
+		signature: "bogus",
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(gitHubSignatureHeader, tt.signature)
+			err := validateGitHubSignature(r, payload, token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGitHubSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebHook_authorizeResults(t *testing.T) {
+	payload := []byte("payload")
+
+	resultFor := func(buildName, secretName string) inventory.SearchResult {
+		result := inventory.SearchResult{
+			BuildName: types.NamespacedName{Namespace: stubs.Namespace, Name: buildName},
+		}
+		if secretName != "" {
+			result.SecretName = types.NamespacedName{Namespace: stubs.Namespace, Name: secretName}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		objs      []client.Object
+		signature string
+		results   []inventory.SearchResult
+		want      []string
+	}{{
+		name:      "build without secret is authorized",
+		signature: "",
+		results:   []inventory.SearchResult{resultFor("build", "")},
+		want:      []string{"build"},
+	}, {
+		name:      "build with secret and valid signature is authorized",
+		objs:      []client.Object{triggerSecret("secret", "token")},
+		signature: signPayload(payload, []byte("token")),
+		results:   []inventory.SearchResult{resultFor("build", "secret")},
+		want:      []string{"build"},
+	}, {
+		name:      "build with secret and missing signature is rejected",
+		objs:      []client.Object{triggerSecret("secret", "token")},
+		signature: "",
+		results:   []inventory.SearchResult{resultFor("build", "secret")},
+		want:      nil,
+	}, {
+		name:      "build with a missing secret is rejected",
+		signature: signPayload(payload, []byte("token")),
+		results:   []inventory.SearchResult{resultFor("build", "secret")},
+		want:      nil,
+	}, {
+		name: "builds with different secrets are validated individually",
+		objs: []client.Object{
+			triggerSecret("secret-a", "token-a"),
+			triggerSecret("secret-b", "token-b"),
+		},
+		signature: signPayload(payload, []byte("token-b")),
+		results: []inventory.SearchResult{
+			resultFor("build-a", "secret-a"),
+			resultFor("build-b", "secret-b"),
+		},
+		want: []string{"build-b"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			w := NewWebHook(newFakeClient(t, tt.objs...), inventory.NewInventory(), Options{})
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(gitHubSignatureHeader, tt.signature)
+			got := w.authorizeResults(r.Context(), w.logger, func(token []byte) error {
+				return validateGitHubSignature(r, payload, token)
+			}, tt.results)
+			g.Expect(inventory.ExtractBuildNames(got...)).To(gomega.Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,513 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/template"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// maxPayloadBytes maximum webhook request body size, same limit GitHub applies on deliveries.
+	maxPayloadBytes = 25 * 1024 * 1024
+	// readHeaderTimeout amount of time allowed to read the request headers.
+	readHeaderTimeout = 10 * time.Second
+	// shutdownTimeout amount of time waiting for in-flight requests during shutdown.
+	shutdownTimeout = 30 * time.Second
+)
+
+// WebHook HTTP server receiving Git provider events, it searches the Inventory for Builds matching
+// the event repository and branch, and issues a BuildRun for each of them.
+type WebHook struct {
+	client.Client // kubernetes client
+
+	logger         logr.Logger          // component logger
+	addr           string               // server bind address
+	buildInventory inventory.Interface  // local build triggers database
+	providers      []Provider           // git providers, in detection order
+	pendingTTL     time.Duration        // amount of time triggers wait for approval
+	deliveries     *deliveryCache       // delivery IDs handled, detects duplicated deliveries
+	deliveryTTL    time.Duration        // amount of time delivery records are kept
+	queue          *taskQueue           // events processed asynchronously, nil when inline
+	debouncer      *debouncer           // triggers of debounced Builds waiting for the window
+	limiter        *concurrency.Limiter // creates BuildRuns honoring the Build concurrency policy
+}
+
+// Options WebHook server configuration.
+type Options struct {
+	Addr       string        // server bind address
+	Providers  []Provider    // git providers, in detection order
+	PendingTTL time.Duration // amount of time pull-request triggers wait for approval
+	// DeliveryCacheSize amount of delivery IDs remembered to detect duplicated deliveries.
+	DeliveryCacheSize int
+	// DeliveryTTL amount of time delivery records are kept.
+	DeliveryTTL time.Duration
+	// Workers amount of workers processing the events asynchronously, the events are processed
+	// inline, before responding, when zero.
+	Workers int
+	// QueueSize maximum amount of events waiting on the queue.
+	QueueSize int
+	// APIReader uncached reader confirming no BuildRuns are running before skipping the
+	// concurrency queue, the client is employed when not informed.
+	APIReader client.Reader
+}
+
+//+kubebuilder:rbac:groups=shipwright.io,resources=builds,verbs=get;list;watch
+//+kubebuilder:rbac:groups=shipwright.io,resources=buildruns,verbs=create;get;list;patch;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;get;list;update
+
+var (
+	_ http.Handler                   = &WebHook{}
+	_ manager.Runnable               = &WebHook{}
+	_ manager.LeaderElectionRunnable = &WebHook{}
+)
+
+// Response webhook response body, documents the BuildRuns issued for the event.
+type Response struct {
+	Message   string   `json:"message"`
+	BuildRuns []string `json:"buildRuns,omitempty"`
+}
+
+// respond writes the informed status code and message as the JSON response body.
+func (w *WebHook) respond(rw http.ResponseWriter, code int, message string, buildRuns []string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(Response{Message: message, BuildRuns: buildRuns}); err != nil {
+		w.logger.V(0).Error(err, "Writing webhook response body")
+	}
+}
+
+// createBuildRun renders the Build template with the event on the BuildRun, and creates it honoring
+// the Build concurrency policy, the BuildRun may wait on the queue until the running ones finish.
+// When the BuildRun name is derived from the event and it already exists the event is a replay, and
+// the existing BuildRun is taken as issued. Unless a pull-request is reopened and the existing
+// BuildRun is finished, or cancelled when the pull-request was closed, then a new BuildRun is
+// issued using the event name as base.
+func createBuildRun(
+	ctx context.Context,
+	c client.Client,
+	limiter *concurrency.Limiter,
+	event *Event,
+	b *buildapi.Build,
+	br *buildapi.BuildRun,
+) error {
+	if err := template.Render(b, br, templateData(event)); err != nil {
+		return err
+	}
+	_, err := limiter.Create(ctx, b, br, event.Ref)
+	if !apierrors.IsAlreadyExists(err) || br.GetGenerateName() != "" {
+		return err
+	}
+	if !event.IsPullRequest() || event.Action != PullRequestOpened {
+		return nil
+	}
+
+	var existing buildapi.BuildRun
+	if err = c.Get(ctx, client.ObjectKeyFromObject(br), &existing); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !existing.IsDone() && !existing.IsCanceled() {
+		return nil
+	}
+	br.SetGenerateName(fmt.Sprintf("%s-", br.GetName()))
+	br.SetName("")
+	br.SetResourceVersion("")
+	_, err = limiter.Create(ctx, b, br, event.Ref)
+	return err
+}
+
+// issueBuildRuns creates a BuildRun for each search result, returns the BuildRun names issued.
+func (w *WebHook) issueBuildRuns(
+	ctx context.Context,
+	event *Event,
+	results []inventory.SearchResult,
+) ([]string, error) {
+	var created []string
+	for _, result := range results {
+		var b buildapi.Build
+		if err := w.Get(ctx, result.BuildName, &b); err != nil {
+			return created, err
+		}
+		br := generateBuildRun(event, &b)
+		if err := createBuildRun(ctx, w.Client, w.limiter, event, &b, br); err != nil {
+			return created, err
+		}
+		created = append(created, br.GetName())
+	}
+	return created, nil
+}
+
+// search searches the Inventory for Builds matching the event, tags and branches are matched
+// using different criteria.
+func (w *WebHook) search(event *Event) []inventory.SearchResult {
+	if event.IsTag() {
+		return w.buildInventory.SearchForGitTag(
+			buildapi.GitHubWebHookTrigger,
+			event.RepoURL,
+			event.Tag,
+		)
+	}
+	return w.buildInventory.SearchForGit(
+		buildapi.GitHubWebHookTrigger,
+		event.Name,
+		event.RepoURL,
+		event.Branch,
+	)
+}
+
+// ServeHTTP handles the webhook requests, parses the event and searches the Inventory for Builds
+// configured for the event repository and branch, issuing BuildRuns for all of them.
+func (w *WebHook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.respond(rw, http.StatusMethodNotAllowed, "only POST is allowed", nil)
+		return
+	}
+
+	p := w.detectProvider(r)
+	if p == nil {
+		w.logger.V(0).Info("Unable to identify the webhook request provider")
+		w.respond(rw, http.StatusBadRequest, "unsupported webhook provider", nil)
+		return
+	}
+	deliveryID := p.DeliveryID(r)
+	logger := w.logger.WithValues(
+		"provider", p.Name(),
+		"delivery-id", deliveryID,
+	)
+	key := deliveryKey(p.Name(), deliveryID)
+
+	payload, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxPayloadBytes))
+	if err != nil {
+		logger.V(0).Error(err, "Reading webhook request body")
+		w.respond(rw, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	event, err := p.Parse(r, payload)
+	if err != nil {
+		if errors.Is(err, ErrEventIgnored) {
+			logger.V(0).Info("Skipping webhook event", "reason", err.Error())
+			w.respond(rw, http.StatusOK, err.Error(), nil)
+			return
+		}
+		logger.V(0).Error(err, "Parsing webhook event payload")
+		w.respond(rw, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	logger = logger.WithValues(
+		"event", event.Name,
+		"repo-url", event.RepoURL,
+		"branch", event.Branch,
+		"tag", event.Tag,
+		"head-sha", event.HeadSHA,
+		"author", event.Author,
+	)
+	// each Build may carry its own TriggerSecret, the request is validated per Build and only the
+	// authorized ones are triggered
+	validateFn := func(token []byte) error {
+		return p.Verify(r, payload, token)
+	}
+
+	if event.IsPullRequestCommand() {
+		w.serveCommands(rw, r, logger, key, validateFn, event)
+		return
+	}
+
+	// the pull-request head is recorded before directives and filters are applied, so commands
+	// commented on the pull-request act on its newest commit
+	if event.IsPullRequest() && !event.IsPullRequestApproved() {
+		if err = w.recordPullRequestHead(r.Context(), logger, validateFn, event); err != nil {
+			logger.V(0).Error(err, "Recording pull-request head")
+			w.respond(rw, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+	}
+
+	// directives on the head commit message are honored before searching the Inventory, updating
+	// a pull-request is never skipped, the BuildRuns superseded are cancelled regardless
+	directives := ParseDirectives(event.HeadMessage)
+	if directives.Skip && !event.IsPullRequestUpdated() {
+		logger.V(0).Info("Skipping webhook event, head commit message carries a skip directive",
+			"head-sha", event.HeadSHA)
+		w.respond(rw, http.StatusOK, "skipped by commit message directive", nil)
+		return
+	}
+
+	// approvals release the triggers held for the pull-request, instead of searching the Inventory
+	var results []inventory.SearchResult
+	var pending map[types.NamespacedName]*PendingTrigger
+	if event.IsPullRequestApproved() {
+		results, pending, err = w.searchPendingTriggers(r.Context(), logger, event)
+		if err != nil {
+			logger.V(0).Error(err, "Searching pending pull-request triggers")
+			w.respond(rw, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+	} else {
+		results = w.search(event)
+		// the BuildRuns of all Builds matching a pull-request update are cancelled, filters only
+		// apply to the Builds issuing new BuildRuns
+		if !event.IsPullRequestUpdated() {
+			results = w.filterResults(logger, event, directives, results)
+		}
+	}
+	if len(results) == 0 {
+		logger.V(0).Info("No Builds matching webhook event")
+		w.respond(rw, http.StatusOK, "no builds matching event", nil)
+		return
+	}
+	logger.V(0).Info("Build names in the Inventory matching criteria",
+		"build-names", inventory.ExtractBuildNames(results...))
+
+	authorized := w.authorizeResults(r.Context(), logger, validateFn, results)
+	if len(authorized) == 0 {
+		logger.V(0).Info("Webhook event is not authorized to trigger any Build")
+		w.respond(rw, http.StatusUnauthorized, "request validation failed", nil)
+		return
+	}
+
+	// the BuildRuns are issued, or cancelled, by processing the event either inline or on the queue
+	w.dispatch(rw, r, logger, key, func(ctx context.Context) *outcome {
+		return w.processEvent(ctx, logger, p.Name(), deliveryID, event, directives, pending,
+			authorized)
+	})
+}
+
+// filterResults narrows the search results to the Builds triggered by the event, honoring the
+// "only" directive, the changed files and the Builds filter expression.
+func (w *WebHook) filterResults(
+	logger logr.Logger,
+	event *Event,
+	directives Directives,
+	results []inventory.SearchResult,
+) []inventory.SearchResult {
+	results = filterByOnlyDirective(logger, event, directives, results)
+	// when the changed files are informed, only the Builds affected by them are triggered, events
+	// with incomplete lists fall back to trigger all Builds matching
+	if event.HasCompleteChangedFiles() {
+		results = w.buildInventory.FilterByChangedFiles(results, event.ChangedFiles)
+	}
+	// Builds annotated with a filter expression are only triggered when it matches the event
+	return w.buildInventory.FilterByExpression(results, event.Variables())
+}
+
+// processEvent issues, or cancels, the BuildRuns for the authorized search results, depending on
+// the event kind. Pull-request approvals release the pending triggers informed. Pull-request
+// updates cancel the BuildRuns of all results, the directives and filters are applied afterwards.
+func (w *WebHook) processEvent(
+	ctx context.Context,
+	logger logr.Logger,
+	provider string,
+	deliveryID string,
+	event *Event,
+	directives Directives,
+	pending map[types.NamespacedName]*PendingTrigger,
+	authorized []inventory.SearchResult,
+) *outcome {
+	if event.IsPullRequestApproved() {
+		buildRuns, err := w.releasePendingTriggers(ctx, pending, authorized)
+		if err != nil {
+			logger.V(0).Error(err, "trying to release pending triggers", "buildruns", buildRuns)
+			return &outcome{http.StatusInternalServerError, err.Error(), buildRuns}
+		}
+		logger.V(0).Info("Pending triggers released", "buildruns", buildRuns)
+		return &outcome{http.StatusOK, "pending triggers released", buildRuns}
+	}
+
+	// new commits on a pull-request supersede the BuildRuns still running, and closing it cancels
+	// all of them
+	if event.IsPullRequestUpdated() {
+		cancelled, err := w.cancelPullRequestBuildRuns(ctx, logger, event, authorized)
+		if err != nil {
+			logger.V(0).Error(err, "trying to cancel pull-request BuildRuns", "buildruns", cancelled)
+			return &outcome{http.StatusInternalServerError, err.Error(), cancelled}
+		}
+		if event.IsPullRequestClosed() {
+			if err = w.dropPullRequestTriggers(ctx, event, authorized); err != nil {
+				logger.V(0).Error(err, "trying to drop pending pull-request triggers")
+				return &outcome{http.StatusInternalServerError, err.Error(), cancelled}
+			}
+			if err = w.debouncer.drop(ctx, event, authorized); err != nil {
+				logger.V(0).Error(err, "trying to drop debounced pull-request triggers")
+				return &outcome{http.StatusInternalServerError, err.Error(), cancelled}
+			}
+			logger.V(0).Info("Pull-request BuildRuns cancelled", "buildruns", cancelled)
+			return &outcome{http.StatusOK, "buildruns cancelled", cancelled}
+		}
+
+		if directives.Skip {
+			logger.V(0).Info("Skipping pull-request BuildRuns, head commit message carries a "+
+				"skip directive", "buildruns", cancelled)
+			return &outcome{http.StatusOK, "skipped by commit message directive", cancelled}
+		}
+		if authorized = w.filterResults(logger, event, directives, authorized); len(authorized) == 0 {
+			logger.V(0).Info("No Builds matching webhook event filters", "buildruns", cancelled)
+			return &outcome{http.StatusOK, "no builds matching event", cancelled}
+		}
+	}
+
+	// pull-requests from forks, or from authors who are not collaborators, wait for a maintainer
+	// approval before running the Builds
+	if event.RequiresApproval() {
+		held, err := w.holdPullRequestTriggers(ctx, event, authorized)
+		if err != nil {
+			logger.V(0).Error(err, "trying to hold pull-request triggers", "builds", held)
+			return &outcome{http.StatusInternalServerError, err.Error(), nil}
+		}
+		logger.V(0).Info("Pull-request triggers held for approval",
+			"builds", held, "fork", event.Fork, "author-association", event.AuthorAssociation)
+		return &outcome{http.StatusAccepted, "pending approval", nil}
+	}
+	// providers without means to approve pull-requests never issue BuildRuns for untrusted ones,
+	// the Builds would run with the secrets available to them
+	if event.IsUnapprovable() {
+		logger.V(0).Info("Untrusted pull-request ignored, the provider does not support approvals",
+			"provider", event.Provider, "fork", event.Fork)
+		return &outcome{http.StatusOK, "untrusted pull-request ignored", nil}
+	}
+
+	// debounced Builds wait for the window to close, the events for the same Git reference are
+	// coalesced into a single BuildRun for the newest commit
+	authorized, debounced, err := w.debouncer.debounce(ctx, event, authorized)
+	if err != nil {
+		logger.V(0).Error(err, "trying to debounce triggers", "builds", debounced)
+		return &outcome{http.StatusInternalServerError, err.Error(), nil}
+	}
+	if len(debounced) > 0 {
+		logger.V(0).Info("Triggers debounced", "builds", debounced)
+		if len(authorized) == 0 {
+			return &outcome{http.StatusAccepted, "triggers debounced", nil}
+		}
+	}
+
+	// the delivery is recorded, so BuildRuns failing to be issued are retried later on
+	buildRuns, delivered, err := w.deliver(ctx, provider, deliveryID, event, authorized)
+	if err != nil {
+		logger.V(0).Error(err, "trying to record webhook delivery", "buildruns", buildRuns)
+		return &outcome{http.StatusInternalServerError, err.Error(), buildRuns}
+	}
+	switch delivered {
+	case DeliveryFailed:
+		logger.V(0).Info("Unable to issue all BuildRuns, delivery recorded for retry",
+			"buildruns", buildRuns)
+		return &outcome{http.StatusAccepted, "delivery recorded for retry", buildRuns}
+	case DeliveryRejected:
+		logger.V(0).Info("BuildRuns rejected by the concurrency queue", "buildruns", buildRuns)
+		return &outcome{http.StatusTooManyRequests, "concurrency queue is full", buildRuns}
+	}
+	logger.V(0).Info("BuildRuns issued", "buildruns", buildRuns)
+	return &outcome{http.StatusOK, "buildruns issued", buildRuns}
+}
+
+// Start starts the HTTP server, and the queue workers when enabled, and blocks until the context is
+// done, then gracefully shuts down the server waiting for in-flight requests, and drains the queue.
+func (w *WebHook) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/", w)
+
+	server := &http.Server{
+		Addr:              w.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	// queued events are processed until the queue is drained, even after the context is done
+	if w.queue != nil {
+		w.queue.start(context.WithoutCancel(ctx))
+	}
+	// debounced triggers are persisted, the ones waiting on shutdown are flushed on the next start
+	go w.debouncer.run(ctx)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		w.logger.V(0).Info("Shutting down webhook server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			w.logger.V(0).Error(err, "Shutting down webhook server")
+		}
+		if w.queue != nil {
+			w.logger.V(0).Info("Draining webhook queue")
+			w.queue.drain()
+		}
+	}()
+
+	w.logger.V(0).Info("Starting webhook server", "addr", w.addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-shutdownDone
+	return nil
+}
+
+// NeedLeaderElection the WebHook serves requests on every replica, the state shared among them is
+// stored on ConfigMaps updated with conflict retries, and BuildRuns are named after the event.
+func (w *WebHook) NeedLeaderElection() bool {
+	return false
+}
+
+// NewWebHook instantiate the WebHook server, handling requests from the informed providers. The
+// default pending TTL, delivery cache size, delivery TTL and queue size are employed when not
+// informed. Events are processed asynchronously when workers are informed.
+func NewWebHook(
+	ctrlClient client.Client,
+	buildInventory inventory.Interface,
+	opts Options,
+) *WebHook {
+	if opts.PendingTTL <= 0 {
+		opts.PendingTTL = DefaultPendingTTL
+	}
+	if opts.DeliveryCacheSize <= 0 {
+		opts.DeliveryCacheSize = DefaultDeliveryCacheSize
+	}
+	if opts.DeliveryTTL <= 0 {
+		opts.DeliveryTTL = DefaultDeliveryTTL
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.APIReader == nil {
+		opts.APIReader = ctrlClient
+	}
+	limiter := concurrency.NewLimiter(ctrlClient, opts.APIReader)
+	logger := logr.New(log.Log.GetSink()).WithName("component.webhook")
+	var queue *taskQueue
+	if opts.Workers > 0 {
+		queue = newTaskQueue(opts.Workers, opts.QueueSize)
+	}
+	return &WebHook{
+		Client:         ctrlClient,
+		logger:         logger,
+		addr:           opts.Addr,
+		buildInventory: buildInventory,
+		providers:      opts.Providers,
+		pendingTTL:     opts.PendingTTL,
+		deliveries:     newDeliveryCache(opts.DeliveryCacheSize),
+		deliveryTTL:    opts.DeliveryTTL,
+		queue:          queue,
+		debouncer:      newDebouncer(ctrlClient, limiter, logger.WithName("debounce")),
+		limiter:        limiter,
+	}
+}
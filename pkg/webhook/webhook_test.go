@@ -0,0 +1,694 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onsi/gomega"
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newFakeClient instantiate a fake kubernetes client aware of Shipwright resources.
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register Kubernetes scheme: %v", err)
+	}
+	if err := buildapi.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register Shipwright scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+// deliveryCount sequence of the delivery IDs informed on the test requests.
+var deliveryCount atomic.Int64
+
+// newGitHubRequest creates a webhook request carrying the informed GitHub event.
+func newGitHubRequest(t *testing.T, eventType string, event interface{}) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(marshalOrFail(t, event)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", eventType)
+	r.Header.Set("X-GitHub-Delivery", fmt.Sprintf("delivery-%d", deliveryCount.Add(1)))
+	return r
+}
+
+// listBuildRuns lists all BuildRuns in the test namespace.
+func listBuildRuns(t *testing.T, c client.Client) []buildapi.BuildRun {
+	var brs buildapi.BuildRunList
+	if err := c.List(context.TODO(), &brs, client.InNamespace(stubs.Namespace)); err != nil {
+		t.Fatalf("failed to list BuildRuns: %v", err)
+	}
+	return brs.Items
+}
+
+func TestWebHook_ServeHTTP(t *testing.T) {
+	buildWithPushTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-push",
+		stubs.TriggerWhenPushToMain,
+	)
+
+	tests := []struct {
+		name          string
+		request       func(t *testing.T) *http.Request
+		wantCode      int
+		wantBuildRuns int
+	}{{
+		name: "method not allowed",
+		request: func(*testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/", nil)
+		},
+		wantCode: http.StatusMethodNotAllowed,
+	}, {
+		name: "unsupported provider",
+		request: func(*testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("{}")))
+		},
+		wantCode: http.StatusBadRequest,
+	}, {
+		name: "ping event is acknowledged",
+		request: func(t *testing.T) *http.Request {
+			return newGitHubRequest(t, "ping", stubs.GitHubPingEvent())
+		},
+		wantCode: http.StatusOK,
+	}, {
+		name: "invalid payload",
+		request: func(*testing.T) *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("bogus")))
+			r.Header.Set("X-GitHub-Event", "push")
+			return r
+		},
+		wantCode: http.StatusBadRequest,
+	}, {
+		name: "push event on the main branch issues a BuildRun",
+		request: func(t *testing.T) *http.Request {
+			return newGitHubRequest(t, "push", stubs.GitHubPushEvent())
+		},
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name: "push event with skip directive does not issue BuildRuns",
+		request: func(t *testing.T) *http.Request {
+			push := stubs.GitHubPushEvent()
+			message := "update docs\n\n[skip ci]"
+			push.HeadCommit.Message = &message
+			return newGitHubRequest(t, "push", push)
+		},
+		wantCode: http.StatusOK,
+	}, {
+		name: "push event with only directive listing the Build issues a BuildRun",
+		request: func(t *testing.T) *http.Request {
+			push := stubs.GitHubPushEvent()
+			message := "fix api [shp only: build-api, build-push]"
+			push.HeadCommit.Message = &message
+			return newGitHubRequest(t, "push", push)
+		},
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name: "push event with only directive listing other Builds does not issue BuildRuns",
+		request: func(t *testing.T) *http.Request {
+			push := stubs.GitHubPushEvent()
+			message := "fix api [shp only: build-api]"
+			push.HeadCommit.Message = &message
+			return newGitHubRequest(t, "push", push)
+		},
+		wantCode: http.StatusOK,
+	}, {
+		name: "push event on another branch does not issue BuildRuns",
+		request: func(t *testing.T) *http.Request {
+			push := stubs.GitHubPushEvent()
+			ref := "refs/heads/another"
+			push.Ref = &ref
+			return newGitHubRequest(t, "push", push)
+		},
+		wantCode: http.StatusOK,
+	}, {
+		name: "pull-request event does not issue BuildRuns for push triggers",
+		request: func(t *testing.T) *http.Request {
+			return newGitHubRequest(t, "pull_request", stubs.GitHubPullRequestEvent("opened"))
+		},
+		wantCode: http.StatusOK,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildWithPushTrigger)
+
+			c := newFakeClient(t, buildWithPushTrigger)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, tt.request(t))
+			g.Expect(rec.Code).To(gomega.Equal(tt.wantCode))
+
+			var response Response
+			g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+			g.Expect(response.BuildRuns).To(gomega.HaveLen(tt.wantBuildRuns))
+
+			brs := listBuildRuns(t, c)
+			g.Expect(brs).To(gomega.HaveLen(tt.wantBuildRuns))
+			for _, br := range brs {
+				g.Expect(br.Spec.Build.Name).To(gomega.BeNil())
+				g.Expect(br.Spec.Build.Spec).ToNot(gomega.BeNil())
+				g.Expect(*br.Spec.Build.Spec.Source.Git.Revision).To(gomega.Equal(stubs.HeadCommitID))
+				g.Expect(br.GetLabels()).To(gomega.HaveKeyWithValue(
+					filter.BuildName, buildWithPushTrigger.GetName()))
+				g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+					filter.WebHookCommitSHA, stubs.HeadCommitID))
+				g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+					filter.WebHookProvider, GitHubProvider))
+				g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+					filter.WebHookRepoURL, stubs.RepoURL))
+			}
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPTags(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithTags := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-tags",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildWithTags.SetAnnotations(map[string]string{filter.BuildTags: ">=1.0.0"})
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithTags)
+
+	c := newFakeClient(t, buildWithTags)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	push := stubs.GitHubPushEvent()
+	ref := "refs/tags/v1.0.0"
+	push.Ref = &ref
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, newGitHubRequest(t, "push", push))
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	g.Expect(brs[0].GetAnnotations()).To(gomega.HaveKeyWithValue(filter.WebHookGitTag, "v1.0.0"))
+}
+
+func TestWebHook_ServeHTTPSignature(t *testing.T) {
+	secretName := "trigger-secret"
+	buildWithSecret := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-with-secret",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildWithSecret.Spec.Trigger.TriggerSecret = &secretName
+
+	payload := marshalOrFail(t, stubs.GitHubPushEvent())
+
+	tests := []struct {
+		name          string
+		signature     string
+		wantCode      int
+		wantBuildRuns int
+	}{{
+		name:          "valid signature issues a BuildRun",
+		signature:     signPayload(payload, []byte("token")),
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name:          "invalid signature is rejected",
+		signature:     signPayload(payload, []byte("wrong-token")),
+		wantCode:      http.StatusUnauthorized,
+		wantBuildRuns: 0,
+	}, {
+		name:          "missing signature is rejected",
+		signature:     "",
+		wantCode:      http.StatusUnauthorized,
+		wantBuildRuns: 0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildWithSecret)
+
+			c := newFakeClient(t, buildWithSecret, triggerSecret(secretName, "token"))
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+			r.Header.Set("X-GitHub-Event", "push")
+			r.Header.Set(gitHubSignatureHeader, tt.signature)
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, r)
+			g.Expect(rec.Code).To(gomega.Equal(tt.wantCode))
+			g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(tt.wantBuildRuns))
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPGitLab(t *testing.T) {
+	secretName := "trigger-secret"
+	buildWithSecret := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-gitlab",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildWithSecret.Spec.Source.Git.URL = "git@gitlab.com:" + stubs.GitLabRepoPathWithNamespace + ".git"
+	buildWithSecret.Spec.Trigger.TriggerSecret = &secretName
+
+	tests := []struct {
+		name          string
+		token         string
+		wantCode      int
+		wantBuildRuns int
+	}{{
+		name:          "valid token issues a BuildRun",
+		token:         "token",
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name:          "invalid token is rejected",
+		token:         "wrong-token",
+		wantCode:      http.StatusUnauthorized,
+		wantBuildRuns: 0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildWithSecret)
+
+			c := newFakeClient(t, buildWithSecret, triggerSecret(secretName, "token"))
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			payload := marshalOrFail(t, stubs.GitLabPushEvent(stubs.GitRef))
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+			r.Header.Set(gitLabEventHeader, gitLabPushHook)
+			r.Header.Set(gitLabTokenHeader, tt.token)
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, r)
+			g.Expect(rec.Code).To(gomega.Equal(tt.wantCode))
+
+			brs := listBuildRuns(t, c)
+			g.Expect(brs).To(gomega.HaveLen(tt.wantBuildRuns))
+			for _, br := range brs {
+				g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+					filter.WebHookProvider, GitLabProvider))
+			}
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPBitbucketServer(t *testing.T) {
+	secretName := "trigger-secret"
+	buildWithSecret := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-bitbucket",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildWithSecret.Spec.Source.Git.URL = stubs.BitbucketServerCloneURL
+	buildWithSecret.Spec.Trigger.TriggerSecret = &secretName
+
+	payload := marshalOrFail(t, stubs.BitbucketServerRefsChangedEvent(stubs.GitRef, "UPDATE"))
+
+	tests := []struct {
+		name          string
+		signature     string
+		wantCode      int
+		wantBuildRuns int
+	}{{
+		name:          "valid signature issues a BuildRun",
+		signature:     signPayload(payload, []byte("token")),
+		wantCode:      http.StatusOK,
+		wantBuildRuns: 1,
+	}, {
+		name:          "missing signature is rejected",
+		signature:     "",
+		wantCode:      http.StatusUnauthorized,
+		wantBuildRuns: 0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildWithSecret)
+
+			c := newFakeClient(t, buildWithSecret, triggerSecret(secretName, "token"))
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+			r.Header.Set(bitbucketEventHeader, "repo:refs_changed")
+			r.Header.Set(bitbucketSignatureHeader, tt.signature)
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, r)
+			g.Expect(rec.Code).To(gomega.Equal(tt.wantCode))
+
+			brs := listBuildRuns(t, c)
+			g.Expect(brs).To(gomega.HaveLen(tt.wantBuildRuns))
+			for _, br := range brs {
+				g.Expect(br.GetAnnotations()).To(gomega.HaveKeyWithValue(
+					filter.WebHookProvider, BitbucketServerProvider))
+			}
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPGitea(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	secretName := "trigger-secret"
+	buildWithSecret := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-gitea",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildWithSecret.Spec.Source.Git.URL = stubs.GiteaRepoURL + ".git"
+	buildWithSecret.Spec.Trigger.TriggerSecret = &secretName
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithSecret)
+
+	c := newFakeClient(t, buildWithSecret, triggerSecret(secretName, "token"))
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// Gitea sends GitHub headers as well, the request must be handled by the Gitea provider
+	payload := stubs.GiteaPushEvent(stubs.GitRef)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	r.Header.Set(giteaEventHeader, "push")
+	r.Header.Set("X-GitHub-Event", "push")
+	r.Header.Set(giteaSignatureHeader, signGiteaPayload(payload, []byte("token")))
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, r)
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	g.Expect(brs[0].GetAnnotations()).To(gomega.HaveKeyWithValue(filter.WebHookProvider, GiteaProvider))
+}
+
+func TestWebHook_ServeHTTPChangedFiles(t *testing.T) {
+	// stub builds use "source-build" as context directory, matching one of the changed files
+	buildOnContextDir := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-context-dir",
+		stubs.TriggerWhenPushToMain,
+	)
+	anotherContextDir := "another-build"
+	buildOnAnotherContextDir := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-another-context-dir",
+		stubs.TriggerWhenPushToMain,
+	)
+	buildOnAnotherContextDir.Spec.Source.ContextDir = &anotherContextDir
+
+	tests := []struct {
+		name          string
+		totalCommits  int
+		wantBuildRuns int
+	}{{
+		name:          "only the build affected by the changed files is triggered",
+		totalCommits:  1,
+		wantBuildRuns: 1,
+	}, {
+		name:          "truncated changed files triggers all builds",
+		totalCommits:  30,
+		wantBuildRuns: 2,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(buildOnContextDir)
+			buildInventory.Add(buildOnAnotherContextDir)
+
+			c := newFakeClient(t, buildOnContextDir, buildOnAnotherContextDir)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			push := stubs.GitLabPushEvent(stubs.GitRef)
+			push["project"].(map[string]interface{})["web_url"] = stubs.RepoURL
+			push["total_commits_count"] = tt.totalCommits
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(marshalOrFail(t, push)))
+			r.Header.Set(gitLabEventHeader, gitLabPushHook)
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, r)
+			g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+			g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(tt.wantBuildRuns))
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPFilterExpression(t *testing.T) {
+	tests := []struct {
+		name          string
+		expr          string
+		wantBuildRuns int
+	}{{
+		name: "matching expression triggers the build",
+		expr: fmt.Sprintf("event.author == %q && event.branch == %q",
+			stubs.HeadCommitAuthorName, stubs.Branch),
+		wantBuildRuns: 1,
+	}, {
+		name:          "expression not matching skips the build",
+		expr:          fmt.Sprintf("event.author != %q", stubs.HeadCommitAuthorName),
+		wantBuildRuns: 0,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			b := stubs.ShipwrightBuildWithTriggers(
+				"ghcr.io/shipwright-io",
+				"build-filter",
+				stubs.TriggerWhenPushToMain,
+			)
+			b.SetAnnotations(map[string]string{filter.BuildFilter: tt.expr})
+
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(b)
+
+			c := newFakeClient(t, b)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, newGitHubRequest(t, "push", stubs.GitHubPushEvent()))
+			g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+			g.Expect(listBuildRuns(t, c)).To(gomega.HaveLen(tt.wantBuildRuns))
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPPullRequestCancellation(t *testing.T) {
+	buildWithPullRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-pull-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+
+	// pullRequestBuildRun returns a BuildRun previously issued for the stub pull-request
+	pullRequestBuildRun := func(name string, done bool) *buildapi.BuildRun {
+		br := stubs.ShipwrightBuildRun(name)
+		br.SetLabels(map[string]string{
+			filter.BuildName:          buildWithPullRequestTrigger.GetName(),
+			filter.WebHookRepo:        repoLabelValue(stubs.RepoURL),
+			filter.WebHookPullRequest: "1",
+		})
+		if done {
+			br.Status.Conditions = buildapi.Conditions{{
+				Type:   buildapi.Succeeded,
+				Status: corev1.ConditionTrue,
+			}}
+		}
+		return br
+	}
+
+	tests := []struct {
+		name          string
+		action        string
+		expr          string
+		wantCancelled []string
+		wantBuildRuns int
+	}{{
+		name:          "synchronize cancels in-flight BuildRuns and issues a new one",
+		action:        "synchronize",
+		wantCancelled: []string{"in-flight"},
+		wantBuildRuns: 3,
+	}, {
+		name:          "closed cancels in-flight BuildRuns",
+		action:        "closed",
+		wantCancelled: []string{"in-flight"},
+		wantBuildRuns: 2,
+	}, {
+		name:          "opened does not cancel BuildRuns",
+		action:        "opened",
+		wantCancelled: nil,
+		wantBuildRuns: 3,
+	}, {
+		name:          "synchronize cancels in-flight BuildRuns of the Build filtered out",
+		action:        "synchronize",
+		expr:          `event.action == "opened"`,
+		wantCancelled: []string{"in-flight"},
+		wantBuildRuns: 2,
+	}, {
+		name:          "closed cancels in-flight BuildRuns of the Build filtered out",
+		action:        "closed",
+		expr:          `event.action == "opened"`,
+		wantCancelled: []string{"in-flight"},
+		wantBuildRuns: 2,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			b := buildWithPullRequestTrigger.DeepCopy()
+			if tt.expr != "" {
+				b.SetAnnotations(map[string]string{filter.BuildFilter: tt.expr})
+			}
+			buildInventory := inventory.NewInventory()
+			buildInventory.Add(b)
+
+			c := newFakeClient(t,
+				b,
+				pullRequestBuildRun("in-flight", false),
+				pullRequestBuildRun("done", true),
+			)
+			w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, newGitHubRequest(t, "pull_request", stubs.GitHubPullRequestEvent(tt.action)))
+			g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+
+			brs := listBuildRuns(t, c)
+			g.Expect(brs).To(gomega.HaveLen(tt.wantBuildRuns))
+
+			var cancelled []string
+			for _, br := range brs {
+				if br.IsCanceled() {
+					cancelled = append(cancelled, br.GetName())
+				}
+			}
+			g.Expect(cancelled).To(gomega.Equal(tt.wantCancelled))
+		})
+	}
+}
+
+func TestWebHook_ServeHTTPPullRequestReopened(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithPullRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-pull-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithPullRequestTrigger)
+	c := newFakeClient(t, buildWithPullRequestTrigger)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	serve := func(action string) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newGitHubRequest(t, "pull_request", stubs.GitHubPullRequestEvent(action)))
+		g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	}
+
+	// delivering the event again resolves to the BuildRun still running
+	serve("opened")
+	serve("opened")
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	first := brs[0].GetName()
+
+	// reopening the pull-request with the same head issues a new BuildRun, the one cancelled when
+	// the pull-request was closed shares the event name
+	serve("closed")
+	serve("reopened")
+	brs = listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(2))
+	for _, br := range brs {
+		if br.GetName() == first {
+			g.Expect(br.IsCanceled()).To(gomega.BeTrue())
+			continue
+		}
+		g.Expect(br.GetName()).To(gomega.HavePrefix(first + "-"))
+		g.Expect(br.IsCanceled()).To(gomega.BeFalse())
+	}
+}
+
+func TestWebHook_ServeHTTPPullRequestSkipDirective(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	buildWithMergeRequestTrigger := stubs.ShipwrightBuildWithTriggers(
+		"ghcr.io/shipwright-io",
+		"build-merge-request",
+		stubs.TriggerWhenPullRequestToMain,
+	)
+	buildWithMergeRequestTrigger.Spec.Source.Git.URL = stubs.GitLabRepoURL
+
+	inFlight := stubs.ShipwrightBuildRun("in-flight")
+	inFlight.SetLabels(map[string]string{
+		filter.BuildName:          buildWithMergeRequestTrigger.GetName(),
+		filter.WebHookRepo:        repoLabelValue(stubs.GitLabRepoURL),
+		filter.WebHookPullRequest: "1",
+	})
+
+	buildInventory := inventory.NewInventory()
+	buildInventory.Add(buildWithMergeRequestTrigger)
+	c := newFakeClient(t, buildWithMergeRequestTrigger, inFlight)
+	w := NewWebHook(c, buildInventory, Options{Providers: builtinProviders})
+
+	// new commits skipping CI still supersede the BuildRuns running for the merge-request
+	mr := stubs.GitLabMergeRequestEvent("update")
+	mr["object_attributes"].(map[string]interface{})["last_commit"] = map[string]interface{}{
+		"id":      "another-commit-id",
+		"message": "docs: typo [skip ci]",
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(marshalOrFail(t, mr)))
+	r.Header.Set(gitLabEventHeader, gitLabMergeRequestHook)
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, r)
+	g.Expect(rec.Code).To(gomega.Equal(http.StatusOK))
+	var response Response
+	g.Expect(json.NewDecoder(rec.Body).Decode(&response)).To(gomega.Succeed())
+	g.Expect(response.Message).To(gomega.Equal("skipped by commit message directive"))
+
+	brs := listBuildRuns(t, c)
+	g.Expect(brs).To(gomega.HaveLen(1))
+	g.Expect(brs[0].IsCanceled()).To(gomega.BeTrue())
+}
@@ -0,0 +1,110 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package integration
+
+import (
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/concurrency"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Concurrency Controller", Ordered, func() {
+	// asserts the Concurrency controller releases the next BuildRun waiting on the queue when the
+	// running BuildRun finishes, and when it's deleted
+	Context("Queued BuildRuns are released", func() {
+		buildWithQueue := stubs.ShipwrightBuildWithTriggers(
+			"shipwright.io/triggers",
+			"build-with-queue",
+			stubs.TriggerWhenPushToMain,
+		)
+		buildWithQueue.SetAnnotations(map[string]string{
+			filter.BuildConcurrency: string(concurrency.PolicyQueue),
+		})
+
+		var limiter *concurrency.Limiter
+
+		// createBuildRun creates the named BuildRun through the limiter, returns whether it has
+		// been created instead of queued
+		createBuildRun := func(name string) bool {
+			buildName := buildWithQueue.GetName()
+			br := stubs.ShipwrightBuildRun(name)
+			br.Spec.Build.Name = &buildName
+			created, err := limiter.Create(ctx, buildWithQueue, br, stubs.GitRef)
+			Expect(err).ToNot(HaveOccurred())
+			return created
+		}
+
+		// buildRunExistsFn returns a function asserting the named BuildRun exists
+		buildRunExistsFn := func(name string) func() bool {
+			return func() bool {
+				var br buildapi.BuildRun
+				key := types.NamespacedName{Namespace: stubs.Namespace, Name: name}
+				return kubeClient.Get(ctx, key, &br) == nil
+			}
+		}
+
+		BeforeAll(func() {
+			limiter = concurrency.NewLimiter(kubeClient, kubeClient)
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			Expect(kubeClient.Create(ctx, buildWithQueue)).Should(Succeed())
+			time.Sleep(gracefulWait)
+		})
+
+		AfterAll(func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, buildWithQueue, deleteNowOpts)).Should(Succeed())
+			cm := &corev1.ConfigMap{}
+			cm.SetNamespace(stubs.Namespace)
+			cm.SetName(concurrency.QueueConfigMapName)
+			err := kubeClient.Delete(ctx, cm, deleteNowOpts)
+			Expect(client.IgnoreNotFound(err)).Should(Succeed())
+		})
+
+		It("BuildRun waits on the queue while another one is running", func() {
+			Expect(createBuildRun("br-queue-1")).To(BeTrue())
+			Expect(createBuildRun("br-queue-2")).To(BeFalse())
+
+			time.Sleep(gracefulWait)
+			Expect(buildRunExistsFn("br-queue-2")()).To(BeFalse())
+		})
+
+		It("Finishing the running BuildRun releases the next one", func() {
+			key := types.NamespacedName{Namespace: stubs.Namespace, Name: "br-queue-1"}
+			Expect(markBuildRunSucceeded(key)).Should(Succeed())
+
+			eventuallyWithTimeoutFn(buildRunExistsFn("br-queue-2")).Should(BeTrue())
+		})
+
+		It("Deleting the running BuildRun releases the next one", func() {
+			Expect(createBuildRun("br-queue-3")).To(BeFalse())
+
+			var br buildapi.BuildRun
+			key := types.NamespacedName{Namespace: stubs.Namespace, Name: "br-queue-2"}
+			Expect(kubeClient.Get(ctx, key, &br)).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, &br, deleteNowOpts)).Should(Succeed())
+
+			eventuallyWithTimeoutFn(buildRunExistsFn("br-queue-3")).Should(BeTrue())
+
+			var cm corev1.ConfigMap
+			cmKey := types.NamespacedName{Namespace: stubs.Namespace, Name: concurrency.QueueConfigMapName}
+			err := kubeClient.Get(ctx, cmKey, &cm)
+			if !apierrors.IsNotFound(err) {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cm.Data).To(BeEmpty())
+			}
+		})
+	})
+})
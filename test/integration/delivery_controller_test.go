@@ -0,0 +1,165 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package integration
+
+import (
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/webhook"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Delivery Controller", Ordered, func() {
+	// asserts the Delivery controller retries the failed webhook deliveries with backoff, replays
+	// the deliveries annotated, and purges the expired records
+	Context("Webhook delivery records are retried, replayed and purged", func() {
+		buildWithGitHubTrigger := stubs.ShipwrightBuildWithTriggers(
+			"shipwright.io/triggers",
+			"build-with-delivery",
+			stubs.TriggerWhenPushToMain,
+		)
+
+		deliveryName := types.NamespacedName{
+			Namespace: stubs.Namespace,
+			Name:      webhook.DeliveryConfigMapPrefix + "retried",
+		}
+		expiredName := types.NamespacedName{
+			Namespace: stubs.Namespace,
+			Name:      webhook.DeliveryConfigMapPrefix + "expired",
+		}
+
+		// createDeliveryRecord creates the delivery ConfigMap for the push event on the Build, the
+		// record is due for retry and expires at the informed moment
+		createDeliveryRecord := func(name types.NamespacedName, expiresAt time.Time) error {
+			now := time.Now()
+			nextAttemptAt := metav1.NewTime(now.Add(-time.Second))
+			record := &webhook.DeliveryRecord{
+				Provider:   webhook.GitHubProvider,
+				DeliveryID: name.Name,
+				Event: &webhook.Event{
+					Provider: webhook.GitHubProvider,
+					Name:     buildapi.GitHubPushEvent,
+					RepoURL:  stubs.RepoURL,
+					Ref:      stubs.GitRef,
+					Branch:   stubs.Branch,
+					HeadSHA:  stubs.HeadCommitID,
+				},
+				Builds:        []string{buildWithGitHubTrigger.GetName()},
+				Outcome:       webhook.DeliveryFailed,
+				CreatedAt:     metav1.NewTime(now),
+				LastAttemptAt: metav1.NewTime(now),
+				NextAttemptAt: &nextAttemptAt,
+				ExpiresAt:     metav1.NewTime(expiresAt),
+			}
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: name.Namespace,
+					Name:      name.Name,
+					Labels:    map[string]string{filter.WebHookDelivery: "true"},
+				},
+			}
+			if err := webhook.EncodeDeliveryRecord(cm, record); err != nil {
+				return err
+			}
+			return kubeClient.Create(ctx, cm)
+		}
+
+		// getDeliveryRecord retrieves and decodes the delivery record
+		getDeliveryRecord := func() (*corev1.ConfigMap, *webhook.DeliveryRecord, error) {
+			var cm corev1.ConfigMap
+			if err := kubeClient.Get(ctx, deliveryName, &cm); err != nil {
+				return nil, nil, err
+			}
+			record, err := webhook.DecodeDeliveryRecord(&cm)
+			return &cm, record, err
+		}
+
+		// deliveryRecordFn returns the delivery record attempts and outcome
+		deliveryRecordFn := func() []interface{} {
+			_, record, err := getDeliveryRecord()
+			if err != nil {
+				return nil
+			}
+			return []interface{}{record.Attempts, record.Outcome}
+		}
+
+		BeforeAll(func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+		})
+
+		AfterAll(func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, buildWithGitHubTrigger, deleteNowOpts)).Should(Succeed())
+			cm := &corev1.ConfigMap{}
+			cm.SetNamespace(deliveryName.Namespace)
+			cm.SetName(deliveryName.Name)
+			Expect(kubeClient.Delete(ctx, cm, deleteNowOpts)).Should(Succeed())
+		})
+
+		It("Failed delivery is retried with backoff", func() {
+			// the Build does not exist yet, thus the first attempt fails
+			Expect(createDeliveryRecord(deliveryName, time.Now().Add(time.Hour))).Should(Succeed())
+			eventuallyWithTimeoutFn(deliveryRecordFn).
+				Should(Equal([]interface{}{1, webhook.DeliveryFailed}))
+
+			_, record, err := getDeliveryRecord()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(record.Errors).To(HaveLen(1))
+			Expect(record.NextAttemptAt).ToNot(BeNil())
+			Expect(record.NextAttemptAt.Time).To(BeTemporally(">", record.LastAttemptAt.Time))
+			Expect(amountOfBuildRunsFn()).To(Equal(0))
+
+			// the retry is due after the backoff, issuing the BuildRun
+			Expect(kubeClient.Create(ctx, buildWithGitHubTrigger)).Should(Succeed())
+			Eventually(deliveryRecordFn).
+				WithPolling(time.Second).
+				WithTimeout(time.Minute).
+				Should(Equal([]interface{}{2, webhook.DeliverySucceeded}))
+			eventuallyWithTimeoutFn(amountOfBuildRunsFn).Should(Equal(1))
+
+			_, record, err = getDeliveryRecord()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(record.BuildRuns).To(HaveKey(buildWithGitHubTrigger.GetName()))
+			Expect(record.NextAttemptAt).To(BeNil())
+		})
+
+		It("Delivery annotated for replay issues the BuildRun again", func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			eventuallyWithTimeoutFn(amountOfBuildRunsFn).Should(Equal(0))
+
+			cm, _, err := getDeliveryRecord()
+			Expect(err).ToNot(HaveOccurred())
+			cm.SetAnnotations(map[string]string{filter.WebHookReplay: "true"})
+			Expect(kubeClient.Update(ctx, cm)).Should(Succeed())
+
+			eventuallyWithTimeoutFn(deliveryRecordFn).
+				Should(Equal([]interface{}{3, webhook.DeliverySucceeded}))
+			eventuallyWithTimeoutFn(amountOfBuildRunsFn).Should(Equal(1))
+
+			cm, _, err = getDeliveryRecord()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.GetAnnotations()).ToNot(HaveKey(filter.WebHookReplay))
+		})
+
+		It("Expired delivery record is deleted", func() {
+			Expect(createDeliveryRecord(expiredName, time.Now().Add(-time.Second))).Should(Succeed())
+
+			eventuallyWithTimeoutFn(func() bool {
+				var cm corev1.ConfigMap
+				return apierrors.IsNotFound(kubeClient.Get(ctx, expiredName, &cm))
+			}).Should(BeTrue())
+		})
+	})
+})
@@ -7,11 +7,15 @@ package integration
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
 	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/status"
 	"github.com/shipwright-io/triggers/test/stubs"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
@@ -54,6 +58,62 @@ func assertBuildRun(brNamespacedName types.NamespacedName, fn BuildRunAssertFn)
 	return fn(&br)
 }
 
+// markBuildRunSucceeded updates the BuildRun status with the succeeded condition.
+func markBuildRunSucceeded(brNamespacedName types.NamespacedName) error {
+	return assertBuildRun(brNamespacedName, func(br *buildapi.BuildRun) error {
+		br.Status = buildapi.BuildRunStatus{Conditions: []buildapi.Condition{{
+			Type:               buildapi.Succeeded,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "Succeeded",
+			Message:            "succeeded",
+		}}}
+		return kubeClient.Status().Update(ctx, br)
+	})
+}
+
+// fakeReporter status reporter recording the commit statuses reported, the informed amount of
+// failures is returned before recording.
+type fakeReporter struct {
+	mu       sync.Mutex
+	failures int
+	reported []status.Status
+}
+
+var _ status.Reporter = &fakeReporter{}
+
+// Report records the status, or fails while there are failures left.
+func (f *fakeReporter) Report(_ context.Context, _ []byte, s *status.Status) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return fmt.Errorf("failing to report status %q", s.State)
+	}
+	f.reported = append(f.reported, *s)
+	return nil
+}
+
+// failNext makes the informed amount of next reports fail.
+func (f *fakeReporter) failNext(failures int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = failures
+}
+
+// states returns the states reported for the commit SHA, in order.
+func (f *fakeReporter) states(sha string) []status.State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var states []status.State
+	for _, s := range f.reported {
+		if s.SHA == sha {
+			states = append(states, s.State)
+		}
+	}
+	return states
+}
+
 // eventuallyWithTimeoutFn wraps the informed function on Eventually() with default timeout.
 func eventuallyWithTimeoutFn(fn interface{}) gomegatypes.AsyncAssertion {
 	return Eventually(fn).
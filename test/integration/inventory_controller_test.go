@@ -32,6 +32,7 @@ var _ = Describe("Build Inventory Controller", Ordered, func() {
 		searchForBuildWithGitHubTriggerFn := func() int {
 			return len(buildInventory.SearchForGit(
 				buildapi.GitHubWebHookTrigger,
+				buildapi.GitHubPushEvent,
 				buildWithGitHubTrigger.Spec.Source.Git.URL,
 				stubs.Branch,
 			))
@@ -0,0 +1,132 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package integration
+
+import (
+	"fmt"
+	"time"
+
+	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
+	"github.com/shipwright-io/triggers/pkg/filter"
+	"github.com/shipwright-io/triggers/pkg/status"
+	"github.com/shipwright-io/triggers/pkg/webhook"
+	"github.com/shipwright-io/triggers/test/stubs"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Status Controller", Ordered, func() {
+	// asserts the Status controller reports the state of the BuildRuns issued by the webhook on the
+	// originating commit, only once per state, retrying when the Git provider fails, and skipping
+	// Builds without a status Secret
+	Context("BuildRuns issued by the webhook report their status", func() {
+		statusSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: stubs.Namespace, Name: "status-token"},
+			Data:       map[string][]byte{"token": []byte("token")},
+		}
+
+		buildWithStatus := stubs.ShipwrightBuildWithTriggers(
+			"shipwright.io/triggers",
+			"build-with-status",
+			stubs.TriggerWhenPushToMain,
+		)
+		buildWithStatus.SetAnnotations(map[string]string{
+			filter.BuildStatusSecret: statusSecret.GetName(),
+		})
+
+		buildWithoutStatus := stubs.ShipwrightBuildWithTriggers(
+			"shipwright.io/triggers",
+			"build-without-status",
+			stubs.TriggerWhenPushToMain,
+		)
+
+		// webhookBuildRun returns a BuildRun for the Build, annotated as issued by the webhook for
+		// the commit SHA
+		webhookBuildRun := func(b *buildapi.Build, sha string) *buildapi.BuildRun {
+			buildName := b.GetName()
+			br := stubs.ShipwrightBuildRun(fmt.Sprintf("%s-%s", buildName, sha))
+			br.SetLabels(map[string]string{filter.BuildName: buildName})
+			br.SetAnnotations(map[string]string{
+				filter.WebHookProvider:  webhook.GitHubProvider,
+				filter.WebHookEventName: string(buildapi.GitHubPushEvent),
+				filter.WebHookRepoURL:   stubs.RepoURL,
+				filter.WebHookGitRef:    stubs.GitRef,
+				filter.WebHookBranch:    stubs.Branch,
+				filter.WebHookCommitSHA: sha,
+			})
+			br.Spec.Build.Name = &buildName
+			return br
+		}
+
+		// reportedStatusFn returns the status recorded on the BuildRun annotations
+		reportedStatusFn := func(br *buildapi.BuildRun) func() string {
+			return func() string {
+				var current buildapi.BuildRun
+				if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(br), &current); err != nil {
+					return ""
+				}
+				return current.GetAnnotations()[filter.WebHookReportedStatus]
+			}
+		}
+
+		BeforeAll(func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			Expect(kubeClient.Create(ctx, statusSecret)).Should(Succeed())
+			Expect(kubeClient.Create(ctx, buildWithStatus)).Should(Succeed())
+			Expect(kubeClient.Create(ctx, buildWithoutStatus)).Should(Succeed())
+			time.Sleep(gracefulWait)
+		})
+
+		AfterAll(func() {
+			Expect(deleteAllBuildRuns()).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, buildWithStatus, deleteNowOpts)).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, buildWithoutStatus, deleteNowOpts)).Should(Succeed())
+			Expect(kubeClient.Delete(ctx, statusSecret, deleteNowOpts)).Should(Succeed())
+		})
+
+		It("BuildRun status is reported once per state", func() {
+			sha := "status-commit-id"
+			br := webhookBuildRun(buildWithStatus, sha)
+			Expect(kubeClient.Create(ctx, br)).Should(Succeed())
+
+			eventuallyWithTimeoutFn(reportedStatusFn(br)).Should(Equal(string(status.StatePending)))
+			Expect(statusReporter.states(sha)).To(Equal([]status.State{status.StatePending}))
+
+			Expect(markBuildRunSucceeded(client.ObjectKeyFromObject(br))).Should(Succeed())
+			eventuallyWithTimeoutFn(reportedStatusFn(br)).Should(Equal(string(status.StateSuccess)))
+
+			time.Sleep(gracefulWait)
+			Expect(statusReporter.states(sha)).To(Equal([]status.State{
+				status.StatePending,
+				status.StateSuccess,
+			}))
+		})
+
+		It("BuildRun status is reported again when the Git provider fails", func() {
+			sha := "retry-commit-id"
+			statusReporter.failNext(2)
+			br := webhookBuildRun(buildWithStatus, sha)
+			Expect(kubeClient.Create(ctx, br)).Should(Succeed())
+
+			eventuallyWithTimeoutFn(reportedStatusFn(br)).Should(Equal(string(status.StatePending)))
+			Expect(statusReporter.states(sha)).To(Equal([]status.State{status.StatePending}))
+		})
+
+		It("BuildRun status is not reported without a status Secret", func() {
+			sha := "no-status-commit-id"
+			br := webhookBuildRun(buildWithoutStatus, sha)
+			Expect(kubeClient.Create(ctx, br)).Should(Succeed())
+
+			time.Sleep(gracefulWait)
+			Expect(statusReporter.states(sha)).To(BeEmpty())
+			Expect(reportedStatusFn(br)()).To(BeEmpty())
+		})
+	})
+})
@@ -13,6 +13,8 @@ import (
 	buildapi "github.com/shipwright-io/build/pkg/apis/build/v1beta1"
 	"github.com/shipwright-io/triggers/controllers"
 	"github.com/shipwright-io/triggers/pkg/inventory"
+	"github.com/shipwright-io/triggers/pkg/status"
+	"github.com/shipwright-io/triggers/pkg/webhook"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonapibeta "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
@@ -38,6 +40,7 @@ var (
 	cancel context.CancelFunc
 
 	buildInventory *inventory.Inventory
+	statusReporter *fakeReporter
 )
 
 func TestAPIs(t *testing.T) {
@@ -92,7 +95,7 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	pipelineRunReconciler := controllers.NewPipelineRunReconciler(
-		mgr.GetClient(), mgr.GetScheme(), buildInventory)
+		mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(), buildInventory)
 
 	err = pipelineRunReconciler.SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
@@ -102,6 +105,25 @@ var _ = BeforeSuite(func() {
 	err = customRunReconciler.SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
 
+	statusReporter = &fakeReporter{}
+	statusReconciler := controllers.NewStatusReconciler(mgr.GetClient(), mgr.GetScheme(),
+		map[string]status.Reporter{webhook.GitHubProvider: statusReporter})
+
+	err = statusReconciler.SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	deliveryReconciler := controllers.NewDeliveryReconciler(
+		mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme())
+
+	err = deliveryReconciler.SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	concurrencyReconciler := controllers.NewConcurrencyReconciler(
+		mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme())
+
+	err = concurrencyReconciler.SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		defer GinkgoRecover()
 		err = mgr.Start(ctx)
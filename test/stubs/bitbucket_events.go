@@ -0,0 +1,115 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stubs
+
+var (
+	BitbucketCloudRepoURL  = "https://bitbucket.org/shipwright-io/sample-nodejs"
+	BitbucketServerRepoURL = "https://bitbucket.example.com/projects/SHP/repos/sample-nodejs/browse"
+)
+
+const (
+	BitbucketServerCloneURL = "ssh://git@bitbucket.example.com:7999/shp/sample-nodejs.git"
+)
+
+// bitbucketCloudRepository returns the Bitbucket Cloud repository attributes.
+func bitbucketCloudRepository() map[string]interface{} {
+	return map[string]interface{}{
+		"full_name": RepoFullName,
+		"links": map[string]interface{}{
+			"html": map[string]interface{}{"href": BitbucketCloudRepoURL},
+		},
+	}
+}
+
+// bitbucketServerRepository returns the Bitbucket Server repository attributes.
+func bitbucketServerRepository() map[string]interface{} {
+	return map[string]interface{}{
+		"slug":    "sample-nodejs",
+		"project": map[string]interface{}{"key": "SHP"},
+		"links": map[string]interface{}{
+			"self": []map[string]interface{}{{"href": BitbucketServerRepoURL}},
+		},
+	}
+}
+
+// BitbucketCloudPushEvent returns a "repo:push" payload, refType is either "branch" or "tag".
+func BitbucketCloudPushEvent(refType, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"push": map[string]interface{}{
+			"changes": []map[string]interface{}{{
+				"new": map[string]interface{}{
+					"type":   refType,
+					"name":   name,
+					"target": map[string]interface{}{"hash": HeadCommitID},
+				},
+				"old": map[string]interface{}{
+					"type":   refType,
+					"name":   name,
+					"target": map[string]interface{}{"hash": BeforeCommitID},
+				},
+			}},
+		},
+		"repository": bitbucketCloudRepository(),
+	}
+}
+
+// BitbucketCloudPullRequestEvent returns a "pullrequest:*" payload targeting the default branch.
+func BitbucketCloudPullRequestEvent() map[string]interface{} {
+	return map[string]interface{}{
+		"pullrequest": map[string]interface{}{
+			"id": PullRequestNumber,
+			"source": map[string]interface{}{
+				"branch":     map[string]interface{}{"name": "feature"},
+				"commit":     map[string]interface{}{"hash": HeadCommitID},
+				"repository": bitbucketCloudRepository(),
+			},
+			"destination": map[string]interface{}{
+				"branch":     map[string]interface{}{"name": Branch},
+				"commit":     map[string]interface{}{"hash": BeforeCommitID},
+				"repository": bitbucketCloudRepository(),
+			},
+		},
+		"actor":      map[string]interface{}{"nickname": PullRequestAuthor},
+		"repository": bitbucketCloudRepository(),
+	}
+}
+
+// BitbucketServerRefsChangedEvent returns a "repo:refs_changed" payload, changeType is either "ADD",
+// "UPDATE" or "DELETE".
+func BitbucketServerRefsChangedEvent(ref, changeType string) map[string]interface{} {
+	return map[string]interface{}{
+		"eventKey":   "repo:refs_changed",
+		"actor":      map[string]interface{}{"name": PusherLogin},
+		"repository": bitbucketServerRepository(),
+		"changes": []map[string]interface{}{{
+			"refId":    ref,
+			"fromHash": BeforeCommitID,
+			"toHash":   HeadCommitID,
+			"type":     changeType,
+		}},
+	}
+}
+
+// BitbucketServerPullRequestEvent returns a "pr:*" payload targeting the default branch.
+func BitbucketServerPullRequestEvent() map[string]interface{} {
+	return map[string]interface{}{
+		"actor": map[string]interface{}{"name": PullRequestAuthor},
+		"pullRequest": map[string]interface{}{
+			"id": PullRequestNumber,
+			"fromRef": map[string]interface{}{
+				"id":           "refs/heads/feature",
+				"displayId":    "feature",
+				"latestCommit": HeadCommitID,
+				"repository":   bitbucketServerRepository(),
+			},
+			"toRef": map[string]interface{}{
+				"id":           GitRef,
+				"displayId":    Branch,
+				"latestCommit": BeforeCommitID,
+				"repository":   bitbucketServerRepository(),
+			},
+		},
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stubs
+
+import (
+	"fmt"
+	"strings"
+)
+
+var GiteaRepoURL = "https://gitea.example.com/shipwright-io/sample-nodejs"
+
+// giteaRepository recorded Gitea repository attributes, shared by all payloads.
+var giteaRepository = fmt.Sprintf(`{
+  "id": 1,
+  "name": "sample-nodejs",
+  "full_name": %q,
+  "html_url": %q,
+  "clone_url": "%s.git",
+  "ssh_url": "git@gitea.example.com:%s.git",
+  "default_branch": %q
+}`, RepoFullName, GiteaRepoURL, GiteaRepoURL, RepoFullName, Branch)
+
+// GiteaPushEvent recorded Gitea "push" payload for the informed reference.
+func GiteaPushEvent(ref string) []byte {
+	return []byte(fmt.Sprintf(`{
+  "ref": %q,
+  "before": %q,
+  "after": %q,
+  "compare_url": "%s/compare/%s...%s",
+  "commits": [{
+    "id": %q,
+    "message": %q,
+    "author": {"name": %q},
+    "added": [],
+    "removed": [],
+    "modified": [%q, %q]
+  }],
+  "total_commits": 1,
+  "head_commit": {
+    "id": %q,
+    "message": %q,
+    "author": {"name": %q}
+  },
+  "repository": %s,
+  "pusher": {"login": %q},
+  "sender": {"login": %q}
+}`,
+		ref, BeforeCommitID, HeadCommitID,
+		GiteaRepoURL, BeforeCommitID, HeadCommitID,
+		HeadCommitID, HeadCommitMsg, HeadCommitAuthorName, ChangedFiles[0], ChangedFiles[1],
+		HeadCommitID, HeadCommitMsg, HeadCommitAuthorName,
+		giteaRepository,
+		PusherLogin, PusherLogin,
+	))
+}
+
+// GiteaCreateEvent recorded Gitea "create" payload, refType is either "branch" or "tag".
+func GiteaCreateEvent(refType, ref string) []byte {
+	return []byte(fmt.Sprintf(`{
+  "sha": %q,
+  "ref": %q,
+  "ref_type": %q,
+  "repository": %s,
+  "sender": {"login": %q}
+}`, HeadCommitID, ref, refType, giteaRepository, PusherLogin))
+}
+
+// GiteaPullRequestEvent recorded Gitea "pull_request" payload targeting the default branch.
+func GiteaPullRequestEvent(action string) []byte {
+	return GiteaPullRequestLabelEvent(action)
+}
+
+// GiteaPullRequestLabelEvent recorded Gitea "pull_request" (or "pull_request_label") payload
+// targeting the default branch, the pull-request carries the informed labels.
+func GiteaPullRequestLabelEvent(action string, labels ...string) []byte {
+	prLabels := make([]string, 0, len(labels))
+	for i, label := range labels {
+		prLabels = append(prLabels, fmt.Sprintf(`{"id": %d, "name": %q}`, i+1, label))
+	}
+	labelsJSON := fmt.Sprintf("[%s]", strings.Join(prLabels, ", "))
+	return []byte(fmt.Sprintf(`{
+  "action": %q,
+  "number": %d,
+  "pull_request": {
+    "id": 1,
+    "number": %d,
+    "state": "open",
+    "labels": %s,
+    "head": {
+      "label": "feature",
+      "ref": "feature",
+      "sha": %q,
+      "repo": %s
+    },
+    "base": {
+      "label": %q,
+      "ref": %q,
+      "sha": %q,
+      "repo": %s
+    }
+  },
+  "repository": %s,
+  "sender": {"login": %q}
+}`,
+		action, PullRequestNumber, PullRequestNumber, labelsJSON,
+		HeadCommitID, giteaRepository,
+		Branch, Branch, BeforeCommitID, giteaRepository,
+		giteaRepository, PullRequestAuthor,
+	))
+}
@@ -10,7 +10,13 @@ import (
 	"github.com/google/go-github/v53/github"
 )
 
-var RepoURL = "https://github.com/shipwright-io/sample-nodejs"
+var (
+	RepoURL = "https://github.com/shipwright-io/sample-nodejs"
+	// ForkRepoURL fork of the repository, employed as pull-request head.
+	ForkRepoURL = "https://github.com/contributor/sample-nodejs"
+	// ChangedFiles files modified by the head commit, one of them inside the Build context directory.
+	ChangedFiles = []string{"source-build/index.js", "README.md"}
+)
 
 const (
 	RepoFullName         = "shipwright-io/sample-nodejs"
@@ -19,6 +25,9 @@ const (
 	HeadCommitAuthorName = "Author's Name"
 	BeforeCommitID       = "before-commit-id"
 	GitRef               = "refs/heads/main"
+	PullRequestNumber    = 1
+	PullRequestAuthor    = "author"
+	PusherLogin          = "pusher"
 )
 
 func GitHubPingEvent() github.PingEvent {
@@ -45,7 +54,57 @@ func GitHubPushEvent() github.PushEvent {
 				Name: github.String(HeadCommitAuthorName),
 			},
 		},
+		Commits: []*github.HeadCommit{{
+			ID:       github.String(HeadCommitID),
+			Message:  github.String(HeadCommitMsg),
+			Modified: ChangedFiles,
+		}},
 		Before: github.String(BeforeCommitID),
 		Ref:    github.String(GitRef),
 	}
 }
+
+func GitHubPullRequestEvent(action string) github.PullRequestEvent {
+	return github.PullRequestEvent{
+		Action: github.String(action),
+		Number: github.Int(PullRequestNumber),
+		PullRequest: &github.PullRequest{
+			Number: github.Int(PullRequestNumber),
+			User:   &github.User{Login: github.String(PullRequestAuthor)},
+			Head: &github.PullRequestBranch{
+				Ref: github.String("feature"),
+				SHA: github.String(HeadCommitID),
+			},
+			Base: &github.PullRequestBranch{
+				Ref: github.String(Branch),
+				Repo: &github.Repository{
+					HTMLURL:  github.String(RepoURL),
+					FullName: github.String(RepoFullName),
+				},
+			},
+		},
+		Repo: &github.Repository{
+			HTMLURL:  github.String(RepoURL),
+			FullName: github.String(RepoFullName),
+		},
+	}
+}
+
+func GitHubIssueCommentEvent(body, authorAssociation string) github.IssueCommentEvent {
+	return github.IssueCommentEvent{
+		Action: github.String("created"),
+		Issue: &github.Issue{
+			Number:           github.Int(PullRequestNumber),
+			PullRequestLinks: &github.PullRequestLinks{},
+		},
+		Comment: &github.IssueComment{
+			Body:              github.String(body),
+			User:              &github.User{Login: github.String("maintainer")},
+			AuthorAssociation: github.String(authorAssociation),
+		},
+		Repo: &github.Repository{
+			HTMLURL:  github.String(RepoURL),
+			FullName: github.String(RepoFullName),
+		},
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright The Shipwright Contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stubs
+
+import (
+	"strings"
+)
+
+var GitLabRepoURL = "https://gitlab.com/shipwright-io/group/sample-nodejs"
+
+const (
+	GitLabRepoPathWithNamespace = "shipwright-io/group/sample-nodejs"
+	MergeRequestIID             = 1
+)
+
+// gitLabProject returns the GitLab project attributes, using a subgroup.
+func gitLabProject() map[string]interface{} {
+	return map[string]interface{}{
+		"web_url":             GitLabRepoURL,
+		"git_http_url":        GitLabRepoURL + ".git",
+		"git_ssh_url":         "git@gitlab.com:" + GitLabRepoPathWithNamespace + ".git",
+		"path_with_namespace": GitLabRepoPathWithNamespace,
+	}
+}
+
+func GitLabPushEvent(ref string) map[string]interface{} {
+	kind := "push"
+	if strings.HasPrefix(ref, "refs/tags/") {
+		kind = "tag_push"
+	}
+	return map[string]interface{}{
+		"object_kind":  kind,
+		"before":       BeforeCommitID,
+		"after":        HeadCommitID,
+		"ref":          ref,
+		"checkout_sha": HeadCommitID,
+		"user_name":    HeadCommitAuthorName,
+		"project":      gitLabProject(),
+		"commits": []map[string]interface{}{{
+			"id":       HeadCommitID,
+			"message":  HeadCommitMsg,
+			"modified": ChangedFiles,
+		}},
+		"total_commits_count": 1,
+	}
+}
+
+func GitLabMergeRequestEvent(action string) map[string]interface{} {
+	return map[string]interface{}{
+		"object_kind": "merge_request",
+		"user":        map[string]interface{}{"username": PullRequestAuthor},
+		"project":     gitLabProject(),
+		"object_attributes": map[string]interface{}{
+			"iid":           MergeRequestIID,
+			"action":        action,
+			"oldrev":        BeforeCommitID,
+			"source_branch": "feature",
+			"target_branch": Branch,
+			"last_commit": map[string]interface{}{
+				"id":      HeadCommitID,
+				"message": HeadCommitMsg,
+			},
+			"source": gitLabProject(),
+			"target": gitLabProject(),
+		},
+	}
+}
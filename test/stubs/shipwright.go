@@ -29,6 +29,17 @@ var (
 			Branches: []string{Branch},
 		},
 	}
+	// TriggerWhenPullRequestToMain describes a trigger for a github pull-request event targeting the
+	// default branch.
+	TriggerWhenPullRequestToMain = buildapi.TriggerWhen{
+		Type: buildapi.GitHubWebHookTrigger,
+		GitHub: &buildapi.WhenGitHub{
+			Events: []buildapi.GitHubEventName{
+				buildapi.GitHubPullRequestEvent,
+			},
+			Branches: []string{Branch},
+		},
+	}
 	// TriggerWhenPipelineSucceeded describes a trigger for Tekton Pipeline on status "succeeded".
 	TriggerWhenPipelineSucceeded = buildapi.TriggerWhen{
 		Type: buildapi.PipelineTrigger,